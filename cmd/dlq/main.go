@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/rmq"
+	"github.com/riandyrn/go-env"
+)
+
+const (
+	envKeyRabbitMQConn = "RABBITMQ_CONN"
+)
+
+const usage = `Usage: dlq -queue <queue_name> <command> [args]
+
+Commands:
+  list                   list the dead-lettered messages
+  inspect <id>           show the headers & body of the dead-lettered message
+  replay <id>... | -all  publish the messages back to their queue
+  purge <id>... | -all   remove the messages from the dead-letter queue
+
+The rabbitmq connection string is read from RABBITMQ_CONN.
+`
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+	}
+	queueName := flag.String("queue", "", "name of the queue whose dead-letter queue is managed, e.g vacancy_queue")
+	flag.Parse()
+	if *queueName == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dlq, err := rmq.NewDeadLetterQueue(rmq.DeadLetterQueueConfig{
+		QueueName:          *queueName,
+		RabbitMQConnString: env.GetString(envKeyRabbitMQConn),
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize dead-letter queue: %v", err)
+	}
+	defer dlq.Close()
+
+	ctx := context.Background()
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	switch cmd {
+	case "list":
+		err = list(ctx, dlq)
+	case "inspect":
+		if len(args) != 1 {
+			err = errors.New("inspect expects exactly one message id")
+			break
+		}
+		err = inspect(ctx, dlq, args[0])
+	case "replay":
+		var ids []string
+		ids, err = parseIDs(cmd, args)
+		if err != nil {
+			break
+		}
+		var count int
+		count, err = dlq.Replay(ctx, ids)
+		fmt.Printf("replayed %d messages to %s\n", count, *queueName)
+	case "purge":
+		var ids []string
+		ids, err = parseIDs(cmd, args)
+		if err != nil {
+			break
+		}
+		var count int
+		count, err = dlq.Purge(ctx, ids)
+		fmt.Printf("purged %d messages from %s\n", count, rmq.DeadLetterName(*queueName))
+	default:
+		err = fmt.Errorf("unknown command `%s`", cmd)
+	}
+	if err != nil {
+		dlq.Close()
+		log.Fatalf("failed to %s: %v", cmd, err)
+	}
+}
+
+// parseIDs returns the message ids targeted by the command, `-all` targets
+// every message and is returned as empty ids.
+func parseIDs(cmd string, args []string) ([]string, error) {
+	if len(args) == 1 && args[0] == "-all" {
+		return nil, nil
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s expects message ids or -all", cmd)
+	}
+	for _, arg := range args {
+		if arg == "-all" {
+			return nil, errors.New("-all can't be combined with message ids")
+		}
+	}
+
+	return args, nil
+}
+
+func list(ctx context.Context, dlq *rmq.DeadLetterQueue) error {
+	deadLetters, err := dlq.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(deadLetters) == 0 {
+		fmt.Println("no dead-lettered messages")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tATTEMPTS\tDEAD LETTERED AT\tLAST ERROR")
+	for _, dl := range deadLetters {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", dl.ID, dl.Attempts, formatTime(dl.DeadLetteredAt), truncate(dl.LastError, 80))
+	}
+
+	return w.Flush()
+}
+
+func inspect(ctx context.Context, dlq *rmq.DeadLetterQueue, id string) error {
+	dl, err := dlq.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("ID: %s\n", dl.ID)
+	fmt.Printf("Attempts: %d\n", dl.Attempts)
+	fmt.Printf("Published At: %s\n", formatTime(dl.PublishedAt))
+	fmt.Printf("Dead Lettered At: %s\n", formatTime(dl.DeadLetteredAt))
+	fmt.Printf("Last Error: %s\n", dl.LastError)
+
+	keys := make([]string, 0, len(dl.Headers))
+	for k := range dl.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Println("Headers:")
+	for _, k := range keys {
+		fmt.Printf("  %s: %v\n", k, dl.Headers[k])
+	}
+
+	fmt.Println("Body:")
+	var body bytes.Buffer
+	if err := json.Indent(&body, dl.Body, "", "  "); err != nil {
+		// the body is not json, print it as it is
+		fmt.Println(string(dl.Body))
+		return nil
+	}
+	fmt.Println(body.String())
+
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"log"
+	"os/signal"
+	"syscall"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/notification-worker/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/notification-worker/driven/publisher/email"
 	"github.com/ghazlabs/idn-remote-entry/internal/notification-worker/driven/publisher/wa"
+	"github.com/ghazlabs/idn-remote-entry/internal/notification-worker/driven/submitter"
 	"github.com/ghazlabs/idn-remote-entry/internal/notification-worker/driver/worker"
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/rmq"
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/tracker"
 	"github.com/go-resty/resty/v2"
 	"github.com/riandyrn/go-env"
+
+	_ "github.com/go-sql-driver/mysql"
 )
 
 const (
@@ -18,12 +26,21 @@ const (
 	envKeySmtpPort             = "SMTP_PORT"
 	envKeySmtpFrom             = "SMTP_FROM"
 	envKeySmtpTo               = "SMTP_TO"
+	envKeySmtpPassword         = "SMTP_PASS"
 	envKeyWhatsappApiUser      = "WHATSAPP_API_USER"
 	envKeyWhatsappApiPass      = "WHATSAPP_API_PASS"
 	envKeyWhatsappApiBaseUrl   = "WHATSAPP_API_BASE_URL"
 	envKeyWhatsappRecipientIDs = "WHATSAPP_RECIPIENT_IDS"
 	envKeyRabbitMQConn         = "RABBITMQ_CONN"
 	envKeyRabbitMQWaQueueName  = "RABBITMQ_WA_QUEUE_NAME"
+	envKeyMysqlDSN             = "MYSQL_DSN"
+	envKeyRetryMaxAttempts     = "RETRY_MAX_ATTEMPTS"
+	envKeyRetryBaseDelaySecs   = "RETRY_BASE_DELAY_SECONDS"
+	envKeyRetryMaxDelaySecs    = "RETRY_MAX_DELAY_SECONDS"
+	envKeyConsumerConcurrency  = "CONSUMER_CONCURRENCY"
+	envKeyConsumerPrefetch     = "CONSUMER_PREFETCH"
+	envKeyHandlerTimeoutSecs   = "HANDLER_TIMEOUT_SECONDS"
+	envKeyShutdownTimeoutSecs  = "SHUTDOWN_TIMEOUT_SECONDS"
 )
 
 func initPublisher() (core.Publisher, error) {
@@ -53,9 +70,42 @@ func main() {
 		log.Fatalf("failed to initialize publisher: %v", err)
 	}
 
+	// initialize submission tracker
+	mysqlClient, err := sql.Open("mysql", env.GetString(envKeyMysqlDSN))
+	if err != nil {
+		log.Fatalf("failed to initialize mysql client: %v", err)
+	}
+	defer mysqlClient.Close()
+
+	submissionTracker, err := tracker.NewTracker(tracker.TrackerConfig{
+		DB: mysqlClient,
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize submission tracker: %v", err)
+	}
+
+	// initialize submitter notifier, it is only enabled when smtp is
+	// configured
+	var submitterNotifier core.SubmitterNotifier
+	if env.GetString(envKeySmtpHost) != "" {
+		submitterNotifier, err = submitter.NewEmailNotifier(submitter.EmailNotifierConfig{
+			Host:     env.GetString(envKeySmtpHost),
+			Port:     env.GetInt(envKeySmtpPort),
+			From:     env.GetString(envKeySmtpFrom),
+			Password: env.GetString(envKeySmtpPassword),
+		})
+		if err != nil {
+			log.Fatalf("failed to initialize submitter notifier: %v", err)
+		}
+	} else {
+		log.Printf("%s is not set, submitters won't be notified when their vacancy is published", envKeySmtpHost)
+	}
+
 	// initialize service
 	svc, err := core.NewService(core.ServiceConfig{
-		Publisher: pub,
+		Publisher:         pub,
+		Tracker:           submissionTracker,
+		SubmitterNotifier: submitterNotifier,
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize service: %v", err)
@@ -65,35 +115,35 @@ func main() {
 	notifRmqConsumer, err := rmq.NewConsumer(rmq.ConsumerConfig{
 		QueueName:          env.GetString(envKeyRabbitMQWaQueueName),
 		RabbitMQConnString: env.GetString(envKeyRabbitMQConn),
+		MaxAttempts:        env.GetInt(envKeyRetryMaxAttempts),
+		RetryBaseDelay:     env.GetSeconds(envKeyRetryBaseDelaySecs),
+		RetryMaxDelay:      env.GetSeconds(envKeyRetryMaxDelaySecs),
+		Concurrency:        env.GetInt(envKeyConsumerConcurrency),
+		Prefetch:           env.GetInt(envKeyConsumerPrefetch),
+		HandlerTimeout:     env.GetSeconds(envKeyHandlerTimeoutSecs),
+		ShutdownTimeout:    env.GetSeconds(envKeyShutdownTimeoutSecs),
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize consumer: %v", err)
 	}
 	defer notifRmqConsumer.Close()
 
-	// initialize notification publisher
-	notifRmqPublisher, err := rmq.NewPublisher(rmq.PublisherConfig{
-		QueueName:          env.GetString(envKeyRabbitMQWaQueueName),
-		RabbitMQConnString: env.GetString(envKeyRabbitMQConn),
-	})
-	if err != nil {
-		log.Fatalf("failed to initialize consumer: %v", err)
-	}
-	defer notifRmqPublisher.Close()
-
 	// initialize worker
 	w, err := worker.New(worker.Config{
-		Service:      svc,
-		RmqConsumer:  notifRmqConsumer,
-		RmqPublisher: notifRmqPublisher,
+		Service:     svc,
+		RmqConsumer: notifRmqConsumer,
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize worker: %v", err)
 	}
 
-	// run worker
+	// run worker until it is asked to shut down, the in-flight messages are
+	// drained before it stops
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT)
+	defer stop()
+
 	log.Printf("notification-worker is running...")
-	err = w.Run()
+	err = w.Run(ctx)
 	if err != nil {
 		log.Fatalf("failed to run worker: %v", err)
 	}
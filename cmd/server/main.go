@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -10,11 +11,16 @@ import (
 	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/server/driven/approval"
 	"github.com/ghazlabs/idn-remote-entry/internal/server/driven/email"
+	leadermysql "github.com/ghazlabs/idn-remote-entry/internal/server/driven/leader/mysql"
 	"github.com/ghazlabs/idn-remote-entry/internal/server/driven/queue"
+	rlmemory "github.com/ghazlabs/idn-remote-entry/internal/server/driven/ratelimit/memory"
+	rlmysql "github.com/ghazlabs/idn-remote-entry/internal/server/driven/ratelimit/mysql"
 	"github.com/ghazlabs/idn-remote-entry/internal/server/driven/storage/mysql"
 	"github.com/ghazlabs/idn-remote-entry/internal/server/driven/token"
 	"github.com/ghazlabs/idn-remote-entry/internal/server/driver"
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driver/sweeper"
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/rmq"
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/tracker"
 	vwcore "github.com/ghazlabs/idn-remote-entry/internal/vacancy-worker/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/vacancy-worker/driven/resolver"
 	"github.com/ghazlabs/idn-remote-entry/internal/vacancy-worker/driven/resolver/hqloc"
@@ -25,6 +31,7 @@ import (
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/riandyrn/go-env"
+	"github.com/robfig/cron/v3"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -46,10 +53,38 @@ const (
 	envKeySmtpFrom                 = "SMTP_FROM"
 	envKeySmtpPassword             = "SMTP_PASS"
 	envKeyApprovalJwtSecretKey     = "APPROVAL_JWT_SECRET_KEY"
+	envKeyApprovalJwtKeys          = "APPROVAL_JWT_KEYS"
+	envKeyApprovalJwtActiveKeyID   = "APPROVAL_JWT_ACTIVE_KEY_ID"
+	envKeyApprovalJwtRetiredKeyIDs = "APPROVAL_JWT_RETIRED_KEY_IDS"
+	envKeyApprovalJwtTTLSeconds    = "APPROVAL_JWT_TTL_SECONDS"
 	envKeyMysqlDSN                 = "MYSQL_DSN"
+	envKeyAdminUsername            = "ADMIN_USERNAME"
+	envKeyAdminPassword            = "ADMIN_PASSWORD"
+	envKeyRateLimitBackend         = "RATE_LIMIT_BACKEND"
+	envKeyRateLimitPerAPIKey       = "RATE_LIMIT_PER_API_KEY"
+	envKeyRateLimitPerEmail        = "RATE_LIMIT_PER_SUBMISSION_EMAIL"
+	envKeyRateLimitPerIP           = "RATE_LIMIT_PER_IP"
+	envKeyRateLimitBulkItemsPerKey = "RATE_LIMIT_BULK_ITEMS_PER_API_KEY"
+	envKeyRateLimitPreviewPerKey   = "RATE_LIMIT_PREVIEW_PER_API_KEY"
+	envKeyRateLimitPreviewPerIP    = "RATE_LIMIT_PREVIEW_PER_IP"
+	envKeyTrustProxyHeaders        = "TRUST_PROXY_HEADERS"
+	envKeyIdempotencyKeyTTLSeconds = "IDEMPOTENCY_KEY_TTL_SECONDS"
+	envKeyInboundEmailSecret       = "INBOUND_EMAIL_SECRET"
+	envKeyInboundEmailAuthServID   = "INBOUND_EMAIL_AUTHSERV_ID"
+	envKeyApprovalTTLSeconds       = "APPROVAL_TTL_SECONDS"
+	envKeyApprovalSweepSeconds     = "APPROVAL_SWEEP_INTERVAL_SECONDS"
+	envKeyApprovalExpiryDigest     = "APPROVAL_EXPIRY_DIGEST_ENABLED"
+	envKeyPendingDigestSchedule    = "PENDING_DIGEST_CRON_SCHEDULE"
 )
 
-func initStorage() (vwcore.Storage, error) {
+// vacancyStorage is used by the vacancy resolver for looking up company
+// location and by the server for detecting duplicate submission.
+type vacancyStorage interface {
+	vwcore.Storage
+	core.VacancyStorage
+}
+
+func initStorage() (vacancyStorage, error) {
 	switch env.GetString(envKeyStorageType) {
 	case "jsonl":
 		return jsonl.NewJSONLStorage(jsonl.JSONLStorageConfig{
@@ -66,6 +101,78 @@ func initStorage() (vwcore.Storage, error) {
 	}
 }
 
+func initTokenizer() (*token.Tokenizer, error) {
+	keys, err := token.ParseKeyring(
+		env.GetString(envKeyApprovalJwtKeys),
+		env.GetStrings(envKeyApprovalJwtRetiredKeyIDs, ","),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", envKeyApprovalJwtKeys, err)
+	}
+	activeKeyID := env.GetString(envKeyApprovalJwtActiveKeyID)
+
+	// fallback to single secret key for deployment without keyring
+	if len(keys) == 0 {
+		keys = []token.SigningKey{{ID: "default", Secret: env.GetString(envKeyApprovalJwtSecretKey)}}
+		activeKeyID = "default"
+	}
+
+	return token.NewTokenizer(token.TokenizerConfig{
+		Keys:        keys,
+		ActiveKeyID: activeKeyID,
+		TTL:         env.GetSeconds(envKeyApprovalJwtTTLSeconds),
+	})
+}
+
+func initRateLimiter(db *sql.DB) (core.RateLimiter, error) {
+	switch env.GetString(envKeyRateLimitBackend) {
+	case "":
+		return nil, nil
+	case "memory":
+		return rlmemory.NewMemoryLimiter(), nil
+	case "mysql":
+		return rlmysql.NewMySQLLimiter(rlmysql.MySQLLimiterConfig{DB: db})
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %s", env.GetString(envKeyRateLimitBackend))
+	}
+}
+
+func initRateLimits() (driver.RateLimits, error) {
+	perAPIKey, err := core.ParseRateLimit(env.GetString(envKeyRateLimitPerAPIKey))
+	if err != nil {
+		return driver.RateLimits{}, fmt.Errorf("invalid %s: %w", envKeyRateLimitPerAPIKey, err)
+	}
+	perEmail, err := core.ParseRateLimit(env.GetString(envKeyRateLimitPerEmail))
+	if err != nil {
+		return driver.RateLimits{}, fmt.Errorf("invalid %s: %w", envKeyRateLimitPerEmail, err)
+	}
+	perIP, err := core.ParseRateLimit(env.GetString(envKeyRateLimitPerIP))
+	if err != nil {
+		return driver.RateLimits{}, fmt.Errorf("invalid %s: %w", envKeyRateLimitPerIP, err)
+	}
+	bulkItemsPerAPIKey, err := core.ParseRateLimit(env.GetString(envKeyRateLimitBulkItemsPerKey))
+	if err != nil {
+		return driver.RateLimits{}, fmt.Errorf("invalid %s: %w", envKeyRateLimitBulkItemsPerKey, err)
+	}
+	previewPerAPIKey, err := core.ParseRateLimit(env.GetString(envKeyRateLimitPreviewPerKey))
+	if err != nil {
+		return driver.RateLimits{}, fmt.Errorf("invalid %s: %w", envKeyRateLimitPreviewPerKey, err)
+	}
+	previewPerIP, err := core.ParseRateLimit(env.GetString(envKeyRateLimitPreviewPerIP))
+	if err != nil {
+		return driver.RateLimits{}, fmt.Errorf("invalid %s: %w", envKeyRateLimitPreviewPerIP, err)
+	}
+
+	return driver.RateLimits{
+		PerAPIKey:          perAPIKey,
+		PerSubmissionEmail: perEmail,
+		PerIP:              perIP,
+		BulkItemsPerAPIKey: bulkItemsPerAPIKey,
+		PreviewPerAPIKey:   previewPerAPIKey,
+		PreviewPerIP:       previewPerIP,
+	}, nil
+}
+
 func main() {
 	// initialize storage
 	strg, err := initStorage()
@@ -94,12 +201,15 @@ func main() {
 		log.Fatalf("failed to initialize email client: %v", err)
 	}
 
-	tokenizer, err := token.NewTokenizer(token.TokenizerConfig{
-		SecretKey: env.GetString(envKeyApprovalJwtSecretKey),
-	})
+	tokenizer, err := initTokenizer()
 	if err != nil {
 		log.Fatalf("failed to initialize tokenizer: %v", err)
 	}
+	// approval links must stay valid until the approval expires, otherwise
+	// the expiry digest lists approvals which can't be decided anymore
+	if approvalTTL := env.GetSeconds(envKeyApprovalTTLSeconds); approvalTTL > 0 && tokenizer.TTL < approvalTTL {
+		log.Fatalf("%s (%s) must not be shorter than %s (%s)", envKeyApprovalJwtTTLSeconds, tokenizer.TTL, envKeyApprovalTTLSeconds, approvalTTL)
+	}
 
 	approval, err := approval.NewApproval(approval.ApprovalConfig{
 		ApprovedSubmitterEmails: env.GetString(envKeyApprovedSubmitterEmails),
@@ -120,6 +230,13 @@ func main() {
 		log.Fatalf("failed to initialize approval storage: %v", err)
 	}
 
+	submissionTracker, err := tracker.NewTracker(tracker.TrackerConfig{
+		DB: mysqlClient,
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize submission tracker: %v", err)
+	}
+
 	// initialize parser
 	httpClient := resty.New()
 	openAiClient := openai.NewClient(option.WithAPIKey(env.GetString(envKeyOpenAiKey)))
@@ -163,26 +280,103 @@ func main() {
 
 	// initialize service
 	svc, err := core.NewService(core.ServiceConfig{
-		VacancyResolver: rslvr,
-		Queue:           queue.NewQueue(rmqPub),
-		Email:           emailClient,
-		Tokenizer:       tokenizer,
-		Approval:        approval,
-		ApprovalStorage: approvalStorage,
+		VacancyResolver:   rslvr,
+		Queue:             queue.NewQueue(rmqPub),
+		Email:             emailClient,
+		Tokenizer:         tokenizer,
+		Approval:          approval,
+		ApprovalStorage:   approvalStorage,
+		SubmissionStorage: submissionTracker,
+		VacancyStorage:    strg,
+		TokenStorage:      approvalStorage,
+		APIKeyStorage:     approvalStorage,
+		AdminEmails:       env.GetStrings(envKeyAdminEmails, ","),
+		ApprovalTTL:       env.GetSeconds(envKeyApprovalTTLSeconds),
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize service: %v", err)
 	}
 
+	// initialize rate limiter
+	rateLimiter, err := initRateLimiter(mysqlClient)
+	if err != nil {
+		log.Fatalf("failed to initialize rate limiter: %v", err)
+	}
+	rateLimits, err := initRateLimits()
+	if err != nil {
+		log.Fatalf("failed to initialize rate limits: %v", err)
+	}
+
 	// initialize handler
 	api, err := driver.NewAPI(driver.APIConfig{
-		Service:      svc,
-		ClientApiKey: env.GetString(envKeyClientApiKey),
+		Service:                svc,
+		ClientApiKey:           env.GetString(envKeyClientApiKey),
+		AdminUsername:          env.GetString(envKeyAdminUsername),
+		AdminPassword:          env.GetString(envKeyAdminPassword),
+		RateLimiter:            rateLimiter,
+		RateLimits:             rateLimits,
+		TrustProxyHeaders:      env.GetBool(envKeyTrustProxyHeaders),
+		IdempotencyStorage:     approvalStorage,
+		IdempotencyKeyTTL:      env.GetSeconds(envKeyIdempotencyKeyTTLSeconds),
+		InboundEmailSecret:     env.GetString(envKeyInboundEmailSecret),
+		InboundEmailAuthServID: env.GetString(envKeyInboundEmailAuthServID),
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize API: %v", err)
 	}
 
+	// background jobs are only run by the replica holding the lock, so
+	// running several replicas doesn't expire & notify twice
+	leader, err := leadermysql.NewMySQLLeader(leadermysql.MySQLLeaderConfig{
+		DB: mysqlClient,
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize leader: %v", err)
+	}
+	defer leader.Close()
+
+	// expire stale approvals in background, approvals never expire when
+	// the ttl is not set
+	if env.GetSeconds(envKeyApprovalTTLSeconds) > 0 {
+		approvalSweeper, err := sweeper.New(sweeper.Config{
+			Service:    svc,
+			Leader:     leader,
+			Interval:   env.GetSeconds(envKeyApprovalSweepSeconds),
+			SendDigest: env.GetBool(envKeyApprovalExpiryDigest),
+		})
+		if err != nil {
+			log.Fatalf("failed to initialize approval sweeper: %v", err)
+		}
+		go approvalSweeper.Run(context.Background())
+	}
+
+	// send digest of pending approvals to admin periodically, the digest is
+	// disabled when the schedule is not set
+	if schedule := env.GetString(envKeyPendingDigestSchedule); schedule != "" {
+		c := cron.New()
+		_, err := c.AddFunc(schedule, func() {
+			isLeader, err := leader.IsLeader(context.Background())
+			if err != nil {
+				log.Printf("failed to check leader: %v", err)
+				return
+			}
+			if !isLeader {
+				return
+			}
+			count, err := svc.SendPendingDigest(context.Background())
+			if err != nil {
+				log.Printf("failed to send pending digest: %v", err)
+				return
+			}
+			log.Printf("sent pending digest of %d approvals", count)
+		})
+		if err != nil {
+			log.Fatalf("invalid pending digest schedule: %v", err)
+		}
+		c.Start()
+		defer c.Stop()
+	}
+
 	// initialize server
 	listenAddr := fmt.Sprintf(":%s", env.GetString(envKeyListenPort))
 	s := &http.Server{
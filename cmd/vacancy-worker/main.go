@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"log"
+	"os/signal"
+	"syscall"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/rmq"
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/tracker"
 	"github.com/ghazlabs/idn-remote-entry/internal/vacancy-worker/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/vacancy-worker/driven/notifier"
 	"github.com/ghazlabs/idn-remote-entry/internal/vacancy-worker/driven/resolver"
@@ -16,6 +21,8 @@ import (
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/riandyrn/go-env"
+
+	_ "github.com/go-sql-driver/mysql"
 )
 
 const (
@@ -26,6 +33,14 @@ const (
 	envKeyRabbitMQConn             = "RABBITMQ_CONN"
 	envKeyRabbitMQWaQueueName      = "RABBITMQ_WA_QUEUE_NAME"
 	envKeyRabbitMQVacancyQueueName = "RABBITMQ_VACANCY_QUEUE_NAME"
+	envKeyMysqlDSN                 = "MYSQL_DSN"
+	envKeyRetryMaxAttempts         = "RETRY_MAX_ATTEMPTS"
+	envKeyRetryBaseDelaySeconds    = "RETRY_BASE_DELAY_SECONDS"
+	envKeyRetryMaxDelaySeconds     = "RETRY_MAX_DELAY_SECONDS"
+	envKeyConsumerConcurrency      = "CONSUMER_CONCURRENCY"
+	envKeyConsumerPrefetch         = "CONSUMER_PREFETCH"
+	envKeyHandlerTimeoutSeconds    = "HANDLER_TIMEOUT_SECONDS"
+	envKeyShutdownTimeoutSeconds   = "SHUTDOWN_TIMEOUT_SECONDS"
 )
 
 func initStorage() (core.Storage, error) {
@@ -111,30 +126,42 @@ func main() {
 		log.Fatalf("failed to initialize notifier: %v", err)
 	}
 
+	// initialize submission tracker
+	mysqlClient, err := sql.Open("mysql", env.GetString(envKeyMysqlDSN))
+	if err != nil {
+		log.Fatalf("failed to initialize mysql client: %v", err)
+	}
+	defer mysqlClient.Close()
+
+	submissionTracker, err := tracker.NewTracker(tracker.TrackerConfig{
+		DB: mysqlClient,
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize submission tracker: %v", err)
+	}
+
 	// initialize service
 	svc, err := core.NewService(core.ServiceConfig{
 		Storage:         strg,
 		VacancyResolver: rslvr,
 		Notifier:        waNotf,
+		Tracker:         submissionTracker,
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize service: %v", err)
 	}
 
-	// initialize vacancy publisher
-	vacancyRmqPub, err := rmq.NewPublisher(rmq.PublisherConfig{
-		QueueName:          env.GetString(envKeyRabbitMQVacancyQueueName),
-		RabbitMQConnString: env.GetString(envKeyRabbitMQConn),
-	})
-	if err != nil {
-		log.Fatalf("failed to initialize vacancy publisher: %v", err)
-	}
-	defer vacancyRmqPub.Close()
-
 	// initialize vacancy consumer
 	vacancyRmqConsumer, err := rmq.NewConsumer(rmq.ConsumerConfig{
 		QueueName:          env.GetString(envKeyRabbitMQVacancyQueueName),
 		RabbitMQConnString: env.GetString(envKeyRabbitMQConn),
+		MaxAttempts:        env.GetInt(envKeyRetryMaxAttempts),
+		RetryBaseDelay:     env.GetSeconds(envKeyRetryBaseDelaySeconds),
+		RetryMaxDelay:      env.GetSeconds(envKeyRetryMaxDelaySeconds),
+		Concurrency:        env.GetInt(envKeyConsumerConcurrency),
+		Prefetch:           env.GetInt(envKeyConsumerPrefetch),
+		HandlerTimeout:     env.GetSeconds(envKeyHandlerTimeoutSeconds),
+		ShutdownTimeout:    env.GetSeconds(envKeyShutdownTimeoutSeconds),
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize vacancy consumer: %v", err)
@@ -143,17 +170,20 @@ func main() {
 
 	// initialize worker
 	w, err := worker.New(worker.Config{
-		Service:      svc,
-		RmqConsumer:  vacancyRmqConsumer,
-		RmqPublisher: vacancyRmqPub,
+		Service:     svc,
+		RmqConsumer: vacancyRmqConsumer,
 	})
 	if err != nil {
 		log.Fatalf("failed to initialize worker: %v", err)
 	}
 
-	// run worker
+	// run worker until it is asked to shut down, the in-flight messages are
+	// drained before it stops
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT)
+	defer stop()
+
 	log.Printf("vacancy-worker is running...")
-	err = w.Run()
+	err = w.Run(ctx)
 	if err != nil {
 		log.Fatalf("failed to run worker: %v", err)
 	}
@@ -0,0 +1,115 @@
+-- Brings databases created by an older schema.sql up to date. schema.sql is
+-- only run by docker-entrypoint-initdb.d when the database is created fresh,
+-- so the columns & indexes added afterwards to existing tables are added here.
+--
+-- Every statement is idempotent so the file can be run on every deploy, e.g
+-- `make migrate-ec2`, it must be run after schema.sql which creates the
+-- missing tables. MySQL 8.0 has no `ADD COLUMN IF NOT EXISTS`, so each change
+-- is only prepared when information_schema says it is missing.
+
+-- approvals.kind
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    "ALTER TABLE approvals ADD COLUMN kind VARCHAR(10) NOT NULL DEFAULT 'single' AFTER state",
+    'DO 0')
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND column_name = 'kind');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- approvals.batch_id, the batch of bulk items saved before the column was
+-- introduced is unknown so it is left null
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    'ALTER TABLE approvals ADD COLUMN batch_id VARCHAR(255) NULL AFTER kind',
+    'DO 0')
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND column_name = 'batch_id');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- rows saved before approvals.kind was introduced default to single,
+-- the message id of bulk items is generated with the `@bulk` suffix
+UPDATE approvals SET kind = 'bulk' WHERE kind = 'single' AND batch_id IS NULL AND message_id LIKE '%@bulk';
+
+-- approvals.original_request_data
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    'ALTER TABLE approvals ADD COLUMN original_request_data JSON NULL AFTER request_data',
+    'DO 0')
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND column_name = 'original_request_data');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- approvals.reject_reason
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    'ALTER TABLE approvals ADD COLUMN reject_reason TEXT NULL AFTER original_request_data',
+    'DO 0')
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND column_name = 'reject_reason');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- approvals.normalized_apply_url, existing rows keep the empty default so
+-- they are not matched by duplicate detection
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    "ALTER TABLE approvals ADD COLUMN normalized_apply_url VARCHAR(2048) NOT NULL DEFAULT '' AFTER reject_reason",
+    'DO 0')
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND column_name = 'normalized_apply_url');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- approvals.token
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    'ALTER TABLE approvals ADD COLUMN token TEXT NULL AFTER normalized_apply_url',
+    'DO 0')
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND column_name = 'token');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- approvals indexes
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    'ALTER TABLE approvals ADD INDEX idx_approvals_normalized_apply_url (normalized_apply_url(255))',
+    'DO 0')
+    FROM information_schema.statistics
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND index_name = 'idx_approvals_normalized_apply_url');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    'ALTER TABLE approvals ADD INDEX idx_approvals_batch_id (batch_id)',
+    'DO 0')
+    FROM information_schema.statistics
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND index_name = 'idx_approvals_batch_id');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    'ALTER TABLE approvals ADD INDEX idx_approvals_state_created_at (state, created_at)',
+    'DO 0')
+    FROM information_schema.statistics
+    WHERE table_schema = DATABASE() AND table_name = 'approvals' AND index_name = 'idx_approvals_state_created_at');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- submissions.api_key_id
+SET @stmt = (SELECT IF(COUNT(*) = 0,
+    "ALTER TABLE submissions ADD COLUMN api_key_id VARCHAR(32) NOT NULL DEFAULT '' AFTER err_msg",
+    'DO 0')
+    FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'submissions' AND column_name = 'api_key_id');
+PREPARE stmt FROM @stmt;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- approval_token_revocations.revoked_before in millisecond precision
+ALTER TABLE approval_token_revocations MODIFY COLUMN revoked_before TIMESTAMP(3) NOT NULL;
@@ -1,6 +1,91 @@
 CREATE TABLE IF NOT EXISTS approvals (
     message_id VARCHAR(255) PRIMARY KEY,
+    -- pending, approved, rejected or expired
     state VARCHAR(10) NOT NULL,
+    -- single or bulk, rows saved before the column was introduced can be
+    -- backfilled by docs/db/migration.sql
+    kind VARCHAR(10) NOT NULL DEFAULT 'single',
+    -- id of the bulk approval request, only set for bulk kind, the message
+    -- id of the bulk approval email is `<batch_id>@bulk`
+    batch_id VARCHAR(255) NULL,
     request_data JSON NOT NULL,
+    -- set on first admin edit, request_data then holds the edited version
+    original_request_data JSON NULL,
+    reject_reason TEXT NULL,
+    -- apply url of request_data normalized by shcore.NormalizeApplyURL,
+    -- used for detecting duplicate submission
+    normalized_apply_url VARCHAR(2048) NOT NULL DEFAULT '',
+    -- approval token for acting on the approval from admin dashboard &
+    -- digest, reused until half of its lifetime has passed
+    token TEXT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_approvals_normalized_apply_url (normalized_apply_url(255)),
+    INDEX idx_approvals_batch_id (batch_id),
+    -- used for expiring stale pending approvals
+    INDEX idx_approvals_state_created_at (state, created_at)
+);
+
+CREATE TABLE IF NOT EXISTS submissions (
+    id VARCHAR(64) PRIMARY KEY,
+    state VARCHAR(20) NOT NULL,
+    submission_type VARCHAR(10) NOT NULL,
+    submission_email VARCHAR(255) NOT NULL DEFAULT '',
+    apply_url VARCHAR(2048) NOT NULL,
+    public_url VARCHAR(2048) NOT NULL DEFAULT '',
+    err_msg TEXT NULL,
+    -- id of the api key which submitted the vacancy, the submission can
+    -- only be looked up by this key (or admin key)
+    api_key_id VARCHAR(32) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ids of approval tokens that can no longer be used, either because they
+-- have been consumed by an approve/reject action or revoked by admin
+CREATE TABLE IF NOT EXISTS approval_tokens (
+    token_id VARCHAR(64) PRIMARY KEY,
+    state VARCHAR(10) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- tokens issued at or before the latest revoked_before are rejected, it is
+-- kept in millisecond precision like the issued at of approval token
+CREATE TABLE IF NOT EXISTS approval_token_revocations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    revoked_before TIMESTAMP(3) NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
+
+-- client api keys, only the sha256 hash of the key secret is stored
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    secret_hash CHAR(64) NOT NULL,
+    -- comma separated, e.g manual,url,bulk,admin
+    scopes VARCHAR(255) NOT NULL,
+    default_submission_email VARCHAR(255) NOT NULL DEFAULT '',
+    usage_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL
+);
+
+-- token buckets of the submission rate limiter, times are in unix millis
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    bucket_key VARCHAR(320) PRIMARY KEY,
+    tokens DOUBLE NOT NULL,
+    updated_at BIGINT NOT NULL,
+    -- when the bucket is refilled to full, used for pruning
+    full_at BIGINT NOT NULL
+);
+
+-- idempotency keys of vacancy submission, the key is prefixed with the id
+-- of the api key so different clients can't collide
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key VARCHAR(320) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    -- json encoded submit result, null while the request is being processed
+    result JSON NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_idempotency_keys_created_at (created_at)
+);
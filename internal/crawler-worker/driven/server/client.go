@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	"github.com/go-resty/resty/v2"
@@ -29,15 +30,26 @@ func NewClientServer(cfg ServerConfig) (*Server, error) {
 }
 
 func (s *Server) SubmitBulkVacancies(ctx context.Context, vacancies []core.Vacancy) error {
+	// the crawled vacancies are complete, so submit them as manual vacancy
+	// to prevent the server from resolving them again
+	bulkVacancies := make([]core.BulkVacancy, 0, len(vacancies))
+	for _, v := range vacancies {
+		bulkVacancies = append(bulkVacancies, core.BulkVacancy{
+			SubmissionType: core.SubmitTypeManual,
+			Vacancy:        v,
+		})
+	}
 	payload := map[string]interface{}{
 		"submission_type":  "bulk",
 		"submission_email": "crawler",
-		"bulk_vacancies":   vacancies,
+		"bulk_vacancies":   bulkVacancies,
 	}
+	var respBody submitBulkResp
 	resp, err := s.HttpClient.R().
 		SetHeader("Content-Type", "application/json").
 		SetHeader("X-Api-Key", s.ApiKey).
 		SetBody(payload).
+		SetResult(&respBody).
 		Post("/vacancies")
 	if err != nil {
 		return fmt.Errorf("failed to call api to submit bulk vacancies: %w", err)
@@ -46,5 +58,19 @@ func (s *Server) SubmitBulkVacancies(ctx context.Context, vacancies []core.Vacan
 		return fmt.Errorf("failed to submit bulk vacancies: %s", resp.String())
 	}
 
+	// the server skips the duplicate vacancies and submits the rest
+	for _, v := range respBody.Data.SkippedVacancies {
+		log.Printf("vacancy %s is skipped by server: %s", v.ApplyURL, v.Reason)
+	}
+
 	return nil
 }
+
+type submitBulkResp struct {
+	Data struct {
+		SkippedVacancies []struct {
+			ApplyURL string `json:"apply_url"`
+			Reason   string `json:"reason"`
+		} `json:"skipped_vacancies"`
+	} `json:"data"`
+}
@@ -9,3 +9,12 @@ import (
 type Publisher interface {
 	Publish(ctx context.Context, n shcore.Notification) error
 }
+
+type Tracker interface {
+	UpdateSubmission(ctx context.Context, upd shcore.SubmissionUpdate) error
+}
+
+type SubmitterNotifier interface {
+	// NotifyApproved tells the submitter the vacancy has been published
+	NotifyApproved(ctx context.Context, n shcore.Notification) error
+}
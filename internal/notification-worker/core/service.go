@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"log"
 
 	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	"gopkg.in/validator.v2"
@@ -10,10 +11,18 @@ import (
 
 type Service interface {
 	Handle(ctx context.Context, n shcore.Notification) error
+	// HandleFailure marks the submission as failed, it is called once the
+	// notification is given up rather than on every failed attempt since
+	// the notification may still succeed on retry.
+	HandleFailure(ctx context.Context, n shcore.Notification, cause error)
 }
 
 type ServiceConfig struct {
 	Publisher Publisher `validate:"nonnil"`
+	Tracker   Tracker   `validate:"nonnil"`
+	// SubmitterNotifier is optional, submitters are not notified when it
+	// is not set
+	SubmitterNotifier SubmitterNotifier
 }
 
 func NewService(cfg ServiceConfig) (Service, error) {
@@ -35,5 +44,49 @@ func (s *service) Handle(ctx context.Context, n shcore.Notification) error {
 	if err != nil {
 		return fmt.Errorf("failed to publish notification: %w", err)
 	}
+
+	s.updateSubmission(ctx, shcore.SubmissionUpdate{
+		ID:    n.SubmissionID,
+		State: shcore.SubmissionStateNotified,
+	})
+
+	s.notifySubmitter(ctx, n)
+
 	return nil
 }
+
+func (s *service) HandleFailure(ctx context.Context, n shcore.Notification, cause error) {
+	s.updateSubmission(ctx, shcore.SubmissionUpdate{
+		ID:         n.SubmissionID,
+		State:      shcore.SubmissionStateFailed,
+		ErrMessage: cause.Error(),
+	})
+}
+
+// notifySubmitter sends the public url to the submitter, failure is only
+// logged since returning error would make the notification published twice.
+func (s *service) notifySubmitter(ctx context.Context, n shcore.Notification) {
+	// submission without real email address such as crawler has no one to notify
+	if s.SubmitterNotifier == nil || !shcore.IsEmailAddress(n.SubmissionEmail) {
+		return
+	}
+
+	err := s.SubmitterNotifier.NotifyApproved(ctx, n)
+	if err != nil {
+		log.Printf("failed to send approval email to submitter %s: %v", n.SubmissionEmail, err)
+	}
+}
+
+// updateSubmission records the submission state transition, failure is only
+// logged since tracking must not break the notification itself.
+func (s *service) updateSubmission(ctx context.Context, upd shcore.SubmissionUpdate) {
+	// notifications issued before tracking was introduced have no submission id
+	if upd.ID == "" {
+		return
+	}
+
+	err := s.Tracker.UpdateSubmission(ctx, upd)
+	if err != nil {
+		log.Printf("failed to update submission %s to %s: %v", upd.ID, upd.State, err)
+	}
+}
@@ -55,10 +55,10 @@ func (n *WaPublisher) sendMessage(ctx context.Context, ntf core.Notification, re
 		}).
 		Post(fmt.Sprintf("%v/send/message", n.WaApiBaseUrl))
 	if err != nil {
-		return fmt.Errorf("unable to make http request: %w", err)
+		return core.NewUpstreamUnavailableError(fmt.Errorf("unable to make http request: %w", err))
 	}
 	if resp.IsError() {
-		return fmt.Errorf("failed to send notification: %s", resp.String())
+		return core.NewUpstreamError(resp.StatusCode(), fmt.Errorf("failed to send notification: %s", resp.String()))
 	}
 
 	return nil
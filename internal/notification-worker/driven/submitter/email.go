@@ -0,0 +1,96 @@
+package submitter
+
+import (
+	"context"
+	"fmt"
+	"html"
+
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/mailer"
+	"gopkg.in/validator.v2"
+)
+
+type EmailNotifierConfig struct {
+	Host     string `validate:"nonzero"`
+	Port     int    `validate:"nonzero"`
+	From     string `validate:"nonzero"`
+	Password string
+}
+
+type EmailNotifier struct {
+	EmailNotifierConfig
+	mailer *mailer.Mailer
+}
+
+func NewEmailNotifier(cfg EmailNotifierConfig) (*EmailNotifier, error) {
+	err := validator.Validate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	m, err := mailer.NewMailer(mailer.MailerConfig{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		From:     cfg.From,
+		Password: cfg.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+
+	return &EmailNotifier{
+		EmailNotifierConfig: cfg,
+		mailer:              m,
+	}, nil
+}
+
+func (e *EmailNotifier) NotifyApproved(ctx context.Context, n shcore.Notification) error {
+	return e.mailer.Send(mailer.Message{
+		To: []string{n.SubmissionEmail},
+		Headers: map[string]string{
+			"From":    fmt.Sprintf("IDN Remote Entry <%s>", e.From),
+			"To":      n.SubmissionEmail,
+			"Subject": "IDNRemote.com - Your Job Vacancy Submission Has Been Published",
+		},
+		Boundary:  fmt.Sprintf("idnremote-%s", n.ID),
+		BodyHTML:  getApprovedBodyHTML(n),
+		BodyPlain: getApprovedBodyPlain(n),
+	})
+}
+
+func getApprovedBodyPlain(n shcore.Notification) string {
+	return fmt.Sprintf(`Bismillah
+Assalamu'alaikum warahmatullahi wabarakatuh
+
+Hello!
+Thank you for submitting a job vacancy to IDNRemote.com.
+
+Your submission has been approved and is now published:
+
+Job Title: %s
+Company: %s
+Public URL: %s
+
+Thank you and have a nice day!
+Barakallahu fiikum`, n.Vacancy.JobTitle, n.Vacancy.CompanyName, n.PublicURL)
+}
+
+func getApprovedBodyHTML(n shcore.Notification) string {
+	return fmt.Sprintf(`<p>Bismillah<br>Assalamu'alaikum warahmatullahi wabarakatuh</p>
+<p>Hello! <br> Thank you for submitting a job vacancy to IDNRemote.com.</p>
+<p>Your submission has been approved and is now published:</p>
+<p>
+	Job Title: %s<br>
+	Company: %s<br>
+	Public URL: <a href="%s">%s</a>
+</p>
+<p>
+	Thank you and have a nice day!<br>
+	Barakallahu fiikum
+</p>`,
+		html.EscapeString(n.Vacancy.JobTitle),
+		html.EscapeString(n.Vacancy.CompanyName),
+		html.EscapeString(n.PublicURL),
+		html.EscapeString(n.PublicURL),
+	)
+}
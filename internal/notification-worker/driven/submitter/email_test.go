@@ -0,0 +1,37 @@
+package submitter
+
+import (
+	"context"
+	"testing"
+
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/testutil"
+	"github.com/riandyrn/go-env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyApproved(t *testing.T) {
+	notifier, err := NewEmailNotifier(EmailNotifierConfig{
+		Host:     env.GetString(testutil.EnvKeySMTPHost),
+		Port:     env.GetInt(testutil.EnvKeySMTPPort),
+		From:     "from@example.com",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	err = notifier.NotifyApproved(context.Background(), shcore.Notification{
+		SubmissionID:    "test-submission",
+		SubmissionEmail: "submitter@example.com",
+		VacancyRecord: shcore.VacancyRecord{
+			ID:        "17d35004-6357-8173-ad51-e4a2aa25af5e",
+			PublicURL: "https://idn-remote-jobs.notion.site/Software-Engineer",
+			Vacancy: shcore.Vacancy{
+				JobTitle:    "Software Engineer",
+				CompanyName: "Test Company",
+				ApplyURL:    "https://example.com/apply",
+			},
+		},
+	})
+	assert.NoError(t, err)
+}
@@ -19,9 +19,8 @@ type Worker struct {
 }
 
 type Config struct {
-	Service      core.Service   `validate:"nonnil"`
-	RmqConsumer  *rmq.Consumer  `validate:"nonnil"`
-	RmqPublisher *rmq.Publisher `validate:"nonnil"`
+	Service     core.Service  `validate:"nonnil"`
+	RmqConsumer *rmq.Consumer `validate:"nonnil"`
 }
 
 func New(cfg Config) (*Worker, error) {
@@ -34,40 +33,43 @@ func New(cfg Config) (*Worker, error) {
 	return &Worker{Config: cfg}, nil
 }
 
-// Run starts the worker and block until it's done.
-func (w *Worker) Run() error {
+// Run starts the worker and block until ctx is canceled, the in-flight
+// messages are drained before it returns.
+func (w *Worker) Run(ctx context.Context) error {
 	// run the consumer
-	err := w.RmqConsumer.Run(func(d rabbitmq.Delivery) rabbitmq.Action {
-		ctx := context.Background()
+	err := w.RmqConsumer.Run(ctx, func(ctx context.Context, d rabbitmq.Delivery) rabbitmq.Action {
 		// parse the message
 		var n shcore.Notification
 		err := json.Unmarshal(d.Body, &n)
 		if err != nil {
-			// discard the message if failed to parse
-			return rabbitmq.NackDiscard
+			// the message will never be parsed, so dead-letter it right away
+			log.Printf("failed to parse message %s: %s\n", d.Body, err)
+			return w.deadLetter(ctx, d, rmq.Attempt(d), fmt.Errorf("failed to parse message: %w", err))
 		}
 
 		// handle the message
-		log.Printf("handling notification (attempt %d) %s\n", n.Retries+1, d.Body)
+		attempt := rmq.Attempt(d)
+		log.Printf("handling notification (attempt %d) %s\n", attempt, d.Body)
 		startTime := time.Now()
 		err = w.Config.Service.Handle(ctx, n)
 		if err != nil {
-			// output error and sleep for 1 second
-			log.Printf("failed to handle notification (attempt %d) %s: %s, sleeping for 1 second...\n", n.Retries+1, n.ToJSON(), err)
-			time.Sleep(1 * time.Second)
-
-			// increment retry count
-			n.Retries++
-			if n.Retries >= 3 {
-				log.Printf("discarding notification after %d retries: %s\n", n.Retries, n.ToJSON())
-				return rabbitmq.NackDiscard
+			log.Printf("failed to handle notification (attempt %d) %s: %s\n", attempt, d.Body, err)
+			// the handler is canceled by shutdown, put the message back as it
+			// is so the interrupted attempt doesn't count toward its retries
+			if rmq.IsShutdown(ctx) {
+				return rabbitmq.NackRequeue
 			}
-
-			// publish new request
-			w.Config.RmqPublisher.Publish(ctx, n)
-
-			// discard the original message since we've published new one
-			return rabbitmq.NackDiscard
+			// retrying permanent failure gives the same result
+			retryable := shcore.IsRetryable(err)
+			// the submission is only failed once the message is given up,
+			// it may still succeed on retry otherwise
+			if !retryable || attempt >= w.Config.RmqConsumer.MaxAttempts {
+				w.Config.Service.HandleFailure(context.WithoutCancel(ctx), n, err)
+			}
+			if !retryable {
+				return w.deadLetter(ctx, d, attempt, err)
+			}
+			return w.retry(ctx, d, err)
 		}
 
 		defer func() {
@@ -82,3 +84,30 @@ func (w *Worker) Run() error {
 
 	return nil
 }
+
+// retry schedules the failed message to be retried later, or dead-letters
+// it once it has reached the max attempts. The message is requeued when it
+// fails so it won't be lost.
+func (w *Worker) retry(ctx context.Context, d rabbitmq.Delivery, cause error) rabbitmq.Action {
+	// the handler context may have timed out, but the message still needs
+	// to be scheduled for retry
+	err := w.Config.RmqConsumer.Retry(context.WithoutCancel(ctx), d, cause)
+	if err != nil {
+		log.Printf("failed to retry message %s: %s, requeueing...\n", d.Body, err)
+		return rabbitmq.NackRequeue
+	}
+
+	return rabbitmq.NackDiscard
+}
+
+// deadLetter routes the terminally failed message to the dead-letter queue,
+// the message is requeued when it fails so it won't be lost.
+func (w *Worker) deadLetter(ctx context.Context, d rabbitmq.Delivery, attempts int, cause error) rabbitmq.Action {
+	err := w.Config.RmqConsumer.DeadLetter(context.WithoutCancel(ctx), d, attempts, cause)
+	if err != nil {
+		log.Printf("failed to dead-letter message %s: %s, requeueing...\n", d.Body, err)
+		return rabbitmq.NackRequeue
+	}
+
+	return rabbitmq.NackDiscard
+}
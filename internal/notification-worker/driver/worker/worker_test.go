@@ -9,10 +9,12 @@ import (
 	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/rmq"
 	"github.com/ghazlabs/idn-remote-entry/internal/testutil"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/riandyrn/go-env"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/wagslane/go-rabbitmq"
 )
 
 type mockService struct {
@@ -24,6 +26,10 @@ func (m *mockService) Handle(ctx context.Context, n shcore.Notification) error {
 	return args.Error(0)
 }
 
+func (m *mockService) HandleFailure(ctx context.Context, n shcore.Notification, cause error) {
+	m.Called(ctx, n, cause)
+}
+
 type inValidMsg struct{}
 
 func (i inValidMsg) ToJSON() []byte {
@@ -71,12 +77,14 @@ func TestWorkerRun(t *testing.T) {
 			handlerCalledCount: 2,
 		},
 		{
-			name: "discard after max retries",
+			name: "dead-letter after max retries",
 			setupMocks: func(s *mockService) {
 				// this means handle will be called 3 times
 				s.On("Handle", mock.Anything, mock.AnythingOfType("Notification")).Return(errors.New("test error"))
 				s.On("Handle", mock.Anything, mock.AnythingOfType("Notification")).Return(errors.New("test error"))
 				s.On("Handle", mock.Anything, mock.AnythingOfType("Notification")).Return(errors.New("test error"))
+				// the submission is only failed on the last attempt
+				s.On("HandleFailure", mock.Anything, mock.AnythingOfType("Notification"), mock.Anything).Once()
 			},
 			message: shcore.Notification{
 				VacancyRecord: shcore.VacancyRecord{
@@ -85,6 +93,20 @@ func TestWorkerRun(t *testing.T) {
 			},
 			handlerCalledCount: 3,
 		},
+		{
+			name: "dead-letter permanent failure without retry",
+			setupMocks: func(s *mockService) {
+				// this means handle will be called only once
+				s.On("Handle", mock.Anything, mock.AnythingOfType("Notification")).Return(shcore.NewBadRequestError("invalid recipient")).Once()
+				s.On("HandleFailure", mock.Anything, mock.AnythingOfType("Notification"), mock.Anything).Once()
+			},
+			message: shcore.Notification{
+				VacancyRecord: shcore.VacancyRecord{
+					Vacancy: shcore.Vacancy{JobTitle: "Software Engineer"},
+				},
+			},
+			handlerCalledCount: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -104,6 +126,8 @@ func TestWorkerRun(t *testing.T) {
 			consumer, err := rmq.NewConsumer(rmq.ConsumerConfig{
 				RabbitMQConnString: mqConn,
 				QueueName:          queueName,
+				// keep the retries fast so they happen before the test times out
+				RetryBaseDelay: 100 * time.Millisecond,
 			})
 			require.NoError(t, err)
 			defer consumer.Close()
@@ -118,18 +142,20 @@ func TestWorkerRun(t *testing.T) {
 			// Create worker
 			w := &Worker{
 				Config: Config{
-					Service:      mockService,
-					RmqConsumer:  consumer,
-					RmqPublisher: publisher,
+					Service:     mockService,
+					RmqConsumer: consumer,
 				},
 			}
 
 			// Channel to signal worker completion
 			done := make(chan error)
 
-			// Start worker in goroutine
+			// Start worker in goroutine, it is only stopped once the test
+			// is done so it can't be mistaken for unexpected stop
+			runCtx, stopWorker := context.WithCancel(context.Background())
+			defer stopWorker()
 			go func() {
-				done <- w.Run()
+				done <- w.Run(runCtx)
 			}()
 
 			// Publish test message
@@ -151,3 +177,78 @@ func TestWorkerRun(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkerRunShutdown(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mqConn := env.GetString(testutil.EnvKeyRabbitMQConn)
+	queueName := env.GetString(testutil.EnvKeyRabbitMQWaQueueName)
+	consumer, err := rmq.NewConsumer(rmq.ConsumerConfig{
+		RabbitMQConnString: mqConn,
+		QueueName:          queueName,
+		// cancel the in-flight handler quickly on shutdown
+		ShutdownTimeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	publisher, err := rmq.NewPublisher(rmq.PublisherConfig{
+		QueueName:          queueName,
+		RabbitMQConnString: mqConn,
+	})
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	// the handler only returns once it is canceled by shutdown
+	handling := make(chan struct{})
+	mockService := &mockService{}
+	mockService.On("Handle", mock.Anything, mock.AnythingOfType("Notification")).
+		Run(func(args mock.Arguments) {
+			close(handling)
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(context.Canceled).Once()
+
+	w := &Worker{
+		Config: Config{
+			Service:     mockService,
+			RmqConsumer: consumer,
+		},
+	}
+	runCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	done := make(chan error)
+	go func() {
+		done <- w.Run(runCtx)
+	}()
+
+	err = publisher.Publish(ctx, shcore.Notification{
+		VacancyRecord: shcore.VacancyRecord{
+			Vacancy: shcore.Vacancy{JobTitle: "Software Engineer"},
+		},
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-handling:
+	case <-ctx.Done():
+		t.Fatal("message is not handled")
+	}
+	stopWorker()
+	require.NoError(t, <-done)
+	mockService.AssertExpectations(t)
+
+	// the message is back in the queue without using up its attempt
+	conn, err := amqp.Dial(mqConn)
+	require.NoError(t, err)
+	defer conn.Close()
+	ch, err := conn.Channel()
+	require.NoError(t, err)
+	defer ch.Close()
+
+	d, ok, err := ch.Get(queueName, true)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 1, rmq.Attempt(rabbitmq.Delivery{Delivery: d}))
+}
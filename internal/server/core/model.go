@@ -1,14 +1,445 @@
 package core
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
 type ApprovalState string
 
 const (
 	ApprovalStatePending  ApprovalState = "pending"
 	ApprovalStateApproved ApprovalState = "approved"
 	ApprovalStateRejected ApprovalState = "rejected"
+	// ApprovalStateExpired is pending approval which nobody has decided on
+	// within the approval TTL
+	ApprovalStateExpired ApprovalState = "expired"
+)
+
+// ApprovalKind tells how the approval request was sent to admin.
+type ApprovalKind string
+
+const (
+	// ApprovalKindSingle is approval request sent in its own email, the
+	// decision is replied to that email
+	ApprovalKindSingle ApprovalKind = "single"
+	// ApprovalKindBulk is an item of bulk approval request, the items of
+	// the same request share the same batch id
+	ApprovalKindBulk ApprovalKind = "bulk"
 )
 
 type ApprovalRequest struct {
 	TokenRequest string
 	MessageID    string
+	// RejectReason is only used on rejection, it is either one of the
+	// predefined reasons or free text typed by admin
+	RejectReason RejectReason
+}
+
+// ApprovalToken is the decoded approval token.
+type ApprovalToken struct {
+	// ID is unique for every issued token, it is used to make sure the
+	// token is only used once
+	ID        string
+	Request   core.SubmitRequest
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// RevokeTokensRequest revokes the given token, when it is empty all
+// outstanding tokens are revoked instead.
+type RevokeTokensRequest struct {
+	TokenRequest string
+}
+
+type RejectReason string
+
+const (
+	RejectReasonDuplicate       RejectReason = "duplicate"
+	RejectReasonNotRemote       RejectReason = "not_remote"
+	RejectReasonNotForIndonesia RejectReason = "not_for_indonesia"
+	RejectReasonSpam            RejectReason = "spam"
+	RejectReasonBrokenLink      RejectReason = "broken_link"
+
+	maxRejectReasonLength = 500
+)
+
+// RejectReasons lists the predefined reasons admin can pick from.
+var RejectReasons = []RejectReason{
+	RejectReasonDuplicate,
+	RejectReasonNotRemote,
+	RejectReasonNotForIndonesia,
+	RejectReasonSpam,
+	RejectReasonBrokenLink,
+}
+
+// Description returns human readable explanation of the reason, free text
+// reason is returned as it is.
+func (r RejectReason) Description() string {
+	switch r {
+	case RejectReasonDuplicate:
+		return "The vacancy has already been posted"
+	case RejectReasonNotRemote:
+		return "The vacancy is not a remote position"
+	case RejectReasonNotForIndonesia:
+		return "The vacancy is not open for candidates in Indonesia"
+	case RejectReasonSpam:
+		return "The submission is considered as spam"
+	case RejectReasonBrokenLink:
+		return "The apply link is broken or no longer available"
+	default:
+		return string(r)
+	}
+}
+
+// EditApprovalRequest approves a pending vacancy after replacing its
+// details with the corrected version from admin.
+type EditApprovalRequest struct {
+	TokenRequest string
+	MessageID    string
+	Vacancy      core.Vacancy
+}
+
+// BatchApprovalRequest decides on the pending items of bulk approval
+// request at once.
+type BatchApprovalRequest struct {
+	TokenRequest string
+	BatchID      string
+	// MessageIDs limits the decision to the selected items, every pending
+	// item of the batch is decided when it is empty
+	MessageIDs []string
+	// RejectReason is only used on rejection
+	RejectReason RejectReason
+}
+
+// BatchApprovalResult lists the items decided by batch approval request.
+type BatchApprovalResult struct {
+	BatchID    string        `json:"batch_id"`
+	State      ApprovalState `json:"state"`
+	MessageIDs []string      `json:"message_ids"`
+}
+
+// bulkMessageIDSuffix is the domain part of the message id of bulk
+// approval email, the local part is the batch id.
+const bulkMessageIDSuffix = "@bulk"
+
+// BulkApprovalMessageID returns the message id of bulk approval email.
+func BulkApprovalMessageID(batchID string) string {
+	return batchID + bulkMessageIDSuffix
+}
+
+// EmailReply is a reply of admin to approval email, the first line of the
+// body is the command, either `approve` or `reject <reason>`.
+type EmailReply struct {
+	// From is the address of the sender, it must be one of admin emails
+	From string
+	// InReplyTo is the message id of the replied approval email
+	InReplyTo string
+	// Body is the plain text body of the reply
+	Body string
+	// SenderVerified tells whether the inbound mail provider has verified
+	// that the reply is really sent from From address (e.g DKIM or DMARC
+	// pass), From header alone can be forged by anyone
+	SenderVerified bool
+}
+
+type SubmitResult struct {
+	SubmissionID string `json:"submission_id,omitempty"`
+	// SubmissionIDs is only set for bulk request, the order follows
+	// the order of submitted vacancies excluding the skipped ones
+	SubmissionIDs []string `json:"submission_ids,omitempty"`
+	// SkippedVacancies is only set for bulk request, it lists the
+	// vacancies which are not submitted since they are duplicate
+	SkippedVacancies []SkippedVacancy `json:"skipped_vacancies,omitempty"`
+}
+
+// SkippedVacancy is a vacancy of bulk request which is not submitted.
+type SkippedVacancy struct {
+	// Index is the position of the vacancy in the bulk request, starting
+	// from 0
+	Index    int    `json:"index"`
+	ApplyURL string `json:"apply_url"`
+	Reason   string `json:"reason"`
+}
+
+type PreviewRequest struct {
+	ApplyURL string `json:"apply_url"`
+}
+
+// PreviewResult is the vacancy resolved from the apply url, the submitter
+// may correct it before submitting it as manual vacancy.
+type PreviewResult struct {
+	Vacancy core.Vacancy `json:"vacancy"`
+	// Parser is the name of the parser which handled the apply url
+	Parser string `json:"parser"`
+}
+
+// ApprovalRecord is an approval request as stored in approval storage.
+type ApprovalRecord struct {
+	MessageID string
+	State     ApprovalState
+	Kind      ApprovalKind
+	// BatchID is the id of the bulk approval request the item belongs to,
+	// it is only set for bulk kind
+	BatchID string
+	Request core.SubmitRequest
+	// OriginalRequest is the request as submitted, it is only set when
+	// admin has edited the request before approving it
+	OriginalRequest *core.SubmitRequest
+	RejectReason    RejectReason
+	CreatedAt       time.Time
+	// Token is the approval token for acting on the request from admin
+	// dashboard & digest, the service issues a new one when it is missing
+	// or about to expire.
+	Token string
+}
+
+// Age returns how long the approval request has been waiting.
+func (r ApprovalRecord) Age() time.Duration {
+	return time.Since(r.CreatedAt)
+}
+
+// ExpiresAt returns when the pending approval expires given the approval TTL.
+func (r ApprovalRecord) ExpiresAt(ttl time.Duration) time.Time {
+	return r.CreatedAt.Add(ttl)
+}
+
+// ApprovalSource tells where the vacancy waiting for approval comes from.
+type ApprovalSource string
+
+const (
+	// ApprovalSourceForm is manual vacancy, usually submitted from form
+	ApprovalSourceForm ApprovalSource = "form"
+	// ApprovalSourceURL is vacancy submitted by its apply url
+	ApprovalSourceURL ApprovalSource = "url"
+	// ApprovalSourceCrawler is an item of bulk submission from crawler
+	ApprovalSourceCrawler ApprovalSource = "crawler"
+)
+
+// ApprovalSources lists the sources in the order they are presented to admin.
+var ApprovalSources = []ApprovalSource{
+	ApprovalSourceForm,
+	ApprovalSourceURL,
+	ApprovalSourceCrawler,
+}
+
+// Source returns where the vacancy of the approval comes from.
+func (r ApprovalRecord) Source() ApprovalSource {
+	switch {
+	case r.Kind == ApprovalKindBulk:
+		return ApprovalSourceCrawler
+	case r.Request.SubmissionType == core.SubmitTypeURL:
+		return ApprovalSourceURL
+	default:
+		return ApprovalSourceForm
+	}
+}
+
+// PendingDigest summarizes every pending approval for admin, the approvals
+// are grouped by source then by age.
+type PendingDigest struct {
+	Total  int
+	Groups []PendingDigestGroup
+}
+
+type PendingDigestGroup struct {
+	Source ApprovalSource
+	// AgeLabel is human readable age range of the approvals, e.g `1 to 3 days`
+	AgeLabel  string
+	Approvals []ApprovalRecord
+}
+
+// digestAgeGroups are the age ranges of pending digest from the oldest,
+// zero MinAge matches every age.
+var digestAgeGroups = []struct {
+	MinAge time.Duration
+	Label  string
+}{
+	{MinAge: 7 * 24 * time.Hour, Label: "more than 7 days"},
+	{MinAge: 3 * 24 * time.Hour, Label: "3 to 7 days"},
+	{MinAge: 24 * time.Hour, Label: "1 to 3 days"},
+	{MinAge: 0, Label: "less than 1 day"},
+}
+
+// NewPendingDigest groups the approvals by source then by age, the oldest
+// age group comes first and empty groups are skipped.
+func NewPendingDigest(approvals []ApprovalRecord) PendingDigest {
+	digest := PendingDigest{Total: len(approvals)}
+	for _, source := range ApprovalSources {
+		groups := make([]PendingDigestGroup, len(digestAgeGroups))
+		for _, rec := range approvals {
+			if rec.Source() != source {
+				continue
+			}
+			for i, ageGroup := range digestAgeGroups {
+				if rec.Age() >= ageGroup.MinAge {
+					groups[i].Approvals = append(groups[i].Approvals, rec)
+					break
+				}
+			}
+		}
+
+		for i, group := range groups {
+			if len(group.Approvals) == 0 {
+				continue
+			}
+			group.Source = source
+			group.AgeLabel = digestAgeGroups[i].Label
+			digest.Groups = append(digest.Groups, group)
+		}
+	}
+	return digest
+}
+
+// ApprovalFilter narrows down pending approvals, zero value fields are ignored.
+type ApprovalFilter struct {
+	SubmissionEmail string
+	SubmissionType  core.SubmitType
+	BatchID         string
+	// MinAge only includes approvals waiting at least this long
+	MinAge time.Duration
+	// MaxAge only includes approvals waiting at most this long
+	MaxAge time.Duration
+}
+
+type APIKeyScope string
+
+const (
+	APIKeyScopeManual APIKeyScope = "manual"
+	APIKeyScopeURL    APIKeyScope = "url"
+	APIKeyScopeBulk   APIKeyScope = "bulk"
+	APIKeyScopeAdmin  APIKeyScope = "admin"
+)
+
+// APIKeyScopes lists every scope that can be granted to an API key.
+var APIKeyScopes = []APIKeyScope{
+	APIKeyScopeManual,
+	APIKeyScopeURL,
+	APIKeyScopeBulk,
+	APIKeyScopeAdmin,
+}
+
+// SubmitScope returns the scope needed to submit vacancy with the given
+// submission type, unknown type is handled as manual submission.
+func SubmitScope(submitType core.SubmitType) APIKeyScope {
+	switch submitType {
+	case core.SubmitTypeURL:
+		return APIKeyScopeURL
+	case core.SubmitTypeBulk:
+		return APIKeyScopeBulk
+	default:
+		return APIKeyScopeManual
+	}
+}
+
+// APIKey is a named client key, the raw key has the format of
+// `<id>.<secret>` and only the hash of the secret is stored.
+type APIKey struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Scopes []APIKeyScope `json:"scopes"`
+	// DefaultSubmissionEmail is used as submission email when the submitted
+	// request doesn't have one
+	DefaultSubmissionEmail string     `json:"default_submission_email,omitempty"`
+	UsageCount             int64      `json:"usage_count"`
+	CreatedAt              time.Time  `json:"created_at"`
+	LastUsedAt             *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt              *time.Time `json:"revoked_at,omitempty"`
+	SecretHash             string     `json:"-"`
+}
+
+func (k APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name                   string        `json:"name"`
+	Scopes                 []APIKeyScope `json:"scopes"`
+	DefaultSubmissionEmail string        `json:"default_submission_email"`
+}
+
+type CreateAPIKeyResult struct {
+	APIKey
+	// Key is the raw key for X-Api-Key header, it cannot be retrieved
+	// again after creation
+	Key string `json:"key"`
+}
+
+// RateLimit is a token bucket allowing Limit requests per Period, the
+// bucket is refilled continuously so the requests can't burst beyond Limit.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRateLimit parses rate limit in the format of `<limit>/<period>`,
+// e.g `30/1h`, empty value results in disabled rate limit.
+func ParseRateLimit(val string) (RateLimit, error) {
+	if val == "" {
+		return RateLimit{}, nil
+	}
+
+	limitStr, periodStr, ok := strings.Cut(val, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected format limit/period", val)
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, limit must be positive number", val)
+	}
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, period must be positive duration", val)
+	}
+
+	return RateLimit{Limit: limit, Period: period}, nil
+}
+
+func (l RateLimit) IsEnabled() bool {
+	return l.Limit > 0 && l.Period > 0
+}
+
+// Take consumes n tokens from a bucket which had the given tokens at the
+// last update. It returns the tokens left in the bucket, and when the
+// bucket doesn't have enough tokens, how long to wait until it has.
+func (l RateLimit) Take(tokens float64, lastUpdate, now time.Time, n int) (float64, time.Duration, bool) {
+	tokenInterval := l.Period / time.Duration(l.Limit)
+	elapsed := now.Sub(lastUpdate)
+	if elapsed > 0 {
+		tokens += float64(elapsed) / float64(tokenInterval)
+	}
+	if tokens > float64(l.Limit) {
+		tokens = float64(l.Limit)
+	}
+
+	if tokens < float64(n) {
+		retryAfter := time.Duration((float64(n) - tokens) * float64(tokenInterval))
+		return tokens, retryAfter, false
+	}
+
+	return tokens - float64(n), 0, true
+}
+
+// IdempotencyRecord is a submission stored under the client supplied
+// idempotency key.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	// Result is the JSON encoded result of the submission, it is nil when
+	// the submission is still being processed
+	Result    []byte
+	CreatedAt time.Time
+}
+
+func (r IdempotencyRecord) IsCompleted() bool {
+	return r.Result != nil
 }
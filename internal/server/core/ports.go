@@ -2,12 +2,16 @@ package core
 
 import (
 	"context"
+	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 )
 
 type VacancyResolver interface {
 	Resolve(ctx context.Context, url string) (*core.Vacancy, error)
+	// ResolveWithParser is like Resolve, but it also returns the name of
+	// the parser which handled the url
+	ResolveWithParser(ctx context.Context, url string) (*core.Vacancy, string, error)
 }
 
 type Queue interface {
@@ -16,14 +20,24 @@ type Queue interface {
 
 type EmailClient interface {
 	SendApprovalRequest(ctx context.Context, req core.SubmitRequest, tokenReq string) (string, error)
-	SendBulkApprovalRequest(ctx context.Context, req core.SubmitRequest, tokenReqVacancies []string) ([]string, error)
+	// SendBulkApprovalRequest sends the vacancies in a single email along
+	// with the batch actions, it returns the message id of each vacancy
+	SendBulkApprovalRequest(ctx context.Context, req core.SubmitRequest, batchID string, tokenReqBatch string, tokenReqVacancies []string) ([]string, error)
 	ApproveRequest(ctx context.Context, messageID string) error
-	RejectRequest(ctx context.Context, messageID string) error
+	RejectRequest(ctx context.Context, messageID string, reason RejectReason) error
+	// SendSubmitterRejection tells the submitter why the vacancy is rejected
+	SendSubmitterRejection(ctx context.Context, req core.SubmitRequest, reason RejectReason) error
+	// SendExpiryDigest tells admin the pending approvals which are about to
+	// expire, each approval expires ttl after it is created
+	SendExpiryDigest(ctx context.Context, approvals []ApprovalRecord, ttl time.Duration) error
+	// SendPendingDigest sends admin every pending approval in a single email,
+	// the approvals carry fresh tokens for the approve & reject links
+	SendPendingDigest(ctx context.Context, digest PendingDigest) error
 }
 
 type Tokenizer interface {
 	EncodeRequest(req core.SubmitRequest) (string, error)
-	DecodeToken(tokenStr string) (core.SubmitRequest, error)
+	DecodeToken(tokenStr string) (ApprovalToken, error)
 }
 
 type Approval interface {
@@ -31,8 +45,88 @@ type Approval interface {
 }
 
 type ApprovalStorage interface {
-	GetApprovalState(ctx context.Context, messageID string) (ApprovalState, error)
+	// UpdateApprovalState moves the pending approval to the given state, it
+	// returns conflict error when the approval is no longer pending
 	UpdateApprovalState(ctx context.Context, messageID string, state ApprovalState) error
+	// RejectApproval updates the pending approval state to rejected along
+	// with the reason, it returns conflict error when the approval is no
+	// longer pending
+	RejectApproval(ctx context.Context, messageID string, reason RejectReason) error
 	SaveApprovalRequest(ctx context.Context, messageID string, req core.SubmitRequest) error
-	SaveBulkApprovalRequest(ctx context.Context, req core.SubmitRequest, messageIDs []string) error
+	// SaveBulkApprovalRequest saves the items of bulk approval request
+	// under the given batch id
+	SaveBulkApprovalRequest(ctx context.Context, batchID string, reqs []core.SubmitRequest, messageIDs []string) error
+	// UpdateApprovalRequest replaces the request data of a pending approval,
+	// the originally submitted request must be kept for audit
+	UpdateApprovalRequest(ctx context.Context, messageID string, req core.SubmitRequest) error
+	GetApproval(ctx context.Context, messageID string) (*ApprovalRecord, error)
+	// SaveApprovalToken keeps the token issued for acting on the approval
+	// outside approval email, so it can be reused instead of issuing more
+	SaveApprovalToken(ctx context.Context, messageID string, token string) error
+	// DecideBatchApprovals moves the pending items of the batch to the given
+	// state in a single transaction, only the items listed in messageIDs are
+	// decided when it is not empty. Nothing is decided when any listed item
+	// is no longer pending in the batch.
+	DecideBatchApprovals(ctx context.Context, batchID string, messageIDs []string, state ApprovalState, reason RejectReason) ([]ApprovalRecord, error)
+	// FindApprovalByApplyURL returns the latest pending or approved approval
+	// having the same normalized apply url, it returns nil when there is none
+	FindApprovalByApplyURL(ctx context.Context, applyURL string) (*ApprovalRecord, error)
+	ListPendingApprovals(ctx context.Context, filter ApprovalFilter) ([]ApprovalRecord, error)
+	// ExpireApprovals moves the pending approvals waiting at least minAge to
+	// expired state, it returns the expired approvals
+	ExpireApprovals(ctx context.Context, minAge time.Duration) ([]ApprovalRecord, error)
+}
+
+type VacancyStorage interface {
+	// FindByApplyURL returns the published vacancy having the same normalized
+	// apply url, it returns nil when there is none
+	FindByApplyURL(ctx context.Context, applyURL string) (*core.VacancyRecord, error)
+}
+
+type TokenStorage interface {
+	// ConsumeToken marks the token as used, it returns false when the token
+	// has been used or revoked before
+	ConsumeToken(ctx context.Context, tokenID string) (bool, error)
+	RevokeToken(ctx context.Context, tokenID string) error
+	// IsTokenRevoked tells whether the token has been revoked by its id
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	// RevokeTokensIssuedBefore revokes every token issued at or before t
+	RevokeTokensIssuedBefore(ctx context.Context, t time.Time) error
+	// GetTokensRevokedBefore returns zero time when no tokens were revoked this way
+	GetTokensRevokedBefore(ctx context.Context) (time.Time, error)
+}
+
+type APIKeyStorage interface {
+	SaveAPIKey(ctx context.Context, key APIKey) error
+	// GetAPIKey returns not found error when the key doesn't exist
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	// TouchAPIKey records a usage of the key
+	TouchAPIKey(ctx context.Context, id string) error
+}
+
+type RateLimiter interface {
+	// Take consumes n tokens from the bucket identified by key, when the
+	// bucket doesn't have enough tokens it returns false along with how long
+	// to wait until it has
+	Take(ctx context.Context, key string, limit RateLimit, n int) (bool, time.Duration, error)
+}
+
+type IdempotencyStorage interface {
+	// ReserveIdempotencyKey stores the key along with the request hash when
+	// the key hasn't been used within ttl, otherwise it returns the record
+	// stored under the key
+	ReserveIdempotencyKey(ctx context.Context, key string, requestHash string, ttl time.Duration) (*IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the JSON encoded result of the request
+	CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error
+	// ReleaseIdempotencyKey removes the reservation so the request can be
+	// retried with the same key
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+type SubmissionStorage interface {
+	SaveSubmission(ctx context.Context, sub core.Submission) error
+	UpdateSubmission(ctx context.Context, upd core.SubmissionUpdate) error
+	GetSubmission(ctx context.Context, id string) (*core.Submission, error)
 }
@@ -2,26 +2,90 @@ package core
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	"gopkg.in/validator.v2"
 )
 
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+var (
+	// ErrSenderNotAdmin is returned when the email reply is not sent by admin.
+	ErrSenderNotAdmin = errors.New("sender is not admin")
+	// ErrSenderNotVerified is returned when the sender of the email reply
+	// has not been verified by the inbound mail provider.
+	ErrSenderNotVerified = errors.New("sender is not verified")
+)
+
+const maxAPIKeyNameLength = 255
+
 type Service interface {
-	HandleRequest(ctx context.Context, req core.SubmitRequest) error
+	HandleRequest(ctx context.Context, req core.SubmitRequest) (*SubmitResult, error)
+	// PreviewVacancy resolves the apply url without submitting the vacancy
+	PreviewVacancy(ctx context.Context, req PreviewRequest) (*PreviewResult, error)
 	HandleApprove(ctx context.Context, approvalReq ApprovalRequest) error
 	HandleReject(ctx context.Context, approvalReq ApprovalRequest) error
+	HandleEditApprove(ctx context.Context, editReq EditApprovalRequest) error
+	// HandleApproveBatch approves the pending items of bulk approval request
+	HandleApproveBatch(ctx context.Context, batchReq BatchApprovalRequest) (*BatchApprovalResult, error)
+	// HandleRejectBatch rejects the pending items of bulk approval request
+	HandleRejectBatch(ctx context.Context, batchReq BatchApprovalRequest) (*BatchApprovalResult, error)
+	// IssueBatchToken issues a fresh token for deciding on the batch
+	IssueBatchToken(batchID string) (string, error)
+	// HandleEmailReply approves or rejects the replied approval email
+	// following the command in the reply, it returns ErrSenderNotAdmin when
+	// the reply is not sent by admin and ErrSenderNotVerified when the
+	// sender has not been verified
+	HandleEmailReply(ctx context.Context, reply EmailReply) error
+	// ExpireStaleApprovals expires the pending approvals older than the
+	// approval TTL, when digestWithin is positive admin is also sent the
+	// approvals which will expire within that duration. It returns the
+	// number of expired approvals.
+	ExpireStaleApprovals(ctx context.Context, digestWithin time.Duration) (int, error)
+	// SendPendingDigest sends admin the digest of every pending approval, it
+	// returns the number of approvals in the digest. Nothing is sent when
+	// there is no pending approval.
+	SendPendingDigest(ctx context.Context) (int, error)
+	RevokeTokens(ctx context.Context, revokeReq RevokeTokensRequest) error
+	GetSubmission(ctx context.Context, id string) (*core.Submission, error)
+	GetApproval(ctx context.Context, messageID string) (*ApprovalRecord, error)
+	ListPendingApprovals(ctx context.Context, filter ApprovalFilter) ([]ApprovalRecord, error)
+	// AuthenticateAPIKey returns ErrInvalidAPIKey when the key is unknown,
+	// malformed or revoked
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (*APIKey, error)
+	CreateAPIKey(ctx context.Context, req CreateAPIKeyRequest) (*CreateAPIKeyResult, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
 }
 
 type ServiceConfig struct {
-	VacancyResolver VacancyResolver `validate:"nonnil"`
-	Queue           Queue           `validate:"nonnil"`
-	Email           EmailClient     `validate:"nonnil"`
-	Tokenizer       Tokenizer       `validate:"nonnil"`
-	Approval        Approval        `validate:"nonnil"`
-	ApprovalStorage ApprovalStorage `validate:"nonnil"`
+	VacancyResolver   VacancyResolver   `validate:"nonnil"`
+	Queue             Queue             `validate:"nonnil"`
+	Email             EmailClient       `validate:"nonnil"`
+	Tokenizer         Tokenizer         `validate:"nonnil"`
+	Approval          Approval          `validate:"nonnil"`
+	ApprovalStorage   ApprovalStorage   `validate:"nonnil"`
+	SubmissionStorage SubmissionStorage `validate:"nonnil"`
+	VacancyStorage    VacancyStorage    `validate:"nonnil"`
+	TokenStorage      TokenStorage      `validate:"nonnil"`
+	APIKeyStorage     APIKeyStorage     `validate:"nonnil"`
+
+	// AdminEmails are the addresses allowed to decide on approval by
+	// replying the approval email, the reply is always refused when empty
+	AdminEmails []string
+
+	// ApprovalTTL is how long approval stays pending before it is expired,
+	// pending approvals never expire when it is zero
+	ApprovalTTL time.Duration
 }
 
 func NewService(cfg ServiceConfig) (Service, error) {
@@ -39,25 +103,54 @@ type service struct {
 	ServiceConfig
 }
 
-func (s *service) HandleRequest(ctx context.Context, req core.SubmitRequest) error {
+func (s *service) HandleRequest(ctx context.Context, req core.SubmitRequest) (*SubmitResult, error) {
 	err := req.Validate()
 	if err != nil {
-		return core.NewBadRequestError(fmt.Sprintf("invalid request: %v", err))
+		return nil, core.NewValidationError(err)
 	}
 
 	if req.SubmissionType == core.SubmitTypeBulk {
 		return s.handleBulkRequest(ctx, req)
 	}
 
+	err = s.checkDuplicate(ctx, req.Vacancy.ApplyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// register the submission so it can be tracked by the submitter
+	req.SubmissionID = generateSubmissionID()
+	err = s.SubmissionStorage.SaveSubmission(ctx, core.Submission{
+		ID:              req.SubmissionID,
+		State:           core.SubmissionStateReceived,
+		SubmissionType:  req.SubmissionType,
+		SubmissionEmail: req.SubmissionEmail,
+		ApplyURL:        req.Vacancy.ApplyURL,
+		APIKeyID:        req.APIKeyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save submission: %w", err)
+	}
+
 	// handle request
 	switch req.SubmissionType {
 	case core.SubmitTypeManual:
 	case core.SubmitTypeURL:
+		s.updateSubmission(ctx, core.SubmissionUpdate{
+			ID:    req.SubmissionID,
+			State: core.SubmissionStateResolving,
+		})
+
 		// resolve apply url to get vacancy details
 		v, err := s.VacancyResolver.Resolve(ctx, req.Vacancy.ApplyURL)
 		if err != nil {
+			s.updateSubmission(ctx, core.SubmissionUpdate{
+				ID:         req.SubmissionID,
+				State:      core.SubmissionStateFailed,
+				ErrMessage: err.Error(),
+			})
 			// the error will be determined by resolver implementation
-			return err
+			return nil, err
 		}
 
 		// update vacancy with resolved details
@@ -68,145 +161,927 @@ func (s *service) HandleRequest(ctx context.Context, req core.SubmitRequest) err
 		req.SubmissionType = core.SubmitTypeManual
 	}
 
+	result := &SubmitResult{SubmissionID: req.SubmissionID}
+
 	if s.Approval.NeedsApproval(req.SubmissionEmail) {
 		token, err := s.Tokenizer.EncodeRequest(req)
 		if err != nil {
-			return fmt.Errorf("failed to encode token: %w", err)
+			return nil, fmt.Errorf("failed to encode token: %w", err)
 		}
 
 		messageID, err := s.Email.SendApprovalRequest(ctx, req, token)
 		if err != nil {
-			return fmt.Errorf("failed to send approval request: %w", err)
+			return nil, fmt.Errorf("failed to send approval request: %w", err)
 		}
 
 		err = s.ApprovalStorage.SaveApprovalRequest(ctx, messageID, req)
 		if err != nil {
-			return fmt.Errorf("failed to save approval request: %w", err)
+			return nil, fmt.Errorf("failed to save approval request: %w", err)
 		}
 
-		return nil
+		s.updateSubmission(ctx, core.SubmissionUpdate{
+			ID:    req.SubmissionID,
+			State: core.SubmissionStatePendingApproval,
+		})
+
+		return result, nil
 	}
 
-	err = s.Queue.Put(ctx, req)
+	err = s.queueRequest(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to put request in queue: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return result, nil
 }
 
 func (s *service) HandleApprove(ctx context.Context, approvalReq ApprovalRequest) error {
-	req, err := s.Tokenizer.DecodeToken(approvalReq.TokenRequest)
+	tok, err := s.decodeToken(ctx, approvalReq.TokenRequest)
 	if err != nil {
 		return err
 	}
+	req := tok.Request
 
 	err = req.Validate()
 	if err != nil {
-		return core.NewBadRequestError(fmt.Sprintf("invalid request: %v", err))
+		return core.NewValidationError(err)
 	}
 
+	var approval *ApprovalRecord
 	if approvalReq.MessageID != "" {
-		approvalState, err := s.ApprovalStorage.GetApprovalState(ctx, approvalReq.MessageID)
+		approval, err = s.ensureApprovalPending(ctx, approvalReq.MessageID)
 		if err != nil {
 			return err
 		}
+	}
 
-		if approvalState != ApprovalStatePending {
-			return core.NewBadRequestError("approval already processed")
-		}
+	err = s.consumeToken(ctx, tok)
+	if err != nil {
+		return err
+	}
+
+	return s.approve(ctx, approval, req)
+}
+
+func (s *service) HandleEditApprove(ctx context.Context, editReq EditApprovalRequest) error {
+	// edit is only possible for tracked approvals, otherwise there is
+	// nowhere to keep the original version
+	if editReq.MessageID == "" {
+		return core.NewBadRequestError("message id is required")
+	}
+
+	tok, err := s.decodeToken(ctx, editReq.TokenRequest)
+	if err != nil {
+		return err
+	}
+	req := tok.Request
+
+	// the edited vacancy is complete, so mark the request as manual to
+	// prevent the worker from resolving the apply url again
+	err = editReq.Vacancy.Validate()
+	if err != nil {
+		return core.NewValidationError(err)
+	}
+	req.Vacancy = editReq.Vacancy
+	req.SubmissionType = core.SubmitTypeManual
+
+	approval, err := s.ensureApprovalPending(ctx, editReq.MessageID)
+	if err != nil {
+		return err
+	}
+
+	err = s.consumeToken(ctx, tok)
+	if err != nil {
+		return err
+	}
+
+	err = s.ApprovalStorage.UpdateApprovalRequest(ctx, editReq.MessageID, req)
+	if err != nil {
+		return fmt.Errorf("failed to update approval request: %w", err)
+	}
+
+	return s.approve(ctx, approval, req)
+}
 
-		err = s.ApprovalStorage.UpdateApprovalState(ctx, approvalReq.MessageID, ApprovalStateApproved)
+// approve marks the pending approval as approved then puts the request in
+// queue, approval is nil for requests approved outside approval email.
+func (s *service) approve(ctx context.Context, approval *ApprovalRecord, req core.SubmitRequest) error {
+	if approval != nil {
+		err := s.ApprovalStorage.UpdateApprovalState(ctx, approval.MessageID, ApprovalStateApproved)
 		if err != nil {
 			return err
 		}
 
-		// if bulk request, we dont need to send approval email
-		// TODO: this is not the best way to check if this is a bulk request, use explicit function
-		if !strings.Contains(approvalReq.MessageID, "bulk") {
-			err = s.Email.ApproveRequest(ctx, approvalReq.MessageID)
+		// the items of bulk request share a single email, so there is
+		// no email to reply to
+		if approval.Kind != ApprovalKindBulk {
+			err = s.Email.ApproveRequest(ctx, approval.MessageID)
 			if err != nil {
 				return fmt.Errorf("failed to send approval request: %w", err)
 			}
 		}
 	}
 
-	err = s.Queue.Put(ctx, req)
+	return s.queueRequest(ctx, req)
+}
+
+// queueRequest puts the approved request in queue for the vacancy worker.
+// The submission is marked as approved before it is queued, otherwise the
+// worker may record its later states first & get them overwritten.
+func (s *service) queueRequest(ctx context.Context, req core.SubmitRequest) error {
+	s.updateSubmission(ctx, core.SubmissionUpdate{
+		ID:    req.SubmissionID,
+		State: core.SubmissionStateApproved,
+	})
+
+	err := s.Queue.Put(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to put request in queue: %w", err)
+		err = fmt.Errorf("failed to put request in queue: %w", err)
+		s.updateSubmission(ctx, core.SubmissionUpdate{
+			ID:         req.SubmissionID,
+			State:      core.SubmissionStateFailed,
+			ErrMessage: err.Error(),
+		})
+		return err
 	}
 
 	return nil
 }
 
 func (s *service) HandleReject(ctx context.Context, approvalReq ApprovalRequest) error {
-	req, err := s.Tokenizer.DecodeToken(approvalReq.TokenRequest)
+	tok, err := s.decodeToken(ctx, approvalReq.TokenRequest)
 	if err != nil {
 		return err
 	}
+	req := tok.Request
 
 	err = req.Validate()
 	if err != nil {
-		return core.NewBadRequestError(fmt.Sprintf("invalid request: %v", err))
+		return core.NewValidationError(err)
+	}
+
+	reason := RejectReason(strings.TrimSpace(string(approvalReq.RejectReason)))
+	if len(reason) > maxRejectReasonLength {
+		return core.NewBadRequestError(fmt.Sprintf("reject reason cannot be longer than %d characters", maxRejectReasonLength))
 	}
 
+	var approval *ApprovalRecord
 	if approvalReq.MessageID != "" {
-		approvalState, err := s.ApprovalStorage.GetApprovalState(ctx, approvalReq.MessageID)
+		approval, err = s.ensureApprovalPending(ctx, approvalReq.MessageID)
 		if err != nil {
 			return err
 		}
+	}
 
-		if approvalState != ApprovalStatePending {
-			return core.NewBadRequestError("approval already processed")
-		}
+	err = s.consumeToken(ctx, tok)
+	if err != nil {
+		return err
+	}
 
-		err = s.ApprovalStorage.UpdateApprovalState(ctx, approvalReq.MessageID, ApprovalStateRejected)
+	if approval != nil {
+		err = s.ApprovalStorage.RejectApproval(ctx, approval.MessageID, reason)
 		if err != nil {
 			return err
 		}
 
-		// if bulk request, we dont need to send rejection email
-		// TODO: this is not the best way to check if this is a bulk request, use explicit function
-		if !strings.Contains(approvalReq.MessageID, "bulk") {
-			err = s.Email.RejectRequest(ctx, approvalReq.MessageID)
+		// the items of bulk request share a single email, so there is
+		// no email to reply to
+		if approval.Kind != ApprovalKindBulk {
+			err = s.Email.RejectRequest(ctx, approval.MessageID, reason)
 			if err != nil {
 				return fmt.Errorf("failed to send approval request: %w", err)
 			}
 		}
 	}
 
+	s.updateSubmission(ctx, core.SubmissionUpdate{
+		ID:         req.SubmissionID,
+		State:      core.SubmissionStateRejected,
+		ErrMessage: reason.Description(),
+	})
+
+	s.notifySubmitterRejection(ctx, req, reason)
+
 	return nil
 }
 
-func (s *service) handleBulkRequest(ctx context.Context, bulkReq core.SubmitRequest) error {
-	tokenReqs := make([]string, 0)
+func (s *service) HandleApproveBatch(ctx context.Context, batchReq BatchApprovalRequest) (*BatchApprovalResult, error) {
+	recs, err := s.decideBatch(ctx, batchReq, ApprovalStateApproved, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// the items have been approved together, so keep queueing the rest
+	// when one of them fails
+	var failed int
+	for _, rec := range recs {
+		err = s.queueRequest(ctx, rec.Request)
+		if err != nil {
+			log.Printf("failed to queue request of approval %s: %v", rec.MessageID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return nil, fmt.Errorf("failed to put %d of %d requests in queue", failed, len(recs))
+	}
+
+	return newBatchApprovalResult(batchReq.BatchID, ApprovalStateApproved, recs), nil
+}
+
+func (s *service) HandleRejectBatch(ctx context.Context, batchReq BatchApprovalRequest) (*BatchApprovalResult, error) {
+	reason := RejectReason(strings.TrimSpace(string(batchReq.RejectReason)))
+	if len(reason) > maxRejectReasonLength {
+		return nil, core.NewBadRequestError(fmt.Sprintf("reject reason cannot be longer than %d characters", maxRejectReasonLength))
+	}
+
+	recs, err := s.decideBatch(ctx, batchReq, ApprovalStateRejected, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rec := range recs {
+		s.updateSubmission(ctx, core.SubmissionUpdate{
+			ID:         rec.Request.SubmissionID,
+			State:      core.SubmissionStateRejected,
+			ErrMessage: reason.Description(),
+		})
+
+		s.notifySubmitterRejection(ctx, rec.Request, reason)
+	}
+
+	return newBatchApprovalResult(batchReq.BatchID, ApprovalStateRejected, recs), nil
+}
+
+// decideBatch moves the pending items of the batch to the given state, the
+// token must be issued for the batch. The token is not consumed since the
+// same token is used by every batch action of the email, e.g approve the
+// selected items then reject the rest, deciding twice is prevented by only
+// deciding pending items instead.
+func (s *service) decideBatch(ctx context.Context, batchReq BatchApprovalRequest, state ApprovalState, reason RejectReason) ([]ApprovalRecord, error) {
+	if batchReq.BatchID == "" {
+		return nil, core.NewBadRequestError("batch id is required")
+	}
+
+	tok, err := s.decodeToken(ctx, batchReq.TokenRequest)
+	if err != nil {
+		return nil, err
+	}
+	if tok.Request.SubmissionType != core.SubmitTypeBulk || tok.Request.SubmissionID != batchReq.BatchID {
+		return nil, core.NewBadRequestError("token is not issued for the batch")
+	}
+
+	pending, err := s.ApprovalStorage.ListPendingApprovals(ctx, ApprovalFilter{BatchID: batchReq.BatchID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending approvals of batch: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil, core.NewBadRequestError("no pending approval in the batch")
+	}
+	pendingIDs := make(map[string]bool, len(pending))
+	for _, rec := range pending {
+		pendingIDs[rec.MessageID] = true
+	}
+	for _, messageID := range batchReq.MessageIDs {
+		if !pendingIDs[messageID] {
+			return nil, core.NewBadRequestError(fmt.Sprintf("approval %s is not pending in the batch", messageID))
+		}
+	}
+
+	revoked, err := s.TokenStorage.IsTokenRevoked(ctx, tok.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, core.NewBadRequestError("token has been revoked")
+	}
+
+	recs, err := s.ApprovalStorage.DecideBatchApprovals(ctx, batchReq.BatchID, batchReq.MessageIDs, state, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// IssueBatchToken issues token for the bulk approval request, the batch id
+// is kept as submission id so the token cannot be used for other batch. The
+// token stays usable while the batch has pending items until it expires or
+// is revoked.
+func (s *service) IssueBatchToken(batchID string) (string, error) {
+	token, err := s.Tokenizer.EncodeRequest(core.SubmitRequest{
+		SubmissionID:   batchID,
+		SubmissionType: core.SubmitTypeBulk,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token for batch %s: %w", batchID, err)
+	}
+
+	return token, nil
+}
+
+func (s *service) HandleEmailReply(ctx context.Context, reply EmailReply) error {
+	if !s.isAdminEmail(reply.From) {
+		return ErrSenderNotAdmin
+	}
+	if !reply.SenderVerified {
+		return ErrSenderNotVerified
+	}
+
+	messageID := strings.Trim(strings.TrimSpace(reply.InReplyTo), "<>")
+	if messageID == "" {
+		return core.NewBadRequestError("email is not a reply to approval email")
+	}
+
+	state, reason, err := parseEmailReplyCommand(reply.Body)
+	if err != nil {
+		return err
+	}
+
+	// the whole batch is decided when replying bulk approval email since
+	// there is no way to tell the items apart in a reply
+	if batchID, ok := strings.CutSuffix(messageID, bulkMessageIDSuffix); ok {
+		token, err := s.IssueBatchToken(batchID)
+		if err != nil {
+			return err
+		}
+		batchReq := BatchApprovalRequest{TokenRequest: token, BatchID: batchID, RejectReason: reason}
+		if state == ApprovalStateApproved {
+			_, err = s.HandleApproveBatch(ctx, batchReq)
+		} else {
+			_, err = s.HandleRejectBatch(ctx, batchReq)
+		}
+		return err
+	}
+
+	// the admin sender has been verified by the inbound mail provider, so
+	// the reply is as good as the token sent in the approval email
+	approval, err := s.GetApproval(ctx, messageID)
+	if err != nil {
+		return err
+	}
+	approvalReq := ApprovalRequest{
+		TokenRequest: approval.Token,
+		MessageID:    approval.MessageID,
+		RejectReason: reason,
+	}
+	if state == ApprovalStateApproved {
+		return s.HandleApprove(ctx, approvalReq)
+	}
+	return s.HandleReject(ctx, approvalReq)
+}
+
+func (s *service) isAdminEmail(addr string) bool {
+	addr = strings.TrimSpace(addr)
+	for _, adminEmail := range s.AdminEmails {
+		if adminEmail = strings.TrimSpace(adminEmail); adminEmail != "" && strings.EqualFold(adminEmail, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEmailReplyCommand parses the first line written by admin in the
+// reply, the quoted lines of the replied email are skipped.
+func parseEmailReplyCommand(body string) (ApprovalState, RejectReason, error) {
+	var line string
+	for _, l := range strings.Split(body, "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, ">") {
+			line = l
+			break
+		}
+	}
+
+	command, reason, _ := strings.Cut(line, " ")
+	switch strings.ToLower(strings.TrimRight(command, ".,!")) {
+	case "approve", "approved":
+		return ApprovalStateApproved, "", nil
+	case "reject", "rejected":
+		return ApprovalStateRejected, RejectReason(strings.TrimSpace(reason)), nil
+	default:
+		return "", "", core.NewBadRequestError("reply must start with `approve` or `reject <reason>`")
+	}
+}
+
+func newBatchApprovalResult(batchID string, state ApprovalState, recs []ApprovalRecord) *BatchApprovalResult {
+	result := &BatchApprovalResult{
+		BatchID:    batchID,
+		State:      state,
+		MessageIDs: make([]string, 0, len(recs)),
+	}
+	for _, rec := range recs {
+		result.MessageIDs = append(result.MessageIDs, rec.MessageID)
+	}
+	return result
+}
+
+// notifySubmitterRejection explains the rejection to the submitter, failure is
+// only logged since the rejection itself has been recorded.
+func (s *service) notifySubmitterRejection(ctx context.Context, req core.SubmitRequest, reason RejectReason) {
+	// we don't want to give feedback to spammers, the submission
+	// without real email address such as crawler also has no one to notify
+	if reason == RejectReasonSpam || !core.IsEmailAddress(req.SubmissionEmail) {
+		return
+	}
+
+	err := s.Email.SendSubmitterRejection(ctx, req, reason)
+	if err != nil {
+		log.Printf("failed to send rejection email to submitter %s: %v", req.SubmissionEmail, err)
+	}
+}
+
+func (s *service) RevokeTokens(ctx context.Context, revokeReq RevokeTokensRequest) error {
+	if revokeReq.TokenRequest == "" {
+		err := s.TokenStorage.RevokeTokensIssuedBefore(ctx, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to revoke outstanding tokens: %w", err)
+		}
+		return nil
+	}
+
+	tok, err := s.Tokenizer.DecodeToken(revokeReq.TokenRequest)
+	if err != nil {
+		return err
+	}
+
+	err = s.TokenStorage.RevokeToken(ctx, tok.ID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
+}
+
+// decodeToken decodes the approval token and makes sure it hasn't been
+// revoked in bulk, consuming the token is left to the caller.
+func (s *service) decodeToken(ctx context.Context, tokenStr string) (ApprovalToken, error) {
+	tok, err := s.Tokenizer.DecodeToken(tokenStr)
+	if err != nil {
+		return ApprovalToken{}, err
+	}
+
+	revokedBefore, err := s.TokenStorage.GetTokensRevokedBefore(ctx)
+	if err != nil {
+		return ApprovalToken{}, fmt.Errorf("failed to get token revocation: %w", err)
+	}
+	if !revokedBefore.IsZero() && !tok.IssuedAt.After(revokedBefore) {
+		return ApprovalToken{}, core.NewBadRequestError("token has been revoked")
+	}
+
+	return tok, nil
+}
+
+// consumeToken marks the token as used so it cannot be replayed.
+func (s *service) consumeToken(ctx context.Context, tok ApprovalToken) error {
+	ok, err := s.TokenStorage.ConsumeToken(ctx, tok.ID)
+	if err != nil {
+		return fmt.Errorf("failed to consume token: %w", err)
+	}
+	if !ok {
+		return core.NewBadRequestError("token has already been used or revoked")
+	}
+
+	return nil
+}
+
+// ensureApprovalPending returns the approval when it is still waiting for
+// decision, otherwise it returns bad request error.
+func (s *service) ensureApprovalPending(ctx context.Context, messageID string) (*ApprovalRecord, error) {
+	approval, err := s.ApprovalStorage.GetApproval(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch approval.State {
+	case ApprovalStatePending:
+	case ApprovalStateExpired:
+		return nil, core.NewBadRequestError("approval has expired, the vacancy needs to be submitted again")
+	default:
+		return nil, core.NewBadRequestError("approval already processed")
+	}
+
+	return approval, nil
+}
+
+func (s *service) ExpireStaleApprovals(ctx context.Context, digestWithin time.Duration) (int, error) {
+	if s.ApprovalTTL <= 0 {
+		return 0, nil
+	}
+
+	recs, err := s.ApprovalStorage.ExpireApprovals(ctx, s.ApprovalTTL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire approvals: %w", err)
+	}
+	for _, rec := range recs {
+		s.updateSubmission(ctx, core.SubmissionUpdate{
+			ID:         rec.Request.SubmissionID,
+			State:      core.SubmissionStateExpired,
+			ErrMessage: "approval has expired",
+		})
+	}
+
+	if digestWithin > 0 {
+		s.sendExpiryDigest(ctx, digestWithin)
+	}
+
+	return len(recs), nil
+}
+
+func (s *service) SendPendingDigest(ctx context.Context) (int, error) {
+	// the listed approvals already carry usable tokens
+	recs, err := s.ListPendingApprovals(ctx, ApprovalFilter{})
+	if err != nil {
+		return 0, err
+	}
+	if len(recs) == 0 {
+		return 0, nil
+	}
+
+	err = s.Email.SendPendingDigest(ctx, NewPendingDigest(recs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to send pending digest: %w", err)
+	}
+
+	return len(recs), nil
+}
+
+// sendExpiryDigest tells admin the approvals expiring within the given
+// duration, failure is only logged since it is merely a reminder.
+func (s *service) sendExpiryDigest(ctx context.Context, within time.Duration) {
+	recs, err := s.ApprovalStorage.ListPendingApprovals(ctx, ApprovalFilter{MinAge: s.ApprovalTTL - within})
+	if err != nil {
+		log.Printf("failed to list approvals about to expire: %v", err)
+		return
+	}
+	if len(recs) == 0 {
+		return
+	}
+
+	err = s.Email.SendExpiryDigest(ctx, recs, s.ApprovalTTL)
+	if err != nil {
+		log.Printf("failed to send expiry digest of %d approvals: %v", len(recs), err)
+	}
+}
+
+func (s *service) PreviewVacancy(ctx context.Context, req PreviewRequest) (*PreviewResult, error) {
+	applyURL := strings.TrimSpace(req.ApplyURL)
+	if applyURL == "" {
+		return nil, core.NewBadRequestError("missing `apply_url`")
+	}
+
+	// the error will be determined by resolver implementation
+	v, parser, err := s.VacancyResolver.ResolveWithParser(ctx, applyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreviewResult{Vacancy: *v, Parser: parser}, nil
+}
+
+func (s *service) GetSubmission(ctx context.Context, id string) (*core.Submission, error) {
+	sub, err := s.SubmissionStorage.GetSubmission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (s *service) GetApproval(ctx context.Context, messageID string) (*ApprovalRecord, error) {
+	rec, err := s.ApprovalStorage.GetApproval(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.attachApprovalToken(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+func (s *service) ListPendingApprovals(ctx context.Context, filter ApprovalFilter) ([]ApprovalRecord, error) {
+	recs, err := s.ApprovalStorage.ListPendingApprovals(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending approvals: %w", err)
+	}
+
+	for i := range recs {
+		err = s.attachApprovalToken(ctx, &recs[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return recs, nil
+}
+
+// attachApprovalToken makes sure the record carries a usable approval token
+// so admin can act on it through HandleApprove & HandleReject. The stored
+// token is reused until half of its lifetime has passed, so viewing the
+// dashboard & sending digest don't keep issuing untracked tokens.
+func (s *service) attachApprovalToken(ctx context.Context, rec *ApprovalRecord) error {
+	if rec.Token != "" && s.isTokenReusable(ctx, rec.Token) {
+		return nil
+	}
+
+	token, err := s.Tokenizer.EncodeRequest(rec.Request)
+	if err != nil {
+		return fmt.Errorf("failed to encode token for approval %s: %w", rec.MessageID, err)
+	}
+	err = s.ApprovalStorage.SaveApprovalToken(ctx, rec.MessageID, token)
+	if err != nil {
+		return fmt.Errorf("failed to save token for approval %s: %w", rec.MessageID, err)
+	}
+	rec.Token = token
+
+	return nil
+}
+
+// isTokenReusable tells whether the stored token is neither revoked nor
+// past half of its lifetime, in which case a new one is issued so the links
+// sent in digest don't expire shortly after.
+func (s *service) isTokenReusable(ctx context.Context, tokenStr string) bool {
+	tok, err := s.decodeToken(ctx, tokenStr)
+	if err != nil {
+		return false
+	}
+	if time.Until(tok.ExpiresAt) < tok.ExpiresAt.Sub(tok.IssuedAt)/2 {
+		return false
+	}
+
+	revoked, err := s.TokenStorage.IsTokenRevoked(ctx, tok.ID)
+	if err != nil {
+		log.Printf("failed to check revocation of token %s: %v", tok.ID, err)
+		return false
+	}
+
+	return !revoked
+}
+
+func (s *service) handleBulkRequest(ctx context.Context, bulkReq core.SubmitRequest) (*SubmitResult, error) {
+	// duplicates are skipped instead of rejecting the whole request, so a
+	// crawl which contains an already submitted vacancy still gets the new
+	// ones submitted
+	vacancies, skipped, err := s.filterBulkDuplicate(ctx, bulkReq.BulkVacancies)
+	if err != nil {
+		return nil, err
+	}
+	if len(vacancies) == 0 {
+		return &SubmitResult{SkippedVacancies: skipped}, nil
+	}
+	bulkReq.BulkVacancies = vacancies
+
+	batchID := generateSubmissionID()
+	batchToken, err := s.IssueBatchToken(batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	reqs := make([]core.SubmitRequest, 0, len(bulkReq.BulkVacancies))
+	tokenReqs := make([]string, 0, len(bulkReq.BulkVacancies))
 	for _, v := range bulkReq.BulkVacancies {
-		// for now we only support URL submission in bulk request
 		req := core.SubmitRequest{
-			SubmissionType:  core.SubmitTypeURL,
+			SubmissionID:    generateSubmissionID(),
+			SubmissionType:  v.Type(),
 			SubmissionEmail: bulkReq.SubmissionEmail,
-			Vacancy: core.Vacancy{
-				ApplyURL: v.ApplyURL,
-			},
+			APIKeyID:        bulkReq.APIKeyID,
+			Vacancy:         v.Vacancy,
+		}
+		if req.SubmissionType == core.SubmitTypeURL {
+			// the vacancy will be resolved from the apply url once approved
+			req.Vacancy = core.Vacancy{ApplyURL: v.ApplyURL}
 		}
 
 		token, err := s.Tokenizer.EncodeRequest(req)
 		if err != nil {
-			return fmt.Errorf("failed to encode token for vacancy %v: %w", v, err)
+			return nil, fmt.Errorf("failed to encode token for vacancy %v: %w", v, err)
 		}
+		reqs = append(reqs, req)
 		tokenReqs = append(tokenReqs, token)
 	}
 
+	// register each vacancy as its own submission
+	result := &SubmitResult{SubmissionIDs: make([]string, 0, len(reqs)), SkippedVacancies: skipped}
+	for _, req := range reqs {
+		err := s.SubmissionStorage.SaveSubmission(ctx, core.Submission{
+			ID:              req.SubmissionID,
+			State:           core.SubmissionStateReceived,
+			SubmissionType:  req.SubmissionType,
+			SubmissionEmail: req.SubmissionEmail,
+			ApplyURL:        req.Vacancy.ApplyURL,
+			APIKeyID:        req.APIKeyID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save submission: %w", err)
+		}
+		result.SubmissionIDs = append(result.SubmissionIDs, req.SubmissionID)
+	}
+
 	// For bulk request, we need to send approval request to admin
-	messageIDs, err := s.Email.SendBulkApprovalRequest(ctx, bulkReq, tokenReqs)
+	messageIDs, err := s.Email.SendBulkApprovalRequest(ctx, bulkReq, batchID, batchToken, tokenReqs)
 	if err != nil {
-		return fmt.Errorf("failed to send approval request: %w", err)
+		return nil, fmt.Errorf("failed to send approval request: %w", err)
 	}
 
-	err = s.ApprovalStorage.SaveBulkApprovalRequest(ctx, bulkReq, messageIDs)
+	err = s.ApprovalStorage.SaveBulkApprovalRequest(ctx, batchID, reqs, messageIDs)
 	if err != nil {
-		return fmt.Errorf("failed to save approval request: %w", err)
+		return nil, fmt.Errorf("failed to save approval request: %w", err)
 	}
 
-	return nil
+	for _, req := range reqs {
+		s.updateSubmission(ctx, core.SubmissionUpdate{
+			ID:    req.SubmissionID,
+			State: core.SubmissionStatePendingApproval,
+		})
+	}
+
+	return result, nil
+}
+
+// filterBulkDuplicate runs checkDuplicate on every vacancy of the bulk
+// request and returns the vacancies which are not duplicate, the vacancy
+// which is submitted twice within the request is only kept once.
+func (s *service) filterBulkDuplicate(ctx context.Context, vacancies []core.BulkVacancy) ([]core.BulkVacancy, []SkippedVacancy, error) {
+	var (
+		kept    = make([]core.BulkVacancy, 0, len(vacancies))
+		skipped []SkippedVacancy
+		seen    = make(map[string]int, len(vacancies))
+	)
+	for i, v := range vacancies {
+		normalizedURL := core.NormalizeApplyURL(v.ApplyURL)
+		if j, ok := seen[normalizedURL]; ok {
+			skipped = append(skipped, SkippedVacancy{
+				Index:    i,
+				ApplyURL: v.ApplyURL,
+				Reason:   fmt.Sprintf("vacancy has the same apply url as bulk vacancy at index %d", j),
+			})
+			continue
+		}
+		seen[normalizedURL] = i
+
+		err := s.checkDuplicate(ctx, v.ApplyURL)
+		if err != nil {
+			var shErr *core.Error
+			if errors.As(err, &shErr) && shErr.ErrCode == core.ErrCodeConflict {
+				skipped = append(skipped, SkippedVacancy{
+					Index:    i,
+					ApplyURL: v.ApplyURL,
+					Reason:   shErr.Message,
+				})
+				continue
+			}
+			return nil, nil, err
+		}
+		kept = append(kept, v)
+	}
+
+	return kept, skipped, nil
+}
+
+// checkDuplicate returns conflict error when the vacancy with the same
+// normalized apply url has already been published or is being approved.
+func (s *service) checkDuplicate(ctx context.Context, applyURL string) error {
+	vacancy, err := s.VacancyStorage.FindByApplyURL(ctx, applyURL)
+	if err != nil {
+		return fmt.Errorf("failed to find vacancy by apply url: %w", err)
+	}
+	if vacancy != nil {
+		return core.NewConflictError(fmt.Sprintf("vacancy has already been posted at %s", vacancy.PublicURL))
+	}
+
+	rec, err := s.ApprovalStorage.FindApprovalByApplyURL(ctx, applyURL)
+	if err != nil {
+		return fmt.Errorf("failed to find approval by apply url: %w", err)
+	}
+	if rec == nil {
+		return nil
+	}
+
+	// requests issued before tracking was introduced have no submission id
+	ref := fmt.Sprintf("submission id: %s", rec.Request.SubmissionID)
+	if rec.Request.SubmissionID == "" {
+		ref = fmt.Sprintf("approval message id: %s", rec.MessageID)
+	}
+	if rec.State == ApprovalStateApproved {
+		return core.NewConflictError(fmt.Sprintf("vacancy has already been approved (%s)", ref))
+	}
+	return core.NewConflictError(fmt.Sprintf("vacancy is already waiting for approval (%s)", ref))
+}
+
+// updateSubmission records the submission state transition, failure is only
+// logged since tracking must not break the submission flow itself.
+func (s *service) updateSubmission(ctx context.Context, upd core.SubmissionUpdate) {
+	// requests issued before tracking was introduced have no submission id
+	if upd.ID == "" {
+		return
+	}
+
+	err := s.SubmissionStorage.UpdateSubmission(ctx, upd)
+	if err != nil {
+		log.Printf("failed to update submission %s to %s: %v", upd.ID, upd.State, err)
+	}
+}
+
+func (s *service) AuthenticateAPIKey(ctx context.Context, rawKey string) (*APIKey, error) {
+	id, secret, ok := strings.Cut(rawKey, ".")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.APIKeyStorage.GetAPIKey(ctx, id)
+	if err != nil {
+		var shErr *core.Error
+		if errors.As(err, &shErr) && shErr.ErrCode == core.ErrCodeNotFound {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// usage accounting should not block the request
+	err = s.APIKeyStorage.TouchAPIKey(ctx, key.ID)
+	if err != nil {
+		log.Printf("failed to record usage of api key %s: %v", key.ID, err)
+	}
+
+	return key, nil
+}
+
+func (s *service) CreateAPIKey(ctx context.Context, req CreateAPIKeyRequest) (*CreateAPIKeyResult, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, core.NewBadRequestError("name is required")
+	}
+	if len(req.Name) > maxAPIKeyNameLength {
+		return nil, core.NewBadRequestError(fmt.Sprintf("name cannot be longer than %d characters", maxAPIKeyNameLength))
+	}
+	if len(req.Scopes) == 0 {
+		return nil, core.NewBadRequestError("scopes cannot be empty")
+	}
+	for _, scope := range req.Scopes {
+		if !isValidAPIKeyScope(scope) {
+			return nil, core.NewBadRequestError(fmt.Sprintf("invalid scope: %s", scope))
+		}
+	}
+
+	id := generateRandomHex(8)
+	secret := generateRandomHex(32)
+	key := APIKey{
+		ID:                     id,
+		Name:                   req.Name,
+		Scopes:                 req.Scopes,
+		DefaultSubmissionEmail: strings.TrimSpace(req.DefaultSubmissionEmail),
+		CreatedAt:              time.Now().UTC().Truncate(time.Second),
+		SecretHash:             hashAPIKeySecret(secret),
+	}
+	err := s.APIKeyStorage.SaveAPIKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save api key: %w", err)
+	}
+
+	return &CreateAPIKeyResult{
+		APIKey: key,
+		Key:    fmt.Sprintf("%s.%s", id, secret),
+	}, nil
+}
+
+func (s *service) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	return s.APIKeyStorage.ListAPIKeys(ctx)
+}
+
+func (s *service) RevokeAPIKey(ctx context.Context, id string) error {
+	return s.APIKeyStorage.RevokeAPIKey(ctx, id)
+}
+
+func isValidAPIKeyScope(scope APIKeyScope) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateRandomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func generateSubmissionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
@@ -2,7 +2,10 @@ package core_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -18,8 +21,16 @@ func TestServiceHandleRequest(t *testing.T) {
 		request    shcore.SubmitRequest
 	}{
 		{
-			name: "submission type bulk",
+			name: "submission type bulk - mixed manual & url vacancies",
 			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, a *mockApproval, s *mockApprovalStorage) {
+				var batchID string
+				tok.On("EncodeRequest", mock.MatchedBy(func(req shcore.SubmitRequest) bool {
+					if req.SubmissionType != shcore.SubmitTypeBulk || req.SubmissionID == "" {
+						return false
+					}
+					batchID = req.SubmissionID
+					return true
+				})).Return("mock-batch-token", nil)
 				tok.On("EncodeRequest", mock.MatchedBy(func(req shcore.SubmitRequest) bool {
 					return req.ApplyURL == "https://example.com/apply1"
 				})).Return("mock-token1", nil)
@@ -28,25 +39,39 @@ func TestServiceHandleRequest(t *testing.T) {
 				})).Return("mock-token2", nil)
 				e.On("SendBulkApprovalRequest", ctx, mock.MatchedBy(func(req shcore.SubmitRequest) bool {
 					return req.SubmissionEmail == "crawler"
-				}), []string{"mock-token1", "mock-token2"}).Return([]string{"mock-message-id1", "mock-message-id2"}, nil)
-				s.On("SaveBulkApprovalRequest", ctx, mock.MatchedBy(func(req shcore.SubmitRequest) bool {
-					return req.SubmissionEmail == "crawler"
+				}), mock.MatchedBy(func(id string) bool {
+					return id == batchID
+				}), "mock-batch-token", []string{"mock-token1", "mock-token2"}).Return([]string{"mock-message-id1", "mock-message-id2"}, nil)
+				s.On("SaveBulkApprovalRequest", ctx, mock.MatchedBy(func(id string) bool {
+					return id == batchID
+				}), mock.MatchedBy(func(reqs []shcore.SubmitRequest) bool {
+					return len(reqs) == 2 &&
+						reqs[0].SubmissionID != "" &&
+						reqs[0].SubmissionEmail == "crawler" &&
+						// manual vacancy keeps its pre-filled fields
+						reqs[0].SubmissionType == shcore.SubmitTypeManual &&
+						reqs[0].JobTitle == "Test 1" &&
+						reqs[0].CompanyName == "Test 1 Company" &&
+						// url vacancy is resolved once approved
+						reqs[1].SubmissionType == shcore.SubmitTypeURL &&
+						reqs[1].ApplyURL == "https://example.com/apply2" &&
+						reqs[1].JobTitle == ""
 				}), []string{"mock-message-id1", "mock-message-id2"}).Return(nil)
 			},
 			request: shcore.SubmitRequest{
 				SubmissionType:  shcore.SubmitTypeBulk,
 				SubmissionEmail: "crawler",
-				BulkVacancies: []shcore.Vacancy{
-					{
+				BulkVacancies: []shcore.BulkVacancy{
+					{SubmissionType: shcore.SubmitTypeManual, Vacancy: shcore.Vacancy{
 						JobTitle:    "Test 1",
 						CompanyName: "Test 1 Company",
 						ApplyURL:    "https://example.com/apply1",
-					},
-					{
+					}},
+					{Vacancy: shcore.Vacancy{
 						JobTitle:    "Test 2",
 						CompanyName: "Test 2 Company",
 						ApplyURL:    "https://example.com/apply2",
-					},
+					}},
 				},
 			},
 		},
@@ -70,7 +95,7 @@ func TestServiceHandleRequest(t *testing.T) {
 				Vacancy: shcore.Vacancy{
 					JobTitle:         "Software Engineer",
 					CompanyName:      "Test Company",
-					CompanyLocation:  "Test Location",
+					CompanyLocation:  "Jakarta, Indonesia",
 					ShortDescription: "Test Description",
 					RelevantTags:     []string{"test", "test2"},
 					ApplyURL:         "https://example.com/apply",
@@ -95,7 +120,9 @@ func TestServiceHandleRequest(t *testing.T) {
 				SubmissionType:  shcore.SubmitTypeManual,
 				SubmissionEmail: "submitter@example.com",
 				Vacancy: shcore.Vacancy{
-					ApplyURL: "https://example.com/apply",
+					JobTitle:    "Software Engineer",
+					CompanyName: "Test Company",
+					ApplyURL:    "https://example.com/apply",
 				},
 			},
 		},
@@ -119,7 +146,7 @@ func TestServiceHandleRequest(t *testing.T) {
 				Vacancy: shcore.Vacancy{
 					JobTitle:         "Software Engineer",
 					CompanyName:      "Test Company",
-					CompanyLocation:  "Test Location",
+					CompanyLocation:  "Jakarta, Indonesia",
 					ShortDescription: "Test Description",
 					RelevantTags:     []string{"test", "test2"},
 					ApplyURL:         "https://example.com/apply",
@@ -151,9 +178,6 @@ func TestServiceHandleRequest(t *testing.T) {
 		{
 			name: "submission type manual - submission email is whitelisted - no approval needed",
 			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, a *mockApproval, s *mockApprovalStorage) {
-				tok.On("EncodeRequest", mock.MatchedBy(func(req shcore.SubmitRequest) bool {
-					return req.SubmissionEmail == "approved@example.com"
-				})).Return("mock-token", nil)
 				a.On("NeedsApproval", "approved@example.com").Return(false)
 				q.On("Put", ctx, mock.MatchedBy(func(req shcore.SubmitRequest) bool {
 					return req.SubmissionEmail == "approved@example.com"
@@ -172,9 +196,6 @@ func TestServiceHandleRequest(t *testing.T) {
 		{
 			name: "submission type url - submission email is whitelisted - no approval needed",
 			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, a *mockApproval, s *mockApprovalStorage) {
-				tok.On("EncodeRequest", mock.MatchedBy(func(req shcore.SubmitRequest) bool {
-					return req.SubmissionEmail == "approved@example.com"
-				})).Return("mock-token", nil)
 				a.On("NeedsApproval", "approved@example.com").Return(false)
 				q.On("Put", ctx, mock.MatchedBy(func(req shcore.SubmitRequest) bool {
 					return req.SubmissionEmail == "approved@example.com"
@@ -184,7 +205,9 @@ func TestServiceHandleRequest(t *testing.T) {
 				SubmissionType:  shcore.SubmitTypeManual,
 				SubmissionEmail: "approved@example.com",
 				Vacancy: shcore.Vacancy{
-					ApplyURL: "https://example.com/apply",
+					JobTitle:    "Software Engineer",
+					CompanyName: "Test Company",
+					ApplyURL:    "https://example.com/apply",
 				},
 			},
 		},
@@ -201,22 +224,42 @@ func TestServiceHandleRequest(t *testing.T) {
 			mockTokenizer := &mockTokenizer{}
 			mockApproval := &mockApproval{}
 			mockApprovalStorage := &mockApprovalStorage{}
+			mockResolver := &mockVacancyResolver{}
+			mockSubmissionStorage := &mockSubmissionStorage{}
+			mockVacancyStorage := &mockVacancyStorage{}
 
 			service, err := core.NewService(core.ServiceConfig{
-				Queue:           mockQueue,
-				Email:           mockEmail,
-				Tokenizer:       mockTokenizer,
-				Approval:        mockApproval,
-				ApprovalStorage: mockApprovalStorage,
+				VacancyResolver:   mockResolver,
+				Queue:             mockQueue,
+				Email:             mockEmail,
+				Tokenizer:         mockTokenizer,
+				Approval:          mockApproval,
+				ApprovalStorage:   mockApprovalStorage,
+				SubmissionStorage: mockSubmissionStorage,
+				VacancyStorage:    mockVacancyStorage,
+				TokenStorage:      &mockTokenStorage{},  // not used
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
 			})
 			require.NoError(t, err)
 
 			tt.setupMocks(ctx, mockQueue, mockEmail, mockTokenizer, mockApproval, mockApprovalStorage)
+			mockResolver.resolveAny()
+			mockSubmissionStorage.acceptAny()
+			mockApprovalStorage.noDuplicate()
+			mockVacancyStorage.noDuplicate()
 
 			// Execute test
-			err = service.HandleRequest(ctx, tt.request)
+			result, err := service.HandleRequest(ctx, tt.request)
 			require.NoError(t, err)
 
+			// Verify every submitted vacancy is tracked
+			if tt.request.SubmissionType == shcore.SubmitTypeBulk {
+				require.Len(t, result.SubmissionIDs, len(tt.request.BulkVacancies))
+			} else {
+				require.NotEmpty(t, result.SubmissionID)
+			}
+			mockSubmissionStorage.AssertCalled(t, "SaveSubmission", ctx, mock.AnythingOfType("Submission"))
+
 			// Verify mock expectations
 			mockQueue.AssertExpectations(t)
 			mockEmail.AssertExpectations(t)
@@ -226,6 +269,388 @@ func TestServiceHandleRequest(t *testing.T) {
 	}
 }
 
+func TestServiceHandleRequestDuplicate(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupMocks func(a *mockApprovalStorage, v *mockVacancyStorage)
+		expMsg     string
+	}{
+		{
+			name: "vacancy already posted",
+			setupMocks: func(a *mockApprovalStorage, v *mockVacancyStorage) {
+				v.On("FindByApplyURL", mock.Anything, "https://example.com/apply/?utm_source=linkedin").Return(&shcore.VacancyRecord{
+					ID:        "page-id",
+					PublicURL: "https://idnremote.com/jobs/page-id",
+				}, nil)
+			},
+			expMsg: "vacancy has already been posted at https://idnremote.com/jobs/page-id",
+		},
+		{
+			name: "vacancy waiting for approval",
+			setupMocks: func(a *mockApprovalStorage, v *mockVacancyStorage) {
+				v.noDuplicate()
+				a.On("FindApprovalByApplyURL", mock.Anything, "https://example.com/apply/?utm_source=linkedin").Return(&core.ApprovalRecord{
+					MessageID: "msg-1",
+					State:     core.ApprovalStatePending,
+					Request:   shcore.SubmitRequest{SubmissionID: "sub-1"},
+				}, nil)
+			},
+			expMsg: "vacancy is already waiting for approval (submission id: sub-1)",
+		},
+		{
+			name: "vacancy already approved without submission id",
+			setupMocks: func(a *mockApprovalStorage, v *mockVacancyStorage) {
+				v.noDuplicate()
+				a.On("FindApprovalByApplyURL", mock.Anything, "https://example.com/apply/?utm_source=linkedin").Return(&core.ApprovalRecord{
+					MessageID: "msg-1",
+					State:     core.ApprovalStateApproved,
+				}, nil)
+			},
+			expMsg: "vacancy has already been approved (approval message id: msg-1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approvalStorage := &mockApprovalStorage{}
+			vacancyStorage := &mockVacancyStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             &mockQueue{},           // not used
+				Email:             &mockEmailClient{},     // not used
+				Tokenizer:         &mockTokenizer{},       // not used
+				Approval:          &mockApproval{},        // not used
+				ApprovalStorage:   approvalStorage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    vacancyStorage,
+				TokenStorage:      &mockTokenStorage{},  // not used
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+			})
+			require.NoError(t, err)
+
+			tt.setupMocks(approvalStorage, vacancyStorage)
+
+			_, err = svc.HandleRequest(context.Background(), shcore.SubmitRequest{
+				SubmissionType:  shcore.SubmitTypeURL,
+				SubmissionEmail: "submitter@example.com",
+				Vacancy: shcore.Vacancy{
+					ApplyURL: "https://example.com/apply/?utm_source=linkedin",
+				},
+			})
+			var shErr *shcore.Error
+			require.ErrorAs(t, err, &shErr)
+			require.Equal(t, shcore.ErrCodeConflict, shErr.ErrCode)
+			require.Equal(t, tt.expMsg, shErr.Message)
+
+			// duplicate must be rejected before the submission is registered
+			submissionStorage.AssertNotCalled(t, "SaveSubmission", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestServiceHandleBulkRequestDuplicate(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupMocks func(a *mockApprovalStorage, v *mockVacancyStorage)
+		vacancies  []shcore.BulkVacancy
+		expSkipped []core.SkippedVacancy
+	}{
+		{
+			name: "vacancy already posted",
+			setupMocks: func(a *mockApprovalStorage, v *mockVacancyStorage) {
+				a.noDuplicate()
+				v.On("FindByApplyURL", mock.Anything, "https://example.com/posted").Return(&shcore.VacancyRecord{
+					ID:        "page-id",
+					PublicURL: "https://idnremote.com/jobs/page-id",
+				}, nil)
+				v.noDuplicate()
+			},
+			vacancies: []shcore.BulkVacancy{
+				{Vacancy: shcore.Vacancy{ApplyURL: "https://example.com/new"}},
+				{Vacancy: shcore.Vacancy{ApplyURL: "https://example.com/posted"}},
+			},
+			expSkipped: []core.SkippedVacancy{
+				{
+					Index:    1,
+					ApplyURL: "https://example.com/posted",
+					Reason:   "vacancy has already been posted at https://idnremote.com/jobs/page-id",
+				},
+			},
+		},
+		{
+			name: "vacancy submitted twice in the same batch",
+			setupMocks: func(a *mockApprovalStorage, v *mockVacancyStorage) {
+				a.noDuplicate()
+				v.noDuplicate()
+			},
+			vacancies: []shcore.BulkVacancy{
+				{Vacancy: shcore.Vacancy{ApplyURL: "https://example.com/new"}},
+				{Vacancy: shcore.Vacancy{ApplyURL: "https://Example.com/new/?utm_source=linkedin"}},
+			},
+			expSkipped: []core.SkippedVacancy{
+				{
+					Index:    1,
+					ApplyURL: "https://Example.com/new/?utm_source=linkedin",
+					Reason:   "vacancy has the same apply url as bulk vacancy at index 0",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			approvalStorage := &mockApprovalStorage{}
+			vacancyStorage := &mockVacancyStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+			tokenizer := &mockTokenizer{}
+			emailClient := &mockEmailClient{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             &mockQueue{},           // not used
+				Email:             emailClient,
+				Tokenizer:         tokenizer,
+				Approval:          &mockApproval{}, // not used
+				ApprovalStorage:   approvalStorage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    vacancyStorage,
+				TokenStorage:      &mockTokenStorage{},  // not used
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+			})
+			require.NoError(t, err)
+
+			tt.setupMocks(approvalStorage, vacancyStorage)
+			submissionStorage.acceptAny()
+			tokenizer.On("EncodeRequest", mock.MatchedBy(func(req shcore.SubmitRequest) bool {
+				return req.SubmissionType == shcore.SubmitTypeBulk
+			})).Return("mock-batch-token", nil)
+			tokenizer.On("EncodeRequest", mock.MatchedBy(func(req shcore.SubmitRequest) bool {
+				return req.ApplyURL == "https://example.com/new"
+			})).Return("mock-token", nil)
+			// only the new vacancy is sent for approval
+			emailClient.On("SendBulkApprovalRequest", ctx, mock.MatchedBy(func(req shcore.SubmitRequest) bool {
+				return len(req.BulkVacancies) == 1 && req.BulkVacancies[0].ApplyURL == "https://example.com/new"
+			}), mock.Anything, "mock-batch-token", []string{"mock-token"}).Return([]string{"mock-message-id"}, nil)
+			approvalStorage.On("SaveBulkApprovalRequest", ctx, mock.Anything, mock.MatchedBy(func(reqs []shcore.SubmitRequest) bool {
+				return len(reqs) == 1 && reqs[0].ApplyURL == "https://example.com/new"
+			}), []string{"mock-message-id"}).Return(nil)
+
+			result, err := svc.HandleRequest(ctx, shcore.SubmitRequest{
+				SubmissionType:  shcore.SubmitTypeBulk,
+				SubmissionEmail: "crawler",
+				BulkVacancies:   tt.vacancies,
+			})
+			require.NoError(t, err)
+			require.Len(t, result.SubmissionIDs, 1)
+			require.Equal(t, tt.expSkipped, result.SkippedVacancies)
+
+			emailClient.AssertExpectations(t)
+			approvalStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceHandleBulkRequestAllDuplicate(t *testing.T) {
+	approvalStorage := &mockApprovalStorage{}
+	vacancyStorage := &mockVacancyStorage{}
+	submissionStorage := &mockSubmissionStorage{}
+	emailClient := &mockEmailClient{}
+
+	svc, err := core.NewService(core.ServiceConfig{
+		VacancyResolver:   &mockVacancyResolver{}, // not used
+		Queue:             &mockQueue{},           // not used
+		Email:             emailClient,
+		Tokenizer:         &mockTokenizer{}, // not used
+		Approval:          &mockApproval{},  // not used
+		ApprovalStorage:   approvalStorage,
+		SubmissionStorage: submissionStorage,
+		VacancyStorage:    vacancyStorage,
+		TokenStorage:      &mockTokenStorage{},  // not used
+		APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+	})
+	require.NoError(t, err)
+
+	approvalStorage.noDuplicate()
+	vacancyStorage.On("FindByApplyURL", mock.Anything, "https://example.com/posted").Return(&shcore.VacancyRecord{
+		ID:        "page-id",
+		PublicURL: "https://idnremote.com/jobs/page-id",
+	}, nil)
+
+	result, err := svc.HandleRequest(context.Background(), shcore.SubmitRequest{
+		SubmissionType:  shcore.SubmitTypeBulk,
+		SubmissionEmail: "crawler",
+		BulkVacancies: []shcore.BulkVacancy{
+			{Vacancy: shcore.Vacancy{ApplyURL: "https://example.com/posted"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.SubmissionIDs)
+	require.Len(t, result.SkippedVacancies, 1)
+
+	// nothing is left to be approved
+	submissionStorage.AssertNotCalled(t, "SaveSubmission", mock.Anything, mock.Anything)
+	emailClient.AssertNotCalled(t, "SendBulkApprovalRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestServiceHandleRequestInvalid(t *testing.T) {
+	svc, err := core.NewService(core.ServiceConfig{
+		VacancyResolver:   &mockVacancyResolver{},   // not used
+		Queue:             &mockQueue{},             // not used
+		Email:             &mockEmailClient{},       // not used
+		Tokenizer:         &mockTokenizer{},         // not used
+		Approval:          &mockApproval{},          // not used
+		ApprovalStorage:   &mockApprovalStorage{},   // not used
+		SubmissionStorage: &mockSubmissionStorage{}, // not used
+		VacancyStorage:    &mockVacancyStorage{},    // not used
+		TokenStorage:      &mockTokenStorage{},      // not used
+		APIKeyStorage:     &mockAPIKeyStorage{},     // not used
+	})
+	require.NoError(t, err)
+
+	_, err = svc.HandleRequest(context.Background(), shcore.SubmitRequest{
+		SubmissionType:  shcore.SubmitTypeBulk,
+		SubmissionEmail: "crawler",
+		BulkVacancies: []shcore.BulkVacancy{
+			{SubmissionType: shcore.SubmitTypeManual, Vacancy: shcore.Vacancy{JobTitle: "Backend Engineer", CompanyName: "Company A", ApplyURL: "https://example.com/jobs/1"}},
+			{SubmissionType: shcore.SubmitTypeManual, Vacancy: shcore.Vacancy{JobTitle: "Frontend Engineer", CompanyName: "Company B"}},
+			{SubmissionType: shcore.SubmitTypeManual, Vacancy: shcore.Vacancy{ApplyURL: "https://example.com/jobs/3"}},
+			{Vacancy: shcore.Vacancy{ApplyURL: "hr@example.com"}},
+			{SubmissionType: shcore.SubmitTypeBulk, Vacancy: shcore.Vacancy{ApplyURL: "https://example.com/jobs/5"}},
+		},
+	})
+	var shErr *shcore.Error
+	require.ErrorAs(t, err, &shErr)
+	require.Equal(t, shcore.ErrCodeBadRequest, shErr.ErrCode)
+	require.Equal(t, []shcore.FieldError{
+		{Field: "apply_url", JSONPath: "bulk_vacancies[1].apply_url", Rule: "nonzero", Message: "`bulk_vacancies[1].apply_url` is required"},
+		{Field: "job_title", JSONPath: "bulk_vacancies[2].job_title", Rule: "nonzero", Message: "`bulk_vacancies[2].job_title` is required"},
+		{Field: "company_name", JSONPath: "bulk_vacancies[2].company_name", Rule: "nonzero", Message: "`bulk_vacancies[2].company_name` is required"},
+		{Field: "apply_url", JSONPath: "bulk_vacancies[3].apply_url", Rule: "applyurl", Message: "`bulk_vacancies[3].apply_url` must be http(s) url"},
+		{Field: "submission_type", JSONPath: "bulk_vacancies[4].submission_type", Rule: "enum", Message: "`bulk_vacancies[4].submission_type` must be either manual or url"},
+	}, shErr.Fields)
+	require.Contains(t, shErr.Message, "`bulk_vacancies[1].apply_url` is required")
+}
+
+func TestServiceHandleRequestTracksBeforeQueue(t *testing.T) {
+	req := shcore.SubmitRequest{
+		SubmissionType:  shcore.SubmitTypeManual,
+		SubmissionEmail: "approved@example.com",
+		Vacancy: shcore.Vacancy{
+			JobTitle:    "Software Engineer",
+			CompanyName: "Test Company",
+			ApplyURL:    "https://example.com/apply",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		putErr   error
+		expCalls []string
+	}{
+		{
+			name:     "approved before queued",
+			expCalls: []string{string(shcore.SubmissionStateApproved), "put"},
+		},
+		{
+			name:     "failed when queue fails",
+			putErr:   errors.New("queue is down"),
+			expCalls: []string{string(shcore.SubmissionStateApproved), "put", string(shcore.SubmissionStateFailed)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			queue := &mockQueue{}
+			approval := &mockApproval{}
+			approvalStorage := &mockApprovalStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+			vacancyStorage := &mockVacancyStorage{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{},
+				Queue:             queue,
+				Email:             &mockEmailClient{},
+				Tokenizer:         &mockTokenizer{},
+				Approval:          approval,
+				ApprovalStorage:   approvalStorage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    vacancyStorage,
+				TokenStorage:      &mockTokenStorage{},
+				APIKeyStorage:     &mockAPIKeyStorage{},
+			})
+			require.NoError(t, err)
+
+			// record the order of the submission writes & queue put
+			var calls []string
+			approval.On("NeedsApproval", req.SubmissionEmail).Return(false)
+			approvalStorage.noDuplicate()
+			vacancyStorage.noDuplicate()
+			submissionStorage.On("SaveSubmission", ctx, mock.Anything).Return(nil)
+			submissionStorage.On("UpdateSubmission", ctx, mock.Anything).Run(func(args mock.Arguments) {
+				calls = append(calls, string(args.Get(1).(shcore.SubmissionUpdate).State))
+			}).Return(nil)
+			queue.On("Put", ctx, mock.Anything).Run(func(args mock.Arguments) {
+				calls = append(calls, "put")
+			}).Return(tt.putErr)
+
+			_, err = svc.HandleRequest(ctx, req)
+			if tt.putErr != nil {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.expCalls, calls)
+		})
+	}
+}
+
+func TestServicePreviewVacancy(t *testing.T) {
+	ctx := context.Background()
+	resolver := &mockVacancyResolver{}
+
+	svc, err := core.NewService(core.ServiceConfig{
+		VacancyResolver:   resolver,
+		Queue:             &mockQueue{},             // not used
+		Email:             &mockEmailClient{},       // not used
+		Tokenizer:         &mockTokenizer{},         // not used
+		Approval:          &mockApproval{},          // not used
+		ApprovalStorage:   &mockApprovalStorage{},   // not used
+		SubmissionStorage: &mockSubmissionStorage{}, // not used
+		VacancyStorage:    &mockVacancyStorage{},    // not used
+		TokenStorage:      &mockTokenStorage{},      // not used
+		APIKeyStorage:     &mockAPIKeyStorage{},     // not used
+	})
+	require.NoError(t, err)
+
+	vacancy := &shcore.Vacancy{
+		JobTitle:        "Software Engineer",
+		CompanyName:     "Test Company",
+		CompanyLocation: "Singapore",
+		RelevantTags:    []string{"go", "backend"},
+		ApplyURL:        "https://boards.greenhouse.io/test/jobs/1",
+	}
+	resolver.On("ResolveWithParser", ctx, "https://boards.greenhouse.io/test/jobs/1").Return(vacancy, "greenhouse", nil)
+	resolver.On("ResolveWithParser", ctx, "https://example.com/broken").Return(nil, "", shcore.NewBadRequestError("invalid vacancy"))
+
+	result, err := svc.PreviewVacancy(ctx, core.PreviewRequest{ApplyURL: " https://boards.greenhouse.io/test/jobs/1 "})
+	require.NoError(t, err)
+	require.Equal(t, *vacancy, result.Vacancy)
+	require.Equal(t, "greenhouse", result.Parser)
+
+	_, err = svc.PreviewVacancy(ctx, core.PreviewRequest{ApplyURL: "https://example.com/broken"})
+	require.ErrorContains(t, err, "invalid vacancy")
+
+	_, err = svc.PreviewVacancy(ctx, core.PreviewRequest{})
+	require.ErrorContains(t, err, "missing `apply_url`")
+
+	// preview must not submit anything
+	resolver.AssertExpectations(t)
+}
+
 func TestServiceHandleApprove(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -243,8 +668,8 @@ func TestServiceHandleApprove(t *testing.T) {
 						JobTitle: "Test Job",
 					},
 				}
-				tok.On("DecodeToken", "test-token").Return(req, nil)
-				s.On("GetApprovalState", ctx, "test-message").Return(core.ApprovalStatePending, nil)
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStatePending, Kind: core.ApprovalKindSingle}, nil)
 				e.On("ApproveRequest", ctx, "test-message").Return(nil)
 				s.On("UpdateApprovalState", ctx, "test-message", core.ApprovalStateApproved).Return(nil)
 				q.On("Put", ctx, req).Return(nil)
@@ -263,7 +688,7 @@ func TestServiceHandleApprove(t *testing.T) {
 						JobTitle: "Test Job",
 					},
 				}
-				tok.On("DecodeToken", "test-token").Return(req, nil)
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
 				q.On("Put", ctx, req).Return(nil)
 			},
 			request: core.ApprovalRequest{
@@ -276,8 +701,8 @@ func TestServiceHandleApprove(t *testing.T) {
 				req := shcore.SubmitRequest{
 					SubmissionEmail: "test@example.com",
 				}
-				tok.On("DecodeToken", "test-token").Return(req, nil)
-				s.On("GetApprovalState", ctx, "test-message").Return(core.ApprovalStateApproved, nil)
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStateApproved, Kind: core.ApprovalKindSingle}, nil)
 			},
 			request: core.ApprovalRequest{
 				MessageID:    "test-message",
@@ -286,10 +711,26 @@ func TestServiceHandleApprove(t *testing.T) {
 			wantErr: true,
 			errMsg:  "approval already processed",
 		},
+		{
+			name: "approval has expired",
+			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				req := shcore.SubmitRequest{
+					SubmissionEmail: "test@example.com",
+				}
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStateExpired, Kind: core.ApprovalKindSingle}, nil)
+			},
+			request: core.ApprovalRequest{
+				MessageID:    "test-message",
+				TokenRequest: "test-token",
+			},
+			wantErr: true,
+			errMsg:  "approval has expired",
+		},
 		{
 			name: "invalid token",
 			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
-				tok.On("DecodeToken", "invalid-token").Return(shcore.SubmitRequest{}, shcore.NewBadRequestError("invalid token"))
+				tok.On("DecodeToken", "invalid-token").Return(core.ApprovalToken{}, shcore.NewBadRequestError("invalid token"))
 			},
 			request: core.ApprovalRequest{
 				MessageID:    "test-message",
@@ -307,17 +748,26 @@ func TestServiceHandleApprove(t *testing.T) {
 			email := &mockEmailClient{}
 			tokenizer := &mockTokenizer{}
 			approvalStorage := &mockApprovalStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+			tokenStorage := &mockTokenStorage{}
 
 			svc, err := core.NewService(core.ServiceConfig{
-				Queue:           queue,
-				Email:           email,
-				Tokenizer:       tokenizer,
-				Approval:        &mockApproval{}, // not used
-				ApprovalStorage: approvalStorage,
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             queue,
+				Email:             email,
+				Tokenizer:         tokenizer,
+				Approval:          &mockApproval{}, // not used
+				ApprovalStorage:   approvalStorage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    &mockVacancyStorage{}, // not used
+				TokenStorage:      tokenStorage,
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
 			})
 			require.NoError(t, err)
 
 			tt.setupMocks(ctx, queue, email, tokenizer, approvalStorage)
+			submissionStorage.acceptAny()
+			tokenStorage.acceptAny()
 
 			err = svc.HandleApprove(ctx, tt.request)
 			if tt.wantErr {
@@ -349,15 +799,83 @@ func TestServiceHandleReject(t *testing.T) {
 						JobTitle: "Test Job",
 					},
 				}
-				tok.On("DecodeToken", "test-token").Return(req, nil)
-				s.On("GetApprovalState", ctx, "test-message").Return(core.ApprovalStatePending, nil)
-				e.On("RejectRequest", ctx, "test-message").Return(nil)
-				s.On("UpdateApprovalState", ctx, "test-message", core.ApprovalStateRejected).Return(nil)
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStatePending, Kind: core.ApprovalKindSingle}, nil)
+				e.On("RejectRequest", ctx, "test-message", core.RejectReason("")).Return(nil)
+				s.On("RejectApproval", ctx, "test-message", core.RejectReason("")).Return(nil)
+				e.On("SendSubmitterRejection", ctx, req, core.RejectReason("")).Return(nil)
+			},
+			request: core.ApprovalRequest{
+				MessageID:    "test-message",
+				TokenRequest: "test-token",
+			},
+		},
+		{
+			name: "successful rejection with reason",
+			setupMocks: func(ctx context.Context, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				req := shcore.SubmitRequest{
+					SubmissionEmail: "test@example.com",
+					Vacancy: shcore.Vacancy{
+						JobTitle: "Test Job",
+					},
+				}
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStatePending, Kind: core.ApprovalKindSingle}, nil)
+				e.On("RejectRequest", ctx, "test-message", core.RejectReasonNotRemote).Return(nil)
+				s.On("RejectApproval", ctx, "test-message", core.RejectReasonNotRemote).Return(nil)
+				e.On("SendSubmitterRejection", ctx, req, core.RejectReasonNotRemote).Return(nil)
+			},
+			request: core.ApprovalRequest{
+				MessageID:    "test-message",
+				TokenRequest: "test-token",
+				RejectReason: core.RejectReasonNotRemote,
+			},
+		},
+		{
+			name: "rejection as spam doesn't notify submitter",
+			setupMocks: func(ctx context.Context, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				req := shcore.SubmitRequest{
+					SubmissionEmail: "test@example.com",
+				}
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStatePending, Kind: core.ApprovalKindSingle}, nil)
+				e.On("RejectRequest", ctx, "test-message", core.RejectReasonSpam).Return(nil)
+				s.On("RejectApproval", ctx, "test-message", core.RejectReasonSpam).Return(nil)
+			},
+			request: core.ApprovalRequest{
+				MessageID:    "test-message",
+				TokenRequest: "test-token",
+				RejectReason: core.RejectReasonSpam,
+			},
+		},
+		{
+			name: "rejection of crawled vacancy doesn't notify submitter",
+			setupMocks: func(ctx context.Context, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				req := shcore.SubmitRequest{
+					SubmissionEmail: "crawler",
+				}
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				s.On("GetApproval", ctx, "test-crawled").Return(&core.ApprovalRecord{MessageID: "test-crawled", State: core.ApprovalStatePending, Kind: core.ApprovalKindBulk, BatchID: "test-batch"}, nil)
+				s.On("RejectApproval", ctx, "test-crawled", core.RejectReason("custom typed reason")).Return(nil)
+			},
+			request: core.ApprovalRequest{
+				MessageID:    "test-crawled",
+				TokenRequest: "test-token",
+				RejectReason: " custom typed reason ",
+			},
+		},
+		{
+			name: "reject reason too long",
+			setupMocks: func(ctx context.Context, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: shcore.SubmitRequest{}}, nil)
 			},
 			request: core.ApprovalRequest{
 				MessageID:    "test-message",
 				TokenRequest: "test-token",
+				RejectReason: core.RejectReason(strings.Repeat("a", 501)),
 			},
+			wantErr: true,
+			errMsg:  "reject reason cannot be longer than",
 		},
 		{
 			name: "successful rejection without message ID",
@@ -368,7 +886,8 @@ func TestServiceHandleReject(t *testing.T) {
 						JobTitle: "Test Job",
 					},
 				}
-				tok.On("DecodeToken", "test-token").Return(req, nil)
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				e.On("SendSubmitterRejection", ctx, req, core.RejectReason("")).Return(nil)
 			},
 			request: core.ApprovalRequest{
 				TokenRequest: "test-token",
@@ -380,8 +899,8 @@ func TestServiceHandleReject(t *testing.T) {
 				req := shcore.SubmitRequest{
 					SubmissionEmail: "test@example.com",
 				}
-				tok.On("DecodeToken", "test-token").Return(req, nil)
-				s.On("GetApprovalState", ctx, "test-message").Return(core.ApprovalStateRejected, nil)
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: req}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStateRejected, Kind: core.ApprovalKindSingle}, nil)
 			},
 			request: core.ApprovalRequest{
 				MessageID:    "test-message",
@@ -393,7 +912,7 @@ func TestServiceHandleReject(t *testing.T) {
 		{
 			name: "invalid token",
 			setupMocks: func(ctx context.Context, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
-				tok.On("DecodeToken", "invalid-token").Return(shcore.SubmitRequest{}, shcore.NewBadRequestError("invalid token"))
+				tok.On("DecodeToken", "invalid-token").Return(core.ApprovalToken{}, shcore.NewBadRequestError("invalid token"))
 			},
 			request: core.ApprovalRequest{
 				MessageID:    "test-message",
@@ -411,17 +930,26 @@ func TestServiceHandleReject(t *testing.T) {
 			email := &mockEmailClient{}
 			tokenizer := &mockTokenizer{}
 			storage := &mockApprovalStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+			tokenStorage := &mockTokenStorage{}
 
 			svc, err := core.NewService(core.ServiceConfig{
-				Queue:           queue,
-				Email:           email,
-				Tokenizer:       tokenizer,
-				Approval:        &mockApproval{}, // not used
-				ApprovalStorage: storage,
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             queue,
+				Email:             email,
+				Tokenizer:         tokenizer,
+				Approval:          &mockApproval{}, // not used
+				ApprovalStorage:   storage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    &mockVacancyStorage{}, // not used
+				TokenStorage:      tokenStorage,
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
 			})
 			require.NoError(t, err)
 
 			tt.setupMocks(ctx, email, tokenizer, storage)
+			submissionStorage.acceptAny()
+			tokenStorage.acceptAny()
 
 			err = svc.HandleReject(ctx, tt.request)
 			if tt.wantErr {
@@ -435,26 +963,972 @@ func TestServiceHandleReject(t *testing.T) {
 	}
 }
 
-type mockQueue struct {
-	mock.Mock
-}
+func TestServiceHandleEditApprove(t *testing.T) {
+	original := shcore.SubmitRequest{
+		SubmissionID:    "sub-1",
+		SubmissionType:  shcore.SubmitTypeURL,
+		SubmissionEmail: "test@example.com",
+		Vacancy: shcore.Vacancy{
+			JobTitle:        "Sofware Enginer",
+			CompanyName:     "Test Company",
+			CompanyLocation: "Nowhere",
+			ApplyURL:        "https://example.com/apply",
+		},
+	}
+	edited := shcore.Vacancy{
+		JobTitle:        "Software Engineer",
+		CompanyName:     "Test Company",
+		CompanyLocation: "Jakarta, Indonesia",
+		ApplyURL:        "https://example.com/apply",
+	}
+	expected := original
+	expected.SubmissionType = shcore.SubmitTypeManual
+	expected.Vacancy = edited
 
-func (m *mockQueue) Put(ctx context.Context, req shcore.SubmitRequest) error {
-	args := m.Called(ctx, req)
-	return args.Error(0)
-}
+	tests := []struct {
+		name       string
+		setupMocks func(context.Context, *mockQueue, *mockEmailClient, *mockTokenizer, *mockApprovalStorage)
+		request    core.EditApprovalRequest
+		wantErr    bool
+		errMsg     string
+	}{
+		{
+			name: "successful edit approval",
+			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: original}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStatePending, Kind: core.ApprovalKindSingle}, nil)
+				s.On("UpdateApprovalRequest", ctx, "test-message", expected).Return(nil)
+				s.On("UpdateApprovalState", ctx, "test-message", core.ApprovalStateApproved).Return(nil)
+				e.On("ApproveRequest", ctx, "test-message").Return(nil)
+				q.On("Put", ctx, expected).Return(nil)
+			},
+			request: core.EditApprovalRequest{
+				MessageID:    "test-message",
+				TokenRequest: "test-token",
+				Vacancy:      edited,
+			},
+		},
+		{
+			name: "missing message ID",
+			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+			},
+			request: core.EditApprovalRequest{
+				TokenRequest: "test-token",
+				Vacancy:      edited,
+			},
+			wantErr: true,
+			errMsg:  "message id is required",
+		},
+		{
+			name: "incomplete vacancy",
+			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: original}, nil)
+			},
+			request: core.EditApprovalRequest{
+				MessageID:    "test-message",
+				TokenRequest: "test-token",
+				Vacancy: shcore.Vacancy{
+					JobTitle: "Software Engineer",
+				},
+			},
+			wantErr: true,
+			errMsg:  "`company_name` is required",
+		},
+		{
+			name: "approval already processed",
+			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id", Request: original}, nil)
+				s.On("GetApproval", ctx, "test-message").Return(&core.ApprovalRecord{MessageID: "test-message", State: core.ApprovalStateRejected, Kind: core.ApprovalKindSingle}, nil)
+			},
+			request: core.EditApprovalRequest{
+				MessageID:    "test-message",
+				TokenRequest: "test-token",
+				Vacancy:      edited,
+			},
+			wantErr: true,
+			errMsg:  "approval already processed",
+		},
+	}
 
-type mockEmailClient struct {
-	mock.Mock
-}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			queue := &mockQueue{}
+			email := &mockEmailClient{}
+			tokenizer := &mockTokenizer{}
+			approvalStorage := &mockApprovalStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+			tokenStorage := &mockTokenStorage{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             queue,
+				Email:             email,
+				Tokenizer:         tokenizer,
+				Approval:          &mockApproval{}, // not used
+				ApprovalStorage:   approvalStorage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    &mockVacancyStorage{}, // not used
+				TokenStorage:      tokenStorage,
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+			})
+			require.NoError(t, err)
+
+			tt.setupMocks(ctx, queue, email, tokenizer, approvalStorage)
+			submissionStorage.acceptAny()
+			tokenStorage.acceptAny()
+
+			err = svc.HandleEditApprove(ctx, tt.request)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.errMsg)
+				approvalStorage.AssertNotCalled(t, "UpdateApprovalRequest", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			mock.AssertExpectationsForObjects(t, queue, email, tokenizer, approvalStorage)
+		})
+	}
+}
+
+func TestServiceTokenSingleUse(t *testing.T) {
+	req := shcore.SubmitRequest{
+		SubmissionType:  shcore.SubmitTypeManual,
+		SubmissionEmail: "test@example.com",
+		Vacancy: shcore.Vacancy{
+			JobTitle:    "Test Job",
+			CompanyName: "Test Company",
+			ApplyURL:    "https://example.com/apply",
+		},
+	}
+	issuedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		revokedBefore time.Time
+		consumed      bool
+		handle        func(context.Context, core.Service) error
+		wantErr       bool
+		errMsg        string
+	}{
+		{
+			name:     "approve replay without message ID",
+			consumed: true,
+			handle: func(ctx context.Context, svc core.Service) error {
+				return svc.HandleApprove(ctx, core.ApprovalRequest{TokenRequest: "test-token"})
+			},
+			wantErr: true,
+			errMsg:  "token has already been used or revoked",
+		},
+		{
+			name:     "reject replay without message ID",
+			consumed: true,
+			handle: func(ctx context.Context, svc core.Service) error {
+				return svc.HandleReject(ctx, core.ApprovalRequest{TokenRequest: "test-token"})
+			},
+			wantErr: true,
+			errMsg:  "token has already been used or revoked",
+		},
+		{
+			name:          "token issued before revocation",
+			revokedBefore: issuedAt.Add(time.Minute),
+			handle: func(ctx context.Context, svc core.Service) error {
+				return svc.HandleApprove(ctx, core.ApprovalRequest{TokenRequest: "test-token"})
+			},
+			wantErr: true,
+			errMsg:  "token has been revoked",
+		},
+		{
+			name:          "token issued after revocation",
+			revokedBefore: issuedAt.Add(-time.Minute),
+			handle: func(ctx context.Context, svc core.Service) error {
+				return svc.HandleApprove(ctx, core.ApprovalRequest{TokenRequest: "test-token"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			queue := &mockQueue{}
+			tokenizer := &mockTokenizer{}
+			submissionStorage := &mockSubmissionStorage{}
+			tokenStorage := &mockTokenStorage{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             queue,
+				Email:             &mockEmailClient{}, // not used
+				Tokenizer:         tokenizer,
+				Approval:          &mockApproval{},        // not used
+				ApprovalStorage:   &mockApprovalStorage{}, // not used
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    &mockVacancyStorage{}, // not used
+				TokenStorage:      tokenStorage,
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+			})
+			require.NoError(t, err)
+
+			tokenizer.On("DecodeToken", "test-token").Return(core.ApprovalToken{
+				ID:       "token-id",
+				Request:  req,
+				IssuedAt: issuedAt,
+			}, nil)
+			tokenStorage.On("GetTokensRevokedBefore", ctx).Return(tt.revokedBefore, nil)
+			tokenStorage.On("ConsumeToken", ctx, "token-id").Return(!tt.consumed, nil).Maybe()
+			queue.On("Put", ctx, req).Return(nil).Maybe()
+			submissionStorage.acceptAny()
+
+			err = tt.handle(ctx, svc)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.errMsg)
+				queue.AssertNotCalled(t, "Put", mock.Anything, mock.Anything)
+				submissionStorage.AssertNotCalled(t, "UpdateSubmission", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			tokenStorage.AssertCalled(t, "ConsumeToken", ctx, "token-id")
+		})
+	}
+}
+
+func TestServiceHandleBatch(t *testing.T) {
+	batchToken := core.ApprovalToken{
+		ID:      "batch-token-id",
+		Request: shcore.SubmitRequest{SubmissionID: "batch-1", SubmissionType: shcore.SubmitTypeBulk},
+	}
+	pending := []core.ApprovalRecord{
+		{MessageID: "item-1", State: core.ApprovalStatePending, Kind: core.ApprovalKindBulk, BatchID: "batch-1", Request: shcore.SubmitRequest{SubmissionID: "sub-1", SubmissionEmail: "crawler"}},
+		{MessageID: "item-2", State: core.ApprovalStatePending, Kind: core.ApprovalKindBulk, BatchID: "batch-1", Request: shcore.SubmitRequest{SubmissionID: "sub-2", SubmissionEmail: "crawler"}},
+	}
+
+	tests := []struct {
+		name       string
+		reject     bool
+		request    core.BatchApprovalRequest
+		setupMocks func(ctx context.Context, q *mockQueue, tok *mockTokenizer, s *mockApprovalStorage)
+		expResult  *core.BatchApprovalResult
+		errMsg     string
+	}{
+		{
+			name:    "approve all",
+			request: core.BatchApprovalRequest{TokenRequest: "batch-token", BatchID: "batch-1"},
+			setupMocks: func(ctx context.Context, q *mockQueue, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "batch-token").Return(batchToken, nil)
+				s.On("ListPendingApprovals", ctx, core.ApprovalFilter{BatchID: "batch-1"}).Return(pending, nil)
+				s.On("DecideBatchApprovals", ctx, "batch-1", []string(nil), core.ApprovalStateApproved, core.RejectReason("")).Return(pending, nil)
+				q.On("Put", ctx, pending[0].Request).Return(nil)
+				q.On("Put", ctx, pending[1].Request).Return(nil)
+			},
+			expResult: &core.BatchApprovalResult{BatchID: "batch-1", State: core.ApprovalStateApproved, MessageIDs: []string{"item-1", "item-2"}},
+		},
+		{
+			name:    "approve selected",
+			request: core.BatchApprovalRequest{TokenRequest: "batch-token", BatchID: "batch-1", MessageIDs: []string{"item-2"}},
+			setupMocks: func(ctx context.Context, q *mockQueue, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "batch-token").Return(batchToken, nil)
+				s.On("ListPendingApprovals", ctx, core.ApprovalFilter{BatchID: "batch-1"}).Return(pending, nil)
+				s.On("DecideBatchApprovals", ctx, "batch-1", []string{"item-2"}, core.ApprovalStateApproved, core.RejectReason("")).Return(pending[1:], nil)
+				q.On("Put", ctx, pending[1].Request).Return(nil)
+			},
+			expResult: &core.BatchApprovalResult{BatchID: "batch-1", State: core.ApprovalStateApproved, MessageIDs: []string{"item-2"}},
+		},
+		{
+			name:    "reject all",
+			reject:  true,
+			request: core.BatchApprovalRequest{TokenRequest: "batch-token", BatchID: "batch-1", RejectReason: " not_remote "},
+			setupMocks: func(ctx context.Context, q *mockQueue, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "batch-token").Return(batchToken, nil)
+				s.On("ListPendingApprovals", ctx, core.ApprovalFilter{BatchID: "batch-1"}).Return(pending, nil)
+				s.On("DecideBatchApprovals", ctx, "batch-1", []string(nil), core.ApprovalStateRejected, core.RejectReasonNotRemote).Return(pending, nil)
+			},
+			expResult: &core.BatchApprovalResult{BatchID: "batch-1", State: core.ApprovalStateRejected, MessageIDs: []string{"item-1", "item-2"}},
+		},
+		{
+			name:    "token of other batch",
+			request: core.BatchApprovalRequest{TokenRequest: "batch-token", BatchID: "batch-2"},
+			setupMocks: func(ctx context.Context, q *mockQueue, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "batch-token").Return(batchToken, nil)
+			},
+			errMsg: "token is not issued for the batch",
+		},
+		{
+			name:    "token of single vacancy",
+			request: core.BatchApprovalRequest{TokenRequest: "item-token", BatchID: "batch-1"},
+			setupMocks: func(ctx context.Context, q *mockQueue, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "item-token").Return(core.ApprovalToken{ID: "item-token-id", Request: pending[0].Request}, nil)
+			},
+			errMsg: "token is not issued for the batch",
+		},
+		{
+			name:    "selected approval is not pending",
+			request: core.BatchApprovalRequest{TokenRequest: "batch-token", BatchID: "batch-1", MessageIDs: []string{"item-1", "item-3"}},
+			setupMocks: func(ctx context.Context, q *mockQueue, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "batch-token").Return(batchToken, nil)
+				s.On("ListPendingApprovals", ctx, core.ApprovalFilter{BatchID: "batch-1"}).Return(pending, nil)
+			},
+			errMsg: "approval item-3 is not pending in the batch",
+		},
+		{
+			name:    "nothing pending",
+			request: core.BatchApprovalRequest{TokenRequest: "batch-token", BatchID: "batch-1"},
+			setupMocks: func(ctx context.Context, q *mockQueue, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("DecodeToken", "batch-token").Return(batchToken, nil)
+				s.On("ListPendingApprovals", ctx, core.ApprovalFilter{BatchID: "batch-1"}).Return([]core.ApprovalRecord{}, nil)
+			},
+			errMsg: "no pending approval in the batch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			queue := &mockQueue{}
+			email := &mockEmailClient{}
+			tokenizer := &mockTokenizer{}
+			storage := &mockApprovalStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+			tokenStorage := &mockTokenStorage{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             queue,
+				Email:             email,
+				Tokenizer:         tokenizer,
+				Approval:          &mockApproval{}, // not used
+				ApprovalStorage:   storage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    &mockVacancyStorage{}, // not used
+				TokenStorage:      tokenStorage,
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+			})
+			require.NoError(t, err)
+
+			tt.setupMocks(ctx, queue, tokenizer, storage)
+			submissionStorage.acceptAny()
+			tokenStorage.acceptAny()
+
+			handle := svc.HandleApproveBatch
+			if tt.reject {
+				handle = svc.HandleRejectBatch
+			}
+			result, err := handle(ctx, tt.request)
+			// the batch token is shared by every batch action, so it
+			// must stay usable after deciding
+			tokenStorage.AssertNotCalled(t, "ConsumeToken", mock.Anything, mock.Anything)
+			if tt.errMsg != "" {
+				require.ErrorContains(t, err, tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expResult, result)
+			tokenStorage.AssertCalled(t, "IsTokenRevoked", ctx, "batch-token-id")
+			// bulk items share a single email, so there is nothing to reply to
+			email.AssertNotCalled(t, "ApproveRequest", mock.Anything, mock.Anything)
+			email.AssertNotCalled(t, "RejectRequest", mock.Anything, mock.Anything, mock.Anything)
+			mock.AssertExpectationsForObjects(t, queue, tokenizer, storage)
+		})
+	}
+}
+
+func TestServiceHandleBatchRevokedToken(t *testing.T) {
+	ctx := context.Background()
+	tokenizer := &mockTokenizer{}
+	storage := &mockApprovalStorage{}
+	tokenStorage := &mockTokenStorage{}
+
+	svc, err := core.NewService(core.ServiceConfig{
+		VacancyResolver:   &mockVacancyResolver{}, // not used
+		Queue:             &mockQueue{},           // not used
+		Email:             &mockEmailClient{},     // not used
+		Tokenizer:         tokenizer,
+		Approval:          &mockApproval{}, // not used
+		ApprovalStorage:   storage,
+		SubmissionStorage: &mockSubmissionStorage{}, // not used
+		VacancyStorage:    &mockVacancyStorage{},    // not used
+		TokenStorage:      tokenStorage,
+		APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+	})
+	require.NoError(t, err)
+
+	tokenizer.On("DecodeToken", "batch-token").Return(core.ApprovalToken{
+		ID:      "batch-token-id",
+		Request: shcore.SubmitRequest{SubmissionID: "batch-1", SubmissionType: shcore.SubmitTypeBulk},
+	}, nil)
+	storage.On("ListPendingApprovals", ctx, core.ApprovalFilter{BatchID: "batch-1"}).Return([]core.ApprovalRecord{
+		{MessageID: "item-1", State: core.ApprovalStatePending, Kind: core.ApprovalKindBulk, BatchID: "batch-1"},
+	}, nil)
+	tokenStorage.On("GetTokensRevokedBefore", ctx).Return(time.Time{}, nil)
+	tokenStorage.On("IsTokenRevoked", ctx, "batch-token-id").Return(true, nil)
+
+	_, err = svc.HandleApproveBatch(ctx, core.BatchApprovalRequest{TokenRequest: "batch-token", BatchID: "batch-1"})
+	require.ErrorContains(t, err, "token has been revoked")
+	storage.AssertNotCalled(t, "DecideBatchApprovals", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestServiceHandleEmailReply(t *testing.T) {
+	req := shcore.SubmitRequest{
+		SubmissionID:    "sub-1",
+		SubmissionType:  shcore.SubmitTypeManual,
+		SubmissionEmail: "test@example.com",
+		Vacancy: shcore.Vacancy{
+			JobTitle:    "Test Job",
+			CompanyName: "Test Company",
+			ApplyURL:    "https://example.com/apply",
+		},
+	}
+	approval := &core.ApprovalRecord{MessageID: "test-message@idnremote.com", State: core.ApprovalStatePending, Kind: core.ApprovalKindSingle, Request: req}
+	batchReq := shcore.SubmitRequest{SubmissionID: "batch-1", SubmissionType: shcore.SubmitTypeBulk}
+	pending := []core.ApprovalRecord{
+		{MessageID: "item-1", State: core.ApprovalStatePending, Kind: core.ApprovalKindBulk, BatchID: "batch-1", Request: shcore.SubmitRequest{SubmissionID: "sub-2"}},
+	}
+
+	tests := []struct {
+		name       string
+		reply      core.EmailReply
+		setupMocks func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage)
+		errMsg     string
+	}{
+		{
+			name: "approve",
+			reply: core.EmailReply{
+				From:           "Admin@Example.com",
+				InReplyTo:      "<test-message@idnremote.com>",
+				Body:           "Approve.\n\nOn Mon, 1 Jan 2025 IDN Remote Entry wrote:\n> Hello Admin!",
+				SenderVerified: true,
+			},
+			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				s.On("GetApproval", ctx, "test-message@idnremote.com").Return(approval, nil)
+				tok.On("EncodeRequest", req).Return("reply-token", nil)
+				s.On("SaveApprovalToken", ctx, "test-message@idnremote.com", "reply-token").Return(nil)
+				tok.On("DecodeToken", "reply-token").Return(core.ApprovalToken{ID: "reply-token-id", Request: req}, nil)
+				s.On("UpdateApprovalState", ctx, "test-message@idnremote.com", core.ApprovalStateApproved).Return(nil)
+				e.On("ApproveRequest", ctx, "test-message@idnremote.com").Return(nil)
+				q.On("Put", ctx, req).Return(nil)
+			},
+		},
+		{
+			name: "reject with reason",
+			reply: core.EmailReply{
+				From:           "admin@example.com",
+				InReplyTo:      "<test-message@idnremote.com>",
+				Body:           "\n  reject not_remote\nSent from my phone",
+				SenderVerified: true,
+			},
+			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				s.On("GetApproval", ctx, "test-message@idnremote.com").Return(approval, nil)
+				tok.On("EncodeRequest", req).Return("reply-token", nil)
+				s.On("SaveApprovalToken", ctx, "test-message@idnremote.com", "reply-token").Return(nil)
+				tok.On("DecodeToken", "reply-token").Return(core.ApprovalToken{ID: "reply-token-id", Request: req}, nil)
+				s.On("RejectApproval", ctx, "test-message@idnremote.com", core.RejectReasonNotRemote).Return(nil)
+				e.On("RejectRequest", ctx, "test-message@idnremote.com", core.RejectReasonNotRemote).Return(nil)
+				e.On("SendSubmitterRejection", ctx, req, core.RejectReasonNotRemote).Return(nil)
+			},
+		},
+		{
+			name: "approve bulk batch",
+			reply: core.EmailReply{
+				From:           "admin@example.com",
+				InReplyTo:      "<batch-1@bulk>",
+				Body:           "approve",
+				SenderVerified: true,
+			},
+			setupMocks: func(ctx context.Context, q *mockQueue, e *mockEmailClient, tok *mockTokenizer, s *mockApprovalStorage) {
+				tok.On("EncodeRequest", batchReq).Return("batch-token", nil)
+				tok.On("DecodeToken", "batch-token").Return(core.ApprovalToken{ID: "batch-token-id", Request: batchReq}, nil)
+				s.On("ListPendingApprovals", ctx, core.ApprovalFilter{BatchID: "batch-1"}).Return(pending, nil)
+				s.On("DecideBatchApprovals", ctx, "batch-1", []string(nil), core.ApprovalStateApproved, core.RejectReason("")).Return(pending, nil)
+				q.On("Put", ctx, pending[0].Request).Return(nil)
+			},
+		},
+		{
+			name: "sender is not admin",
+			reply: core.EmailReply{
+				From:           "someone@example.com",
+				InReplyTo:      "<test-message@idnremote.com>",
+				Body:           "approve",
+				SenderVerified: true,
+			},
+			errMsg: core.ErrSenderNotAdmin.Error(),
+		},
+		{
+			name: "sender is not verified",
+			reply: core.EmailReply{
+				From:      "admin@example.com",
+				InReplyTo: "<test-message@idnremote.com>",
+				Body:      "approve",
+			},
+			errMsg: core.ErrSenderNotVerified.Error(),
+		},
+		{
+			name: "not a reply",
+			reply: core.EmailReply{
+				From:           "admin@example.com",
+				Body:           "approve",
+				SenderVerified: true,
+			},
+			errMsg: "email is not a reply to approval email",
+		},
+		{
+			name: "unknown command",
+			reply: core.EmailReply{
+				From:           "admin@example.com",
+				InReplyTo:      "<test-message@idnremote.com>",
+				Body:           "> approve\nlooks good to me",
+				SenderVerified: true,
+			},
+			errMsg: "reply must start with `approve` or `reject <reason>`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			queue := &mockQueue{}
+			email := &mockEmailClient{}
+			tokenizer := &mockTokenizer{}
+			storage := &mockApprovalStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+			tokenStorage := &mockTokenStorage{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             queue,
+				Email:             email,
+				Tokenizer:         tokenizer,
+				Approval:          &mockApproval{}, // not used
+				ApprovalStorage:   storage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    &mockVacancyStorage{}, // not used
+				TokenStorage:      tokenStorage,
+				APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+				AdminEmails:       []string{"admin@example.com", " other-admin@example.com"},
+			})
+			require.NoError(t, err)
+
+			if tt.setupMocks != nil {
+				tt.setupMocks(ctx, queue, email, tokenizer, storage)
+			}
+			submissionStorage.acceptAny()
+			tokenStorage.acceptAny()
+
+			err = svc.HandleEmailReply(ctx, tt.reply)
+			if tt.errMsg != "" {
+				require.ErrorContains(t, err, tt.errMsg)
+				tokenizer.AssertNotCalled(t, "EncodeRequest", mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			mock.AssertExpectationsForObjects(t, queue, email, tokenizer, storage)
+		})
+	}
+}
+
+func TestServiceExpireStaleApprovals(t *testing.T) {
+	ttl := 7 * 24 * time.Hour
+	expired := []core.ApprovalRecord{
+		{MessageID: "msg-1", State: core.ApprovalStateExpired, Request: shcore.SubmitRequest{SubmissionID: "sub-1"}},
+		{MessageID: "msg-2", State: core.ApprovalStateExpired, Request: shcore.SubmitRequest{SubmissionID: "sub-2"}},
+	}
+	expiring := []core.ApprovalRecord{
+		{MessageID: "msg-3", State: core.ApprovalStatePending, Request: shcore.SubmitRequest{SubmissionID: "sub-3"}},
+	}
+
+	tests := []struct {
+		name         string
+		ttl          time.Duration
+		digestWithin time.Duration
+		setupMocks   func(ctx context.Context, e *mockEmailClient, s *mockApprovalStorage, sub *mockSubmissionStorage)
+		expCount     int
+	}{
+		{
+			name: "expire without digest",
+			ttl:  ttl,
+			setupMocks: func(ctx context.Context, e *mockEmailClient, s *mockApprovalStorage, sub *mockSubmissionStorage) {
+				s.On("ExpireApprovals", ctx, ttl).Return(expired, nil)
+				for _, rec := range expired {
+					sub.On("UpdateSubmission", ctx, shcore.SubmissionUpdate{
+						ID:         rec.Request.SubmissionID,
+						State:      shcore.SubmissionStateExpired,
+						ErrMessage: "approval has expired",
+					}).Return(nil)
+				}
+			},
+			expCount: 2,
+		},
+		{
+			name:         "expire with digest",
+			ttl:          ttl,
+			digestWithin: time.Hour,
+			setupMocks: func(ctx context.Context, e *mockEmailClient, s *mockApprovalStorage, sub *mockSubmissionStorage) {
+				s.On("ExpireApprovals", ctx, ttl).Return([]core.ApprovalRecord{}, nil)
+				s.On("ListPendingApprovals", ctx, core.ApprovalFilter{MinAge: ttl - time.Hour}).Return(expiring, nil)
+				e.On("SendExpiryDigest", ctx, expiring, ttl).Return(nil)
+			},
+		},
+		{
+			name:         "nothing about to expire",
+			ttl:          ttl,
+			digestWithin: time.Hour,
+			setupMocks: func(ctx context.Context, e *mockEmailClient, s *mockApprovalStorage, sub *mockSubmissionStorage) {
+				s.On("ExpireApprovals", ctx, ttl).Return([]core.ApprovalRecord{}, nil)
+				s.On("ListPendingApprovals", ctx, core.ApprovalFilter{MinAge: ttl - time.Hour}).Return([]core.ApprovalRecord{}, nil)
+			},
+		},
+		{
+			name:         "expiry disabled",
+			digestWithin: time.Hour,
+			setupMocks:   func(ctx context.Context, e *mockEmailClient, s *mockApprovalStorage, sub *mockSubmissionStorage) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			email := &mockEmailClient{}
+			storage := &mockApprovalStorage{}
+			submissionStorage := &mockSubmissionStorage{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             &mockQueue{},           // not used
+				Email:             email,
+				Tokenizer:         &mockTokenizer{}, // not used
+				Approval:          &mockApproval{},  // not used
+				ApprovalStorage:   storage,
+				SubmissionStorage: submissionStorage,
+				VacancyStorage:    &mockVacancyStorage{}, // not used
+				TokenStorage:      &mockTokenStorage{},   // not used
+				APIKeyStorage:     &mockAPIKeyStorage{},  // not used
+				ApprovalTTL:       tt.ttl,
+			})
+			require.NoError(t, err)
+
+			tt.setupMocks(ctx, email, storage, submissionStorage)
+
+			count, err := svc.ExpireStaleApprovals(ctx, tt.digestWithin)
+			require.NoError(t, err)
+			require.Equal(t, tt.expCount, count)
+			mock.AssertExpectationsForObjects(t, email, storage, submissionStorage)
+			if tt.ttl == 0 {
+				storage.AssertNotCalled(t, "ExpireApprovals", mock.Anything, mock.Anything)
+				email.AssertNotCalled(t, "SendExpiryDigest", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestServiceRevokeTokens(t *testing.T) {
+	ctx := context.Background()
+	tokenizer := &mockTokenizer{}
+	tokenStorage := &mockTokenStorage{}
+
+	svc, err := core.NewService(core.ServiceConfig{
+		VacancyResolver:   &mockVacancyResolver{}, // not used
+		Queue:             &mockQueue{},           // not used
+		Email:             &mockEmailClient{},     // not used
+		Tokenizer:         tokenizer,
+		Approval:          &mockApproval{},          // not used
+		ApprovalStorage:   &mockApprovalStorage{},   // not used
+		SubmissionStorage: &mockSubmissionStorage{}, // not used
+		VacancyStorage:    &mockVacancyStorage{},    // not used
+		TokenStorage:      tokenStorage,
+		APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+	})
+	require.NoError(t, err)
+
+	// revoke a single token
+	tokenizer.On("DecodeToken", "test-token").Return(core.ApprovalToken{ID: "token-id"}, nil)
+	tokenStorage.On("RevokeToken", ctx, "token-id").Return(nil)
+
+	err = svc.RevokeTokens(ctx, core.RevokeTokensRequest{TokenRequest: "test-token"})
+	require.NoError(t, err)
+
+	// revoke all outstanding tokens
+	before := time.Now()
+	tokenStorage.On("RevokeTokensIssuedBefore", ctx, mock.AnythingOfType("time.Time")).Return(nil)
+
+	err = svc.RevokeTokens(ctx, core.RevokeTokensRequest{})
+	require.NoError(t, err)
+
+	mock.AssertExpectationsForObjects(t, tokenizer, tokenStorage)
+	revokedBefore := tokenStorage.Calls[len(tokenStorage.Calls)-1].Arguments.Get(1).(time.Time)
+	require.False(t, revokedBefore.Before(before))
+}
+
+func TestServiceAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	keyStorage := &mockAPIKeyStorage{}
+
+	svc, err := core.NewService(core.ServiceConfig{
+		VacancyResolver:   &mockVacancyResolver{},   // not used
+		Queue:             &mockQueue{},             // not used
+		Email:             &mockEmailClient{},       // not used
+		Tokenizer:         &mockTokenizer{},         // not used
+		Approval:          &mockApproval{},          // not used
+		ApprovalStorage:   &mockApprovalStorage{},   // not used
+		SubmissionStorage: &mockSubmissionStorage{}, // not used
+		VacancyStorage:    &mockVacancyStorage{},    // not used
+		TokenStorage:      &mockTokenStorage{},      // not used
+		APIKeyStorage:     keyStorage,
+	})
+	require.NoError(t, err)
+
+	// create key
+	var saved core.APIKey
+	keyStorage.On("SaveAPIKey", ctx, mock.AnythingOfType("APIKey")).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(core.APIKey)
+	}).Return(nil)
+
+	result, err := svc.CreateAPIKey(ctx, core.CreateAPIKeyRequest{
+		Name:                   " crawler ",
+		Scopes:                 []core.APIKeyScope{core.APIKeyScopeBulk},
+		DefaultSubmissionEmail: "crawler",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "crawler", saved.Name)
+	require.Equal(t, "crawler", saved.DefaultSubmissionEmail)
+	require.Equal(t, []core.APIKeyScope{core.APIKeyScopeBulk}, saved.Scopes)
+	require.True(t, strings.HasPrefix(result.Key, saved.ID+"."))
+	require.NotContains(t, saved.SecretHash, strings.TrimPrefix(result.Key, saved.ID+"."))
+
+	// authenticate with the created key
+	keyStorage.On("GetAPIKey", ctx, saved.ID).Return(&saved, nil)
+	keyStorage.On("TouchAPIKey", ctx, saved.ID).Return(nil)
+
+	key, err := svc.AuthenticateAPIKey(ctx, result.Key)
+	require.NoError(t, err)
+	require.Equal(t, saved.ID, key.ID)
+	keyStorage.AssertCalled(t, "TouchAPIKey", ctx, saved.ID)
+
+	// wrong secret, malformed & unknown key
+	_, err = svc.AuthenticateAPIKey(ctx, saved.ID+".wrong-secret")
+	require.ErrorIs(t, err, core.ErrInvalidAPIKey)
+
+	_, err = svc.AuthenticateAPIKey(ctx, "malformed")
+	require.ErrorIs(t, err, core.ErrInvalidAPIKey)
+
+	keyStorage.On("GetAPIKey", ctx, "unknown").Return(nil, shcore.NewNotFoundError("api key not found"))
+	_, err = svc.AuthenticateAPIKey(ctx, "unknown.secret")
+	require.ErrorIs(t, err, core.ErrInvalidAPIKey)
+
+	// revoked key
+	revokedAt := time.Now()
+	revoked := saved
+	revoked.ID = "revoked"
+	revoked.RevokedAt = &revokedAt
+	keyStorage.On("GetAPIKey", ctx, "revoked").Return(&revoked, nil)
+	_, err = svc.AuthenticateAPIKey(ctx, "revoked."+strings.TrimPrefix(result.Key, saved.ID+"."))
+	require.ErrorIs(t, err, core.ErrInvalidAPIKey)
+
+	// invalid create requests
+	_, err = svc.CreateAPIKey(ctx, core.CreateAPIKeyRequest{Scopes: []core.APIKeyScope{core.APIKeyScopeBulk}})
+	require.ErrorContains(t, err, "name is required")
+
+	_, err = svc.CreateAPIKey(ctx, core.CreateAPIKeyRequest{Name: "crawler"})
+	require.ErrorContains(t, err, "scopes cannot be empty")
+
+	_, err = svc.CreateAPIKey(ctx, core.CreateAPIKeyRequest{Name: "crawler", Scopes: []core.APIKeyScope{"superuser"}})
+	require.ErrorContains(t, err, "invalid scope")
+}
+
+func TestServiceListPendingApprovals(t *testing.T) {
+	ctx := context.Background()
+	tokenizer := &mockTokenizer{}
+	approvalStorage := &mockApprovalStorage{}
+	tokenStorage := &mockTokenStorage{}
+
+	svc, err := core.NewService(core.ServiceConfig{
+		VacancyResolver:   &mockVacancyResolver{}, // not used
+		Queue:             &mockQueue{},           // not used
+		Email:             &mockEmailClient{},     // not used
+		Tokenizer:         tokenizer,
+		Approval:          &mockApproval{}, // not used
+		ApprovalStorage:   approvalStorage,
+		SubmissionStorage: &mockSubmissionStorage{}, // not used
+		VacancyStorage:    &mockVacancyStorage{},    // not used
+		TokenStorage:      tokenStorage,
+		APIKeyStorage:     &mockAPIKeyStorage{}, // not used
+	})
+	require.NoError(t, err)
+
+	filter := core.ApprovalFilter{
+		SubmissionType: shcore.SubmitTypeManual,
+		MinAge:         24 * time.Hour,
+	}
+	req1 := shcore.SubmitRequest{SubmissionID: "sub-1", SubmissionEmail: "a@example.com"}
+	req2 := shcore.SubmitRequest{SubmissionID: "sub-2", SubmissionEmail: "b@example.com"}
+	req3 := shcore.SubmitRequest{SubmissionID: "sub-3", SubmissionEmail: "c@example.com"}
+	approvalStorage.On("ListPendingApprovals", ctx, filter).Return([]core.ApprovalRecord{
+		{MessageID: "msg-1", State: core.ApprovalStatePending, Request: req1, Token: "stored-1"},
+		{MessageID: "msg-2", State: core.ApprovalStatePending, Request: req2},
+		{MessageID: "msg-3", State: core.ApprovalStatePending, Request: req3, Token: "stored-3"},
+	}, nil)
+
+	// the stored token is reused while it is fresh, otherwise a new one is
+	// issued & stored
+	now := time.Now()
+	tokenizer.On("DecodeToken", "stored-1").Return(core.ApprovalToken{
+		ID: "id-1", Request: req1, IssuedAt: now.Add(-time.Hour), ExpiresAt: now.Add(7 * 24 * time.Hour),
+	}, nil)
+	tokenizer.On("DecodeToken", "stored-3").Return(core.ApprovalToken{
+		ID: "id-3", Request: req3, IssuedAt: now.Add(-6 * 24 * time.Hour), ExpiresAt: now.Add(24 * time.Hour),
+	}, nil)
+	tokenStorage.On("GetTokensRevokedBefore", ctx).Return(time.Time{}, nil)
+	tokenStorage.On("IsTokenRevoked", ctx, "id-1").Return(false, nil)
+	tokenizer.On("EncodeRequest", req2).Return("token-2", nil)
+	tokenizer.On("EncodeRequest", req3).Return("token-3", nil)
+	approvalStorage.On("SaveApprovalToken", ctx, "msg-2", "token-2").Return(nil)
+	approvalStorage.On("SaveApprovalToken", ctx, "msg-3", "token-3").Return(nil)
+
+	recs, err := svc.ListPendingApprovals(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, recs, 3)
+	require.Equal(t, "stored-1", recs[0].Token)
+	require.Equal(t, "token-2", recs[1].Token)
+	require.Equal(t, "token-3", recs[2].Token)
+	mock.AssertExpectationsForObjects(t, tokenizer, approvalStorage, tokenStorage)
+	tokenizer.AssertNotCalled(t, "EncodeRequest", req1)
+	approvalStorage.AssertNotCalled(t, "SaveApprovalToken", ctx, "msg-1", mock.Anything)
+}
+
+func TestServiceSendPendingDigest(t *testing.T) {
+	now := time.Now()
+	formReq := shcore.SubmitRequest{SubmissionID: "sub-1", SubmissionType: shcore.SubmitTypeManual}
+	urlReq := shcore.SubmitRequest{SubmissionID: "sub-2", SubmissionType: shcore.SubmitTypeURL}
+	crawlerReq := shcore.SubmitRequest{SubmissionID: "sub-3", SubmissionType: shcore.SubmitTypeManual}
+	pending := []core.ApprovalRecord{
+		{MessageID: "msg-1", State: core.ApprovalStatePending, Request: formReq, CreatedAt: now.Add(-2 * time.Hour)},
+		{MessageID: "msg-2", State: core.ApprovalStatePending, Request: urlReq, CreatedAt: now.Add(-8 * 24 * time.Hour)},
+		{MessageID: "msg-3", State: core.ApprovalStatePending, Kind: core.ApprovalKindBulk, Request: crawlerReq, CreatedAt: now.Add(-2 * 24 * time.Hour)},
+	}
+
+	tests := []struct {
+		name       string
+		pending    []core.ApprovalRecord
+		setupMocks func(ctx context.Context, e *mockEmailClient, tk *mockTokenizer)
+		expCount   int
+	}{
+		{
+			name:    "send digest",
+			pending: pending,
+			setupMocks: func(ctx context.Context, e *mockEmailClient, tk *mockTokenizer) {
+				tk.On("EncodeRequest", formReq).Return("token-1", nil)
+				tk.On("EncodeRequest", urlReq).Return("token-2", nil)
+				tk.On("EncodeRequest", crawlerReq).Return("token-3", nil)
+				e.On("SendPendingDigest", ctx, mock.MatchedBy(func(digest core.PendingDigest) bool {
+					if digest.Total != 3 || len(digest.Groups) != 3 {
+						return false
+					}
+					for i, source := range core.ApprovalSources {
+						group := digest.Groups[i]
+						if group.Source != source || len(group.Approvals) != 1 || group.Approvals[0].Token == "" {
+							return false
+						}
+					}
+					return true
+				})).Return(nil)
+			},
+			expCount: 3,
+		},
+		{
+			name:    "nothing pending",
+			pending: []core.ApprovalRecord{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			email := &mockEmailClient{}
+			tokenizer := &mockTokenizer{}
+			storage := &mockApprovalStorage{}
+
+			svc, err := core.NewService(core.ServiceConfig{
+				VacancyResolver:   &mockVacancyResolver{}, // not used
+				Queue:             &mockQueue{},           // not used
+				Email:             email,
+				Tokenizer:         tokenizer,
+				Approval:          &mockApproval{}, // not used
+				ApprovalStorage:   storage,
+				SubmissionStorage: &mockSubmissionStorage{}, // not used
+				VacancyStorage:    &mockVacancyStorage{},    // not used
+				TokenStorage:      &mockTokenStorage{},      // not used
+				APIKeyStorage:     &mockAPIKeyStorage{},     // not used
+			})
+			require.NoError(t, err)
+
+			storage.On("ListPendingApprovals", ctx, core.ApprovalFilter{}).Return(tt.pending, nil)
+			storage.On("SaveApprovalToken", ctx, mock.Anything, mock.Anything).Return(nil).Maybe()
+			if tt.setupMocks != nil {
+				tt.setupMocks(ctx, email, tokenizer)
+			}
+
+			count, err := svc.SendPendingDigest(ctx)
+			require.NoError(t, err)
+			require.Equal(t, tt.expCount, count)
+			mock.AssertExpectationsForObjects(t, email, tokenizer, storage)
+			if tt.expCount == 0 {
+				email.AssertNotCalled(t, "SendPendingDigest", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestNewPendingDigest(t *testing.T) {
+	now := time.Now()
+	approvals := []core.ApprovalRecord{
+		{MessageID: "msg-1", CreatedAt: now.Add(-time.Hour)},
+		{MessageID: "msg-2", CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{MessageID: "msg-3", CreatedAt: now.Add(-2 * 24 * time.Hour)},
+		{MessageID: "msg-4", CreatedAt: now.Add(-3 * time.Hour)},
+		{MessageID: "msg-5", Kind: core.ApprovalKindBulk, CreatedAt: now.Add(-time.Hour)},
+	}
+
+	digest := core.NewPendingDigest(approvals)
+	require.Equal(t, 5, digest.Total)
+
+	type group struct {
+		source     core.ApprovalSource
+		messageIDs []string
+	}
+	var groups []group
+	for _, g := range digest.Groups {
+		var ids []string
+		for _, rec := range g.Approvals {
+			ids = append(ids, rec.MessageID)
+		}
+		groups = append(groups, group{source: g.Source, messageIDs: ids})
+	}
+	require.Equal(t, []group{
+		{source: core.ApprovalSourceForm, messageIDs: []string{"msg-2"}},
+		{source: core.ApprovalSourceForm, messageIDs: []string{"msg-3"}},
+		{source: core.ApprovalSourceForm, messageIDs: []string{"msg-1", "msg-4"}},
+		{source: core.ApprovalSourceCrawler, messageIDs: []string{"msg-5"}},
+	}, groups)
+}
+
+type mockQueue struct {
+	mock.Mock
+}
+
+func (m *mockQueue) Put(ctx context.Context, req shcore.SubmitRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+type mockEmailClient struct {
+	mock.Mock
+}
 
 func (m *mockEmailClient) SendApprovalRequest(ctx context.Context, req shcore.SubmitRequest, token string) (string, error) {
 	args := m.Called(ctx, req, token)
 	return args.String(0), args.Error(1)
 }
 
-func (m *mockEmailClient) SendBulkApprovalRequest(ctx context.Context, req shcore.SubmitRequest, tokenReqVacancies []string) ([]string, error) {
-	args := m.Called(ctx, req, tokenReqVacancies)
+func (m *mockEmailClient) SendBulkApprovalRequest(ctx context.Context, req shcore.SubmitRequest, batchID string, tokenReqBatch string, tokenReqVacancies []string) ([]string, error) {
+	args := m.Called(ctx, req, batchID, tokenReqBatch, tokenReqVacancies)
 	return args.Get(0).([]string), args.Error(1)
 }
 
@@ -463,8 +1937,23 @@ func (m *mockEmailClient) ApproveRequest(ctx context.Context, messageID string)
 	return args.Error(0)
 }
 
-func (m *mockEmailClient) RejectRequest(ctx context.Context, messageID string) error {
-	args := m.Called(ctx, messageID)
+func (m *mockEmailClient) RejectRequest(ctx context.Context, messageID string, reason core.RejectReason) error {
+	args := m.Called(ctx, messageID, reason)
+	return args.Error(0)
+}
+
+func (m *mockEmailClient) SendSubmitterRejection(ctx context.Context, req shcore.SubmitRequest, reason core.RejectReason) error {
+	args := m.Called(ctx, req, reason)
+	return args.Error(0)
+}
+
+func (m *mockEmailClient) SendExpiryDigest(ctx context.Context, approvals []core.ApprovalRecord, ttl time.Duration) error {
+	args := m.Called(ctx, approvals, ttl)
+	return args.Error(0)
+}
+
+func (m *mockEmailClient) SendPendingDigest(ctx context.Context, digest core.PendingDigest) error {
+	args := m.Called(ctx, digest)
 	return args.Error(0)
 }
 
@@ -477,9 +1966,9 @@ func (m *mockTokenizer) EncodeRequest(req shcore.SubmitRequest) (string, error)
 	return args.String(0), args.Error(1)
 }
 
-func (m *mockTokenizer) DecodeToken(token string) (shcore.SubmitRequest, error) {
+func (m *mockTokenizer) DecodeToken(token string) (core.ApprovalToken, error) {
 	args := m.Called(token)
-	return args.Get(0).(shcore.SubmitRequest), args.Error(1)
+	return args.Get(0).(core.ApprovalToken), args.Error(1)
 }
 
 type mockApproval struct {
@@ -495,22 +1984,198 @@ type mockApprovalStorage struct {
 	mock.Mock
 }
 
-func (m *mockApprovalStorage) GetApprovalState(ctx context.Context, messageID string) (core.ApprovalState, error) {
-	args := m.Called(ctx, messageID)
-	return args.Get(0).(core.ApprovalState), args.Error(1)
-}
-
 func (m *mockApprovalStorage) UpdateApprovalState(ctx context.Context, messageID string, state core.ApprovalState) error {
 	args := m.Called(ctx, messageID, state)
 	return args.Error(0)
 }
 
+func (m *mockApprovalStorage) RejectApproval(ctx context.Context, messageID string, reason core.RejectReason) error {
+	args := m.Called(ctx, messageID, reason)
+	return args.Error(0)
+}
+
 func (m *mockApprovalStorage) SaveApprovalRequest(ctx context.Context, messageID string, req shcore.SubmitRequest) error {
 	args := m.Called(ctx, messageID, req)
 	return args.Error(0)
 }
 
-func (m *mockApprovalStorage) SaveBulkApprovalRequest(ctx context.Context, req shcore.SubmitRequest, messageIDs []string) error {
-	args := m.Called(ctx, req, messageIDs)
+func (m *mockApprovalStorage) SaveBulkApprovalRequest(ctx context.Context, batchID string, reqs []shcore.SubmitRequest, messageIDs []string) error {
+	args := m.Called(ctx, batchID, reqs, messageIDs)
+	return args.Error(0)
+}
+
+func (m *mockApprovalStorage) UpdateApprovalRequest(ctx context.Context, messageID string, req shcore.SubmitRequest) error {
+	args := m.Called(ctx, messageID, req)
+	return args.Error(0)
+}
+
+func (m *mockApprovalStorage) GetApproval(ctx context.Context, messageID string) (*core.ApprovalRecord, error) {
+	args := m.Called(ctx, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*core.ApprovalRecord), args.Error(1)
+}
+
+func (m *mockApprovalStorage) SaveApprovalToken(ctx context.Context, messageID string, token string) error {
+	args := m.Called(ctx, messageID, token)
+	return args.Error(0)
+}
+
+func (m *mockApprovalStorage) DecideBatchApprovals(ctx context.Context, batchID string, messageIDs []string, state core.ApprovalState, reason core.RejectReason) ([]core.ApprovalRecord, error) {
+	args := m.Called(ctx, batchID, messageIDs, state, reason)
+	recs, _ := args.Get(0).([]core.ApprovalRecord)
+	return recs, args.Error(1)
+}
+
+func (m *mockApprovalStorage) ListPendingApprovals(ctx context.Context, filter core.ApprovalFilter) ([]core.ApprovalRecord, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]core.ApprovalRecord), args.Error(1)
+}
+
+func (m *mockApprovalStorage) ExpireApprovals(ctx context.Context, minAge time.Duration) ([]core.ApprovalRecord, error) {
+	args := m.Called(ctx, minAge)
+	return args.Get(0).([]core.ApprovalRecord), args.Error(1)
+}
+
+func (m *mockApprovalStorage) FindApprovalByApplyURL(ctx context.Context, applyURL string) (*core.ApprovalRecord, error) {
+	args := m.Called(ctx, applyURL)
+	rec, _ := args.Get(0).(*core.ApprovalRecord)
+	return rec, args.Error(1)
+}
+
+// noDuplicate makes the storage find no approval for any apply url
+func (m *mockApprovalStorage) noDuplicate() {
+	m.On("FindApprovalByApplyURL", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+}
+
+type mockVacancyStorage struct {
+	mock.Mock
+}
+
+// noDuplicate makes the storage find no vacancy for any apply url
+func (m *mockVacancyStorage) noDuplicate() {
+	m.On("FindByApplyURL", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+}
+
+func (m *mockVacancyStorage) FindByApplyURL(ctx context.Context, applyURL string) (*shcore.VacancyRecord, error) {
+	args := m.Called(ctx, applyURL)
+	rec, _ := args.Get(0).(*shcore.VacancyRecord)
+	return rec, args.Error(1)
+}
+
+type mockVacancyResolver struct {
+	mock.Mock
+}
+
+// resolveAny makes the resolver resolve any url into a complete vacancy
+func (m *mockVacancyResolver) resolveAny() {
+	m.On("Resolve", mock.Anything, mock.Anything).Return(&shcore.Vacancy{
+		JobTitle:    "Software Engineer",
+		CompanyName: "Test Company",
+		ApplyURL:    "https://example.com/apply",
+	}, nil).Maybe()
+}
+
+func (m *mockVacancyResolver) Resolve(ctx context.Context, url string) (*shcore.Vacancy, error) {
+	args := m.Called(ctx, url)
+	return args.Get(0).(*shcore.Vacancy), args.Error(1)
+}
+
+func (m *mockVacancyResolver) ResolveWithParser(ctx context.Context, url string) (*shcore.Vacancy, string, error) {
+	args := m.Called(ctx, url)
+	v, _ := args.Get(0).(*shcore.Vacancy)
+	return v, args.String(1), args.Error(2)
+}
+
+type mockSubmissionStorage struct {
+	mock.Mock
+}
+
+// acceptAny makes the storage accept any submission write
+func (m *mockSubmissionStorage) acceptAny() {
+	m.On("SaveSubmission", mock.Anything, mock.Anything).Return(nil).Maybe()
+	m.On("UpdateSubmission", mock.Anything, mock.Anything).Return(nil).Maybe()
+}
+
+func (m *mockSubmissionStorage) SaveSubmission(ctx context.Context, sub shcore.Submission) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *mockSubmissionStorage) UpdateSubmission(ctx context.Context, upd shcore.SubmissionUpdate) error {
+	args := m.Called(ctx, upd)
+	return args.Error(0)
+}
+
+func (m *mockSubmissionStorage) GetSubmission(ctx context.Context, id string) (*shcore.Submission, error) {
+	args := m.Called(ctx, id)
+	sub, _ := args.Get(0).(*shcore.Submission)
+	return sub, args.Error(1)
+}
+
+type mockTokenStorage struct {
+	mock.Mock
+}
+
+// acceptAny makes every token unrevoked and consumable
+func (m *mockTokenStorage) acceptAny() {
+	m.On("GetTokensRevokedBefore", mock.Anything).Return(time.Time{}, nil).Maybe()
+	m.On("ConsumeToken", mock.Anything, mock.Anything).Return(true, nil).Maybe()
+	m.On("IsTokenRevoked", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+}
+
+func (m *mockTokenStorage) ConsumeToken(ctx context.Context, tokenID string) (bool, error) {
+	args := m.Called(ctx, tokenID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockTokenStorage) RevokeToken(ctx context.Context, tokenID string) error {
+	args := m.Called(ctx, tokenID)
+	return args.Error(0)
+}
+
+func (m *mockTokenStorage) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	args := m.Called(ctx, tokenID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockTokenStorage) RevokeTokensIssuedBefore(ctx context.Context, t time.Time) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *mockTokenStorage) GetTokensRevokedBefore(ctx context.Context) (time.Time, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+type mockAPIKeyStorage struct {
+	mock.Mock
+}
+
+func (m *mockAPIKeyStorage) SaveAPIKey(ctx context.Context, key core.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *mockAPIKeyStorage) GetAPIKey(ctx context.Context, id string) (*core.APIKey, error) {
+	args := m.Called(ctx, id)
+	key, _ := args.Get(0).(*core.APIKey)
+	return key, args.Error(1)
+}
+
+func (m *mockAPIKeyStorage) ListAPIKeys(ctx context.Context) ([]core.APIKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]core.APIKey), args.Error(1)
+}
+
+func (m *mockAPIKeyStorage) RevokeAPIKey(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockAPIKeyStorage) TouchAPIKey(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
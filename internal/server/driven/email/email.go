@@ -3,10 +3,12 @@ package email
 import (
 	"context"
 	"fmt"
-	"net/smtp"
 	"strings"
+	"time"
 
+	servercore "github.com/ghazlabs/idn-remote-entry/internal/server/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/mailer"
 	"gopkg.in/validator.v2"
 )
 
@@ -14,20 +16,30 @@ type EmailConfig struct {
 	Host         string `validate:"nonzero"`
 	Port         int    `validate:"nonzero"`
 	From         string `validate:"nonzero"`
-	Password     string `validate:"nonzero"`
+	Password     string
 	ServerDomain string `validate:"nonzero"`
 	AdminEmails  string `validate:"nonzero"`
 }
 
 type EmailClient struct {
 	EmailConfig
+	mailer *mailer.Mailer
 }
 
 func NewEmail(config EmailConfig) (*EmailClient, error) {
 	if err := validator.Validate(config); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
-	return &EmailClient{EmailConfig: config}, nil
+	m, err := mailer.NewMailer(mailer.MailerConfig{
+		Host:     config.Host,
+		Port:     config.Port,
+		From:     config.From,
+		Password: config.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+	return &EmailClient{EmailConfig: config, mailer: m}, nil
 }
 
 func (e *EmailClient) SendApprovalRequest(ctx context.Context, req core.SubmitRequest, tokenReq string) (string, error) {
@@ -49,18 +61,22 @@ func (e *EmailClient) SendApprovalRequest(ctx context.Context, req core.SubmitRe
 	return messageID, nil
 }
 
-func (e *EmailClient) SendBulkApprovalRequest(ctx context.Context, req core.SubmitRequest, tokenReqVacancies []string) ([]string, error) {
-	messageID := generateMessageID("bulk")
+func (e *EmailClient) SendBulkApprovalRequest(ctx context.Context, req core.SubmitRequest, batchID string, tokenReqBatch string, tokenReqVacancies []string) ([]string, error) {
+	// the batch id is used as message id so the email can be traced back
+	// to its batch
+	messageID := servercore.BulkApprovalMessageID(batchID)
 	codeID := getCodeMessageID(messageID)
 	headers := e.buildHeaders(messageID, "", fmt.Sprintf("IDNRemote.com - New Crawled Job Vacancy Approval - ID: %s", codeID))
 
 	messagesIDs := make([]string, len(req.BulkVacancies))
-	for i, _ := range req.BulkVacancies {
+	for i := range req.BulkVacancies {
 		messagesIDs[i] = generateMessageID("bulk")
 	}
 
 	body := templateBulkEmail{
 		req:            req,
+		batchID:        batchID,
+		tokenBatch:     tokenReqBatch,
 		tokenVacancies: tokenReqVacancies,
 		messagesIDs:    messagesIDs,
 		serverDomain:   e.ServerDomain,
@@ -80,13 +96,54 @@ func (e *EmailClient) ApproveRequest(ctx context.Context, messageID string) erro
 	return e.sendEmail(headers, messageID, message, message)
 }
 
-func (e *EmailClient) RejectRequest(ctx context.Context, messageID string) error {
+func (e *EmailClient) RejectRequest(ctx context.Context, messageID string, reason servercore.RejectReason) error {
 	message := "Rejected by admin"
+	if reason != "" {
+		message = fmt.Sprintf("Rejected by admin, reason: %s", reason.Description())
+	}
 	codeID := getCodeMessageID(messageID)
 	headers := e.buildHeaders(messageID, messageID, fmt.Sprintf("Re: IDNRemote.com - New Job Vacancy Approval - ID: %s", codeID))
 	return e.sendEmail(headers, messageID, message, message)
 }
 
+func (e *EmailClient) SendSubmitterRejection(ctx context.Context, req core.SubmitRequest, reason servercore.RejectReason) error {
+	messageID := generateMessageID("idnremote.com")
+	headers := e.buildHeaders(messageID, "", "IDNRemote.com - Your Job Vacancy Submission Was Not Approved")
+	headers["To"] = req.SubmissionEmail
+
+	body := templateSubmitterRejection{
+		req:    req,
+		reason: reason,
+	}
+
+	return e.sendEmailTo([]string{req.SubmissionEmail}, headers, messageID, body.getContentBodyHTML(), body.getContentBodyPlain())
+}
+
+func (e *EmailClient) SendExpiryDigest(ctx context.Context, approvals []servercore.ApprovalRecord, ttl time.Duration) error {
+	messageID := generateMessageID("idnremote.com")
+	headers := e.buildHeaders(messageID, "", fmt.Sprintf("IDNRemote.com - %d Job Vacancy Approvals Are About to Expire", len(approvals)))
+
+	body := templateExpiryDigest{
+		approvals:    approvals,
+		ttl:          ttl,
+		serverDomain: e.ServerDomain,
+	}
+
+	return e.sendEmail(headers, messageID, body.getContentBodyHTML(), body.getContentBodyPlain())
+}
+
+func (e *EmailClient) SendPendingDigest(ctx context.Context, digest servercore.PendingDigest) error {
+	messageID := generateMessageID("idnremote.com")
+	headers := e.buildHeaders(messageID, "", fmt.Sprintf("IDNRemote.com - Daily Digest: %d Job Vacancies Waiting for Approval", digest.Total))
+
+	body := templatePendingDigest{
+		digest:       digest,
+		serverDomain: e.ServerDomain,
+	}
+
+	return e.sendEmail(headers, messageID, body.getContentBodyHTML(), body.getContentBodyPlain())
+}
+
 func (e *EmailClient) buildHeaders(messageID, inReplyTo, subject string) map[string]string {
 	headers := map[string]string{
 		"From":    fmt.Sprintf("IDN Remote Entry <%s>", e.From),
@@ -106,54 +163,15 @@ func (e *EmailClient) buildHeaders(messageID, inReplyTo, subject string) map[str
 }
 
 func (e *EmailClient) sendEmail(headers map[string]string, messageID, bodyHTML, bodyPlain string) error {
-	addr := fmt.Sprintf("%s:%d", e.Host, e.Port)
-	emailReceivers := strings.Split(e.AdminEmails, ",")
-	boundary := getCodeMessageID(messageID)
-
-	message := e.buildEmailMessage(headers, boundary, bodyHTML, bodyPlain)
-
-	var auth smtp.Auth
-	if !e.isLocal() {
-		auth = smtp.PlainAuth("IDN Remote Entry", e.From, e.Password, e.Host)
-	}
-
-	return smtp.SendMail(addr, auth, e.From, emailReceivers, []byte(message))
-}
-
-func (e *EmailClient) buildEmailMessage(headers map[string]string, boundary, bodyHTML, bodyPlain string) string {
-	var message strings.Builder
-
-	// Add headers
-	for k, v := range headers {
-		fmt.Fprintf(&message, "%s: %s\r\n", k, v)
-	}
-
-	// Add MIME headers
-	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
-	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary)
-
-	// Add plain text version
-	fmt.Fprintf(&message, "--%s\r\n", boundary)
-	fmt.Fprintf(&message, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
-	fmt.Fprintf(&message, "%s\r\n\r\n", strings.ReplaceAll(bodyPlain, "<br>", "\n"))
-
-	// Add HTML version
-	fmt.Fprintf(&message, "--%s\r\n", boundary)
-	fmt.Fprintf(&message, "Content-Type: text/html; charset=UTF-8\r\n\r\n")
-	fmt.Fprintf(&message, `<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-</head>
-<body>
-    %s
-</body>
-</html>`, bodyHTML)
-
-	fmt.Fprintf(&message, "\r\n\r\n--%s--", boundary)
-	return message.String()
+	return e.sendEmailTo(strings.Split(e.AdminEmails, ","), headers, messageID, bodyHTML, bodyPlain)
 }
 
-func (e EmailClient) isLocal() bool {
-	return e.Host == "mailpit" || strings.Contains(e.Host, "localhost")
+func (e *EmailClient) sendEmailTo(emailReceivers []string, headers map[string]string, messageID, bodyHTML, bodyPlain string) error {
+	return e.mailer.Send(mailer.Message{
+		To:        emailReceivers,
+		Headers:   headers,
+		Boundary:  getCodeMessageID(messageID),
+		BodyHTML:  bodyHTML,
+		BodyPlain: bodyPlain,
+	})
 }
@@ -3,7 +3,9 @@ package email
 import (
 	"context"
 	"testing"
+	"time"
 
+	servercore "github.com/ghazlabs/idn-remote-entry/internal/server/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/testutil"
 	"github.com/riandyrn/go-env"
@@ -41,3 +43,101 @@ func TestSendApprovalRequest(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, msgID)
 }
+
+func TestSendSubmitterRejection(t *testing.T) {
+	client, err := NewEmail(EmailConfig{
+		Host:         env.GetString(testutil.EnvKeySMTPHost),
+		Port:         env.GetInt(testutil.EnvKeySMTPPort),
+		From:         "from@example.com",
+		Password:     "password",
+		ServerDomain: "http://example.com",
+		AdminEmails:  "admin1@example.com,admin2@example.com",
+	})
+	assert.NoError(t, err)
+
+	req := core.SubmitRequest{
+		SubmissionEmail: "user@example.com",
+		SubmissionType:  core.SubmitTypeManual,
+		Vacancy: core.Vacancy{
+			JobTitle:    "Software Engineer",
+			CompanyName: "Example Inc.",
+			ApplyURL:    "http://example.com/apply",
+		},
+	}
+
+	err = client.SendSubmitterRejection(context.Background(), req, servercore.RejectReasonNotRemote)
+	assert.NoError(t, err)
+}
+
+func TestSendExpiryDigest(t *testing.T) {
+	client, err := NewEmail(EmailConfig{
+		Host:         env.GetString(testutil.EnvKeySMTPHost),
+		Port:         env.GetInt(testutil.EnvKeySMTPPort),
+		From:         "from@example.com",
+		Password:     "password",
+		ServerDomain: "http://example.com",
+		AdminEmails:  "admin1@example.com,admin2@example.com",
+	})
+	assert.NoError(t, err)
+
+	approvals := []servercore.ApprovalRecord{
+		{
+			MessageID: "abc@idnremote.com",
+			State:     servercore.ApprovalStatePending,
+			Request: core.SubmitRequest{
+				SubmissionEmail: "user@example.com",
+				SubmissionType:  core.SubmitTypeURL,
+				Vacancy:         core.Vacancy{ApplyURL: "http://example.com/apply"},
+			},
+			CreatedAt: time.Now().Add(-6 * 24 * time.Hour),
+		},
+	}
+
+	err = client.SendExpiryDigest(context.Background(), approvals, 7*24*time.Hour)
+	assert.NoError(t, err)
+}
+
+func TestSendPendingDigest(t *testing.T) {
+	client, err := NewEmail(EmailConfig{
+		Host:         env.GetString(testutil.EnvKeySMTPHost),
+		Port:         env.GetInt(testutil.EnvKeySMTPPort),
+		From:         "from@example.com",
+		Password:     "password",
+		ServerDomain: "http://example.com",
+		AdminEmails:  "admin1@example.com,admin2@example.com",
+	})
+	assert.NoError(t, err)
+
+	digest := servercore.NewPendingDigest([]servercore.ApprovalRecord{
+		{
+			MessageID: "abc@idnremote.com",
+			State:     servercore.ApprovalStatePending,
+			Request: core.SubmitRequest{
+				SubmissionEmail: "user@example.com",
+				SubmissionType:  core.SubmitTypeURL,
+				Vacancy:         core.Vacancy{ApplyURL: "http://example.com/apply"},
+			},
+			Token:     "token-1",
+			CreatedAt: time.Now().Add(-4 * 24 * time.Hour),
+		},
+		{
+			MessageID: "def@bulk",
+			State:     servercore.ApprovalStatePending,
+			Kind:      servercore.ApprovalKindBulk,
+			Request: core.SubmitRequest{
+				SubmissionEmail: "crawler@example.com",
+				SubmissionType:  core.SubmitTypeManual,
+				Vacancy: core.Vacancy{
+					JobTitle:    "Software Engineer",
+					CompanyName: "Example",
+					ApplyURL:    "http://example.com/apply-2",
+				},
+			},
+			Token:     "token-2",
+			CreatedAt: time.Now().Add(-time.Hour),
+		},
+	})
+
+	err = client.SendPendingDigest(context.Background(), digest)
+	assert.NoError(t, err)
+}
@@ -3,8 +3,12 @@ package email
 import (
 	"crypto/rand"
 	"fmt"
+	"html"
+	"net/url"
 	"strings"
+	"time"
 
+	servercore "github.com/ghazlabs/idn-remote-entry/internal/server/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 )
 
@@ -14,6 +18,7 @@ const (
 	formatHTML        contentFormat = "html"
 	formatPlain       contentFormat = "plain"
 	generatedIDLength               = 10
+	maxSummaryLength                = 200
 )
 
 var possibleChars = []byte("abcdefghijklmnopqrstuvwxyz0123456789")
@@ -32,8 +37,16 @@ func getCodeMessageID(messageID string) string {
 	return ID
 }
 
+// getApprovalLink returns the link for deciding on single vacancy, action
+// is either `approve` or `reject`.
+func getApprovalLink(serverDomain, action, token, messageID string) string {
+	return fmt.Sprintf("%s/vacancies/%s?data=%s&message_id=%s", serverDomain, action, token, messageID)
+}
+
 type templateBulkEmail struct {
 	req            core.SubmitRequest
+	batchID        string
+	tokenBatch     string
 	tokenVacancies []string
 	messagesIDs    []string
 	serverDomain   string
@@ -43,6 +56,7 @@ func (t templateBulkEmail) getContent(format contentFormat) string {
 	parts := []string{
 		t.getHeader(format),
 		t.getDetail(format),
+		t.getBatchActions(format),
 		t.getVacanciesTable(format),
 	}
 	separator := "\r\n"
@@ -89,21 +103,66 @@ func (t templateBulkEmail) getDetail(format contentFormat) string {
 	return content.String()
 }
 
+// getBatchActions returns the links for deciding on every vacancy at once,
+// selecting some of them is done through the form of the vacancies table or
+// in admin dashboard for email clients which don't submit forms.
+func (t templateBulkEmail) getBatchActions(format contentFormat) string {
+	batchQuery := url.Values{"data": {t.tokenBatch}, "batch_id": {t.batchID}}.Encode()
+	approveAllLink := fmt.Sprintf("%s/vacancies/batch/approve?%s", t.serverDomain, batchQuery)
+	rejectAllLink := fmt.Sprintf("%s/vacancies/batch/reject?%s", t.serverDomain, batchQuery)
+	dashboardLink := fmt.Sprintf("%s/admin/approvals?%s", t.serverDomain, url.Values{"batch": {t.batchID}}.Encode())
+
+	if format == formatHTML {
+		return fmt.Sprintf(`<p>
+  <a href="%s" style="display: inline-block; background-color: #4CAF50; color: white; padding: 8px 16px; text-decoration: none; border-radius: 4px; margin-right: 5px; font-weight: bold;">
+    Approve All
+  </a>
+  <a href="%s" style="display: inline-block; background-color: #f44336; color: white; padding: 8px 16px; text-decoration: none; border-radius: 4px; margin-right: 5px; font-weight: bold;">
+    Reject All
+  </a>
+  <a href="%s" style="display: inline-block; background-color: #2196F3; color: white; padding: 8px 16px; text-decoration: none; border-radius: 4px; font-weight: bold;">
+    Review in Dashboard
+  </a>
+</p>`, html.EscapeString(approveAllLink), html.EscapeString(rejectAllLink), html.EscapeString(dashboardLink))
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "Approve All: %s\n", approveAllLink)
+	fmt.Fprintf(&content, "Reject All: %s\n", rejectAllLink)
+	fmt.Fprintf(&content, "Approve Selected: %s&message_id=<message id>, repeat message_id for every selected vacancy\n", t.getApproveSelectedLink())
+	fmt.Fprintf(&content, "Review in Dashboard: %s\n", dashboardLink)
+	return content.String()
+}
+
+// getApproveSelectedLink returns the link for approving the selected
+// vacancies without the message_id query, which is appended per selection.
+func (t templateBulkEmail) getApproveSelectedLink() string {
+	batchQuery := url.Values{"data": {t.tokenBatch}, "batch_id": {t.batchID}}.Encode()
+	return fmt.Sprintf("%s/vacancies/batch/approve/selected?%s", t.serverDomain, batchQuery)
+}
+
 func (t templateBulkEmail) getVacanciesTable(format contentFormat) string {
 	if format == formatHTML {
 		var tableHTML strings.Builder
 
-		tableHTML.WriteString(`<div style="margin: 20px 0;">
-  <table style="width: 100%; border-collapse: collapse; border: 1px solid #ddd;">
+		tableHTML.WriteString(fmt.Sprintf(`<div style="margin: 20px 0;">
+  <form method="get" action="%s/vacancies/batch/approve/selected" target="_blank">
+  <input type="hidden" name="data" value="%s">
+  <input type="hidden" name="batch_id" value="%s">
+  <table style="width: 100%%; border-collapse: collapse; border: 1px solid #ddd;">
     <thead>
       <tr style="background-color: #f2f2f2;">
+        <th style="border: 1px solid #ddd; padding: 8px; text-align: left;">Select</th>
         <th style="border: 1px solid #ddd; padding: 8px; text-align: left;">No</th>
+        <th style="border: 1px solid #ddd; padding: 8px; text-align: left;">Type</th>
         <th style="border: 1px solid #ddd; padding: 8px; text-align: left;">Job Title</th>
         <th style="border: 1px solid #ddd; padding: 8px; text-align: left;">Company</th>
+        <th style="border: 1px solid #ddd; padding: 8px; text-align: left;">Location</th>
+        <th style="border: 1px solid #ddd; padding: 8px; text-align: left;">Tags</th>
         <th style="border: 1px solid #ddd; padding: 8px; text-align: left;">Action</th>
       </tr>
     </thead>
-    <tbody>`)
+    <tbody>`, t.serverDomain, html.EscapeString(t.tokenBatch), html.EscapeString(t.batchID)))
 
 		for i, vacancy := range t.req.BulkVacancies {
 			rowStyle := ""
@@ -111,13 +170,17 @@ func (t templateBulkEmail) getVacanciesTable(format contentFormat) string {
 				rowStyle = `style="background-color: #f9f9f9;"`
 			}
 
-			approveLink := fmt.Sprintf("%s/vacancies/approve?data=%s&message_id=%s", t.serverDomain, t.tokenVacancies[i], t.messagesIDs[i])
-			rejectLink := fmt.Sprintf("%s/vacancies/reject?data=%s&message_id=%s", t.serverDomain, t.tokenVacancies[i], t.messagesIDs[i])
+			approveLink := getApprovalLink(t.serverDomain, "approve", t.tokenVacancies[i], t.messagesIDs[i])
+			rejectLink := getApprovalLink(t.serverDomain, "reject", t.tokenVacancies[i], t.messagesIDs[i])
 
 			tableHTML.WriteString(fmt.Sprintf(`
       <tr %s>
+        <td style="border: 1px solid #ddd; padding: 8px;"><input type="checkbox" name="message_id" value="%s"></td>
         <td style="border: 1px solid #ddd; padding: 8px;">%d</td>
-        <td style="border: 1px solid #ddd; padding: 8px;"><a href="%s" target="_blank">%s</a></td>
+        <td style="border: 1px solid #ddd; padding: 8px;">%s</td>
+        <td style="border: 1px solid #ddd; padding: 8px;"><a href="%s" target="_blank">%s</a><br><small style="color: #666;">%s</small></td>
+        <td style="border: 1px solid #ddd; padding: 8px;">%s</td>
+        <td style="border: 1px solid #ddd; padding: 8px;">%s</td>
         <td style="border: 1px solid #ddd; padding: 8px;">%s</td>
         <td style="border: 1px solid #ddd; padding: 8px;">
           <a href="%s" style="display: inline-block; background-color: #4CAF50; color: white; padding: 5px 10px; text-decoration: none; border-radius: 4px; margin-right: 5px; font-size: 12px; font-weight: bold;">
@@ -127,12 +190,21 @@ func (t templateBulkEmail) getVacanciesTable(format contentFormat) string {
             Reject
           </a>
         </td>
-      </tr>`, rowStyle, i+1, vacancy.ApplyURL, vacancy.JobTitle, vacancy.CompanyName, approveLink, rejectLink))
+      </tr>`, rowStyle, html.EscapeString(t.messagesIDs[i]), i+1, vacancy.Type(),
+				html.EscapeString(vacancy.ApplyURL), html.EscapeString(vacancy.JobTitle), html.EscapeString(summarizeDescription(vacancy.ShortDescription)),
+				html.EscapeString(vacancy.CompanyName), html.EscapeString(vacancy.CompanyLocation), html.EscapeString(strings.Join(vacancy.RelevantTags, ", ")),
+				approveLink, rejectLink))
 		}
 
 		tableHTML.WriteString(`
     </tbody>
   </table>
+  <p>
+    <button type="submit" style="background-color: #2196F3; color: white; padding: 8px 16px; border: none; border-radius: 4px; font-weight: bold;">
+      Approve Selected
+    </button>
+  </p>
+  </form>
   
   <p style="margin-top: 20px;">
     Thank you for reviewing these vacancies.<br>
@@ -149,11 +221,18 @@ func (t templateBulkEmail) getVacanciesTable(format contentFormat) string {
 	tablePlain.WriteString("\nVacancies to review:\n\n")
 
 	for i, vacancy := range t.req.BulkVacancies {
-		approveLink := fmt.Sprintf("%s/vacancies/approve?data=%s&message_id=%s", t.serverDomain, t.tokenVacancies[i], t.messagesIDs[i])
-		rejectLink := fmt.Sprintf("%s/vacancies/reject?data=%s&message_id=%s", t.serverDomain, t.tokenVacancies[i], t.messagesIDs[i])
+		approveLink := getApprovalLink(t.serverDomain, "approve", t.tokenVacancies[i], t.messagesIDs[i])
+		rejectLink := getApprovalLink(t.serverDomain, "reject", t.tokenVacancies[i], t.messagesIDs[i])
 
 		tablePlain.WriteString(fmt.Sprintf("%d. %s at %s\n", i+1, vacancy.JobTitle, vacancy.CompanyName))
+		tablePlain.WriteString(fmt.Sprintf("   Type: %s\n", vacancy.Type()))
+		if vacancy.Type() == core.SubmitTypeManual {
+			tablePlain.WriteString(fmt.Sprintf("   Location: %s\n", vacancy.CompanyLocation))
+			tablePlain.WriteString(fmt.Sprintf("   Tags: %s\n", strings.Join(vacancy.RelevantTags, ", ")))
+			tablePlain.WriteString(fmt.Sprintf("   Description: %s\n", summarizeDescription(vacancy.ShortDescription)))
+		}
 		tablePlain.WriteString(fmt.Sprintf("   URL: %s\n", vacancy.ApplyURL))
+		tablePlain.WriteString(fmt.Sprintf("   Message ID: %s\n", t.messagesIDs[i]))
 		tablePlain.WriteString(fmt.Sprintf("   Approve: %s\n", approveLink))
 		tablePlain.WriteString(fmt.Sprintf("   Reject: %s\n\n", rejectLink))
 	}
@@ -163,6 +242,16 @@ func (t templateBulkEmail) getVacanciesTable(format contentFormat) string {
 	return tablePlain.String()
 }
 
+// summarizeDescription shortens the description so the bulk email stays
+// readable, the full description can be seen in admin dashboard.
+func summarizeDescription(desc string) string {
+	runes := []rune(strings.TrimSpace(desc))
+	if len(runes) <= maxSummaryLength {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:maxSummaryLength])) + "..."
+}
+
 type templateEmail struct {
 	req          core.SubmitRequest
 	token        string
@@ -234,8 +323,8 @@ func (t templateEmail) getDetail(format contentFormat) string {
 }
 
 func (t templateEmail) getApproval(format contentFormat) string {
-	approveLink := fmt.Sprintf("%s/vacancies/approve?data=%s&message_id=%s", t.serverDomain, t.token, t.messageID)
-	rejectLink := fmt.Sprintf("%s/vacancies/reject?data=%s&message_id=%s", t.serverDomain, t.token, t.messageID)
+	approveLink := getApprovalLink(t.serverDomain, "approve", t.token, t.messageID)
+	rejectLink := getApprovalLink(t.serverDomain, "reject", t.token, t.messageID)
 
 	if format == formatHTML {
 		return fmt.Sprintf(`
@@ -249,12 +338,16 @@ func (t templateEmail) getApproval(format contentFormat) string {
   <a href="%s" style="display: inline-block; background-color: #f44336; color: white; padding: 10px 20px; text-decoration: none; border-radius: 4px; font-weight: bold;">
   	Reject
   </a>
+
+  <p>Or reject with a reason:</p>
+  <ul>%s
+  </ul>
   
   <p style="margin-top: 20px;">
 	Thank you and have a nice day!<br>
 	Barakallahu fiikum
   </p>
-</div>`, approveLink, rejectLink)
+</div>`, approveLink, rejectLink, t.getRejectReasonLinks(format))
 	}
 
 	return fmt.Sprintf(`
@@ -268,6 +361,224 @@ Reject:
 
 %s
 
+Or reject with a reason:
+%s
+
 Thank you and have a nice day!
-Barakallahu fiikum`, approveLink, rejectLink)
+Barakallahu fiikum`, approveLink, rejectLink, t.getRejectReasonLinks(format))
+}
+
+func (t templateEmail) getRejectReasonLinks(format contentFormat) string {
+	var links strings.Builder
+	for _, reason := range servercore.RejectReasons {
+		link := fmt.Sprintf("%s&reason=%s", getApprovalLink(t.serverDomain, "reject", t.token, t.messageID), reason)
+		if format == formatHTML {
+			fmt.Fprintf(&links, "\n    <li><a href=\"%s\">%s</a></li>", link, reason.Description())
+		} else {
+			fmt.Fprintf(&links, "\n%s:\n%s\n", reason.Description(), link)
+		}
+	}
+	return links.String()
+}
+
+type templateSubmitterRejection struct {
+	req    core.SubmitRequest
+	reason servercore.RejectReason
+}
+
+func (t templateSubmitterRejection) getContentBodyHTML() string {
+	return t.getContent(formatHTML)
+}
+
+func (t templateSubmitterRejection) getContentBodyPlain() string {
+	return t.getContent(formatPlain)
+}
+
+func (t templateSubmitterRejection) getContent(format contentFormat) string {
+	vacancy := describeVacancy(t.req.Vacancy)
+	reason := t.reason.Description()
+	if reason == "" {
+		reason = "The vacancy doesn't meet our posting criteria"
+	}
+
+	if format == formatHTML {
+		return fmt.Sprintf(`<p>Bismillah<br>Assalamu'alaikum warahmatullahi wabarakatuh</p>
+<p>Hello! <br> Thank you for submitting a job vacancy to IDNRemote.com.</p>
+<p>Unfortunately, after reviewing your submission we decided not to publish it:</p>
+<p>
+	Vacancy: %s<br>
+	URL: %s<br>
+	Reason: %s
+</p>
+<p>
+	Thank you and have a nice day!<br>
+	Barakallahu fiikum
+</p>`, html.EscapeString(vacancy), html.EscapeString(t.req.Vacancy.ApplyURL), html.EscapeString(reason))
+	}
+
+	return fmt.Sprintf(`Bismillah
+Assalamu'alaikum warahmatullahi wabarakatuh
+
+Hello!
+Thank you for submitting a job vacancy to IDNRemote.com.
+
+Unfortunately, after reviewing your submission we decided not to publish it:
+
+Vacancy: %s
+URL: %s
+Reason: %s
+
+Thank you and have a nice day!
+Barakallahu fiikum`, vacancy, t.req.Vacancy.ApplyURL, reason)
+}
+
+// describeVacancy returns the job title along with the company, url vacancy
+// which hasn't been resolved is described by its apply url.
+func describeVacancy(v core.Vacancy) string {
+	desc := v.JobTitle
+	if v.CompanyName != "" {
+		desc = fmt.Sprintf("%s at %s", desc, v.CompanyName)
+	}
+	if desc == "" {
+		desc = v.ApplyURL
+	}
+	return desc
+}
+
+type templateExpiryDigest struct {
+	approvals    []servercore.ApprovalRecord
+	ttl          time.Duration
+	serverDomain string
+}
+
+func (t templateExpiryDigest) getContentBodyHTML() string {
+	return t.getContent(formatHTML)
+}
+
+func (t templateExpiryDigest) getContentBodyPlain() string {
+	return t.getContent(formatPlain)
+}
+
+func (t templateExpiryDigest) getContent(format contentFormat) string {
+	var content strings.Builder
+	if format == formatHTML {
+		fmt.Fprintf(&content, `<p>Bismillah<br>Assalamu'alaikum warahmatullahi wabarakatuh</p>
+<p>Hello Admin! <br> The following %d job vacancies will expire soon if nobody reviews them:</p>
+<ul>`, len(t.approvals))
+		for _, rec := range t.approvals {
+			fmt.Fprintf(&content, "\n  <li><a href=\"%s\">%s</a> from %s, expires at %s</li>",
+				html.EscapeString(t.getDetailLink(rec)),
+				html.EscapeString(describeVacancy(rec.Request.Vacancy)),
+				html.EscapeString(rec.Request.SubmissionEmail),
+				rec.ExpiresAt(t.ttl).UTC().Format(time.RFC1123),
+			)
+		}
+		content.WriteString(`
+</ul>
+<p>
+	Thank you and have a nice day!<br>
+	Barakallahu fiikum
+</p>`)
+		return content.String()
+	}
+
+	fmt.Fprintf(&content, `Bismillah
+Assalamu'alaikum warahmatullahi wabarakatuh
+
+Hello Admin!
+The following %d job vacancies will expire soon if nobody reviews them:
+
+`, len(t.approvals))
+	for i, rec := range t.approvals {
+		fmt.Fprintf(&content, "%d. %s\n", i+1, describeVacancy(rec.Request.Vacancy))
+		fmt.Fprintf(&content, "   Submission From: %s\n", rec.Request.SubmissionEmail)
+		fmt.Fprintf(&content, "   Expires At: %s\n", rec.ExpiresAt(t.ttl).UTC().Format(time.RFC1123))
+		fmt.Fprintf(&content, "   Review: %s\n\n", t.getDetailLink(rec))
+	}
+	content.WriteString("Thank you and have a nice day!\nBarakallahu fiikum")
+	return content.String()
+}
+
+// getDetailLink returns the link of the approval in admin dashboard, the
+// approval email may have been buried by then.
+func (t templateExpiryDigest) getDetailLink(rec servercore.ApprovalRecord) string {
+	return fmt.Sprintf("%s/admin/approvals/%s", t.serverDomain, url.PathEscape(rec.MessageID))
+}
+
+type templatePendingDigest struct {
+	digest       servercore.PendingDigest
+	serverDomain string
+}
+
+func (t templatePendingDigest) getContentBodyHTML() string {
+	return t.getContent(formatHTML)
+}
+
+func (t templatePendingDigest) getContentBodyPlain() string {
+	return t.getContent(formatPlain)
+}
+
+func (t templatePendingDigest) getContent(format contentFormat) string {
+	var content strings.Builder
+	if format == formatHTML {
+		fmt.Fprintf(&content, `<p>Bismillah<br>Assalamu'alaikum warahmatullahi wabarakatuh</p>
+<p>Hello Admin! <br> There are %d job vacancies waiting for your review, you can also review them in the <a href="%s">dashboard</a>:</p>`,
+			t.digest.Total, html.EscapeString(t.serverDomain+"/admin/approvals"))
+		for _, group := range t.digest.Groups {
+			fmt.Fprintf(&content, "\n<h3>%s, %s (%d)</h3>\n<ul>", html.EscapeString(getSourceTitle(group.Source)), html.EscapeString(group.AgeLabel), len(group.Approvals))
+			for _, rec := range group.Approvals {
+				fmt.Fprintf(&content, `
+  <li>
+    <a href="%s" target="_blank">%s</a> from %s<br>
+    <a href="%s" style="color: #4CAF50; font-weight: bold;">Approve</a> | <a href="%s" style="color: #f44336; font-weight: bold;">Reject</a>
+  </li>`,
+					html.EscapeString(rec.Request.Vacancy.ApplyURL),
+					html.EscapeString(describeVacancy(rec.Request.Vacancy)),
+					html.EscapeString(rec.Request.SubmissionEmail),
+					html.EscapeString(getApprovalLink(t.serverDomain, "approve", rec.Token, rec.MessageID)),
+					html.EscapeString(getApprovalLink(t.serverDomain, "reject", rec.Token, rec.MessageID)),
+				)
+			}
+			content.WriteString("\n</ul>")
+		}
+		content.WriteString(`
+<p>
+	Thank you and have a nice day!<br>
+	Barakallahu fiikum
+</p>`)
+		return content.String()
+	}
+
+	fmt.Fprintf(&content, `Bismillah
+Assalamu'alaikum warahmatullahi wabarakatuh
+
+Hello Admin!
+There are %d job vacancies waiting for your review, you can also review them in the dashboard:
+%s/admin/approvals
+`, t.digest.Total, t.serverDomain)
+	for _, group := range t.digest.Groups {
+		fmt.Fprintf(&content, "\n%s, %s (%d):\n\n", getSourceTitle(group.Source), group.AgeLabel, len(group.Approvals))
+		for i, rec := range group.Approvals {
+			fmt.Fprintf(&content, "%d. %s\n", i+1, describeVacancy(rec.Request.Vacancy))
+			fmt.Fprintf(&content, "   Submission From: %s\n", rec.Request.SubmissionEmail)
+			fmt.Fprintf(&content, "   URL: %s\n", rec.Request.Vacancy.ApplyURL)
+			fmt.Fprintf(&content, "   Approve: %s\n", getApprovalLink(t.serverDomain, "approve", rec.Token, rec.MessageID))
+			fmt.Fprintf(&content, "   Reject: %s\n\n", getApprovalLink(t.serverDomain, "reject", rec.Token, rec.MessageID))
+		}
+	}
+	content.WriteString("Thank you and have a nice day!\nBarakallahu fiikum")
+	return content.String()
+}
+
+func getSourceTitle(source servercore.ApprovalSource) string {
+	switch source {
+	case servercore.ApprovalSourceForm:
+		return "Submitted from Form"
+	case servercore.ApprovalSourceURL:
+		return "Submitted by URL"
+	case servercore.ApprovalSourceCrawler:
+		return "Found by Crawler"
+	default:
+		return string(source)
+	}
 }
@@ -0,0 +1,37 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateBulkEmailApproveSelected(t *testing.T) {
+	tmpl := templateBulkEmail{
+		req: core.SubmitRequest{
+			SubmissionEmail: "user@example.com",
+			SubmissionType:  core.SubmitTypeBulk,
+			BulkVacancies: []core.BulkVacancy{
+				{Vacancy: core.Vacancy{JobTitle: "Backend Engineer", ApplyURL: "http://example.com/1"}},
+				{Vacancy: core.Vacancy{JobTitle: "Frontend Engineer", ApplyURL: "http://example.com/2"}},
+			},
+		},
+		batchID:        "batch-1",
+		tokenBatch:     "batch-token",
+		tokenVacancies: []string{"token-1", "token-2"},
+		messagesIDs:    []string{"item-1@bulk", "item-2@bulk"},
+		serverDomain:   "http://example.com",
+	}
+
+	content := tmpl.getContentBodyHTML()
+	assert.Contains(t, content, `<form method="get" action="http://example.com/vacancies/batch/approve/selected"`)
+	assert.Contains(t, content, `<input type="hidden" name="data" value="batch-token">`)
+	assert.Contains(t, content, `<input type="hidden" name="batch_id" value="batch-1">`)
+	assert.Contains(t, content, `<input type="checkbox" name="message_id" value="item-1@bulk">`)
+	assert.Contains(t, content, `<input type="checkbox" name="message_id" value="item-2@bulk">`)
+
+	content = tmpl.getContentBodyPlain()
+	assert.Contains(t, content, "Approve Selected: http://example.com/vacancies/batch/approve/selected?batch_id=batch-1&data=batch-token&message_id=<message id>")
+	assert.Contains(t, content, "Message ID: item-2@bulk")
+}
@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+
+	"gopkg.in/validator.v2"
+)
+
+const defaultLockName = "idn-remote-entry.background-jobs"
+
+type MySQLLeaderConfig struct {
+	DB *sql.DB `validate:"nonnil"`
+	// LockName is the name of the MySQL named lock held by the leader,
+	// default to `idn-remote-entry.background-jobs`
+	LockName string
+}
+
+// MySQLLeader elects a single server replica for running the background
+// jobs, e.g expiry sweeper & pending digest, using MySQL named lock.
+//
+// The lock is held by a dedicated connection for as long as the replica
+// lives, so it is released by MySQL once the replica dies and the next
+// replica asking for it takes over.
+type MySQLLeader struct {
+	MySQLLeaderConfig
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewMySQLLeader(cfg MySQLLeaderConfig) (*MySQLLeader, error) {
+	err := validator.Validate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.LockName == "" {
+		cfg.LockName = defaultLockName
+	}
+	return &MySQLLeader{MySQLLeaderConfig: cfg}, nil
+}
+
+// IsLeader returns true when this replica holds the lock, the lock is
+// acquired when no other replica holds it.
+func (l *MySQLLeader) IsLeader(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		conn, err := l.DB.Conn(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get connection: %w", err)
+		}
+		l.conn = conn
+	}
+
+	// asking for the lock again in the same session nests it, so check
+	// whether it's already held by this session first
+	var held bool
+	err := l.conn.QueryRowContext(ctx, "SELECT COALESCE(IS_USED_LOCK(?) = CONNECTION_ID(), FALSE)", l.LockName).Scan(&held)
+	if err != nil {
+		l.reset()
+		return false, fmt.Errorf("failed to check lock: %w", err)
+	}
+	if held {
+		return true, nil
+	}
+
+	var acquired sql.NullInt64
+	err = l.conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", l.LockName).Scan(&acquired)
+	if err != nil {
+		l.reset()
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return acquired.Valid && acquired.Int64 == 1, nil
+}
+
+// Close releases the lock by closing its connection.
+func (l *MySQLLeader) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+	l.reset()
+	return nil
+}
+
+// reset drops the connection, the lock held by it (if any) is released by
+// MySQL when the session ends. The connection is discarded instead of put
+// back to the pool, otherwise the pooled connection keeps holding the lock.
+func (l *MySQLLeader) reset() {
+	l.conn.Raw(func(any) error { return driver.ErrBadConn })
+	l.conn.Close()
+	l.conn = nil
+}
@@ -0,0 +1,48 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func TestMySQLLeaderIsLeader(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", os.Getenv(testutil.EnvKeyMysqlDsn))
+	require.NoError(t, err)
+	defer db.Close()
+
+	leader, err := NewMySQLLeader(MySQLLeaderConfig{DB: db, LockName: "test.is-leader"})
+	require.NoError(t, err)
+	defer leader.Close()
+
+	// Test first replica becomes leader and stays leader
+	for i := 0; i < 2; i++ {
+		isLeader, err := leader.IsLeader(ctx)
+		require.NoError(t, err)
+		assert.True(t, isLeader)
+	}
+
+	// Test other replica is not leader while the lock is held
+	other, err := NewMySQLLeader(MySQLLeaderConfig{DB: db, LockName: "test.is-leader"})
+	require.NoError(t, err)
+	defer other.Close()
+
+	isLeader, err := other.IsLeader(ctx)
+	require.NoError(t, err)
+	assert.False(t, isLeader)
+
+	// Test other replica takes over once the leader is gone
+	require.NoError(t, leader.Close())
+
+	isLeader, err = other.IsLeader(ctx)
+	require.NoError(t, err)
+	assert.True(t, isLeader)
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+)
+
+// pruneInterval is how often buckets that have been refilled to full
+// are removed, such buckets behave the same as missing ones
+const pruneInterval = time.Minute
+
+type bucket struct {
+	tokens     float64
+	lastUpdate time.Time
+	limit      core.RateLimit
+}
+
+// MemoryLimiter keeps the buckets in memory, use it only when running
+// single server replica.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   map[string]*bucket{},
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Take(ctx context.Context, key string, limit core.RateLimit, n int) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Limit), lastUpdate: now}
+		l.buckets[key] = b
+	}
+	b.limit = limit
+
+	tokens, retryAfter, allowed := limit.Take(b.tokens, b.lastUpdate, now, n)
+	b.tokens = tokens
+	b.lastUpdate = now
+
+	return allowed, retryAfter, nil
+}
+
+func (l *MemoryLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastUpdate) >= b.limit.Period {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiterTake(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limit := core.RateLimit{Limit: 3, Period: time.Minute}
+
+	// Test bucket allows up to limit
+	for i := 0; i < 3; i++ {
+		allowed, _, err := limiter.Take(ctx, "ip:127.0.0.1", limit, 1)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	// Test empty bucket returns time until next token
+	allowed, retryAfter, err := limiter.Take(ctx, "ip:127.0.0.1", limit, 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 20*time.Second, retryAfter)
+
+	// Test other keys have their own bucket
+	allowed, _, err = limiter.Take(ctx, "ip:127.0.0.2", limit, 1)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Test bucket is refilled over time
+	now = now.Add(20 * time.Second)
+	allowed, _, err = limiter.Take(ctx, "ip:127.0.0.1", limit, 1)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, retryAfter, err = limiter.Take(ctx, "ip:127.0.0.1", limit, 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 20*time.Second, retryAfter)
+
+	// Test taking several tokens at once waits until all of them are available
+	now = now.Add(20 * time.Second)
+	allowed, retryAfter, err = limiter.Take(ctx, "ip:127.0.0.1", limit, 3)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 40*time.Second, retryAfter)
+
+	now = now.Add(40 * time.Second)
+	allowed, _, err = limiter.Take(ctx, "ip:127.0.0.1", limit, 3)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Test full buckets are pruned
+	now = now.Add(2 * time.Minute)
+	_, _, err = limiter.Take(ctx, "ip:127.0.0.3", limit, 1)
+	require.NoError(t, err)
+	assert.Len(t, limiter.buckets, 1)
+}
@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"gopkg.in/validator.v2"
+)
+
+const (
+	tableRateLimitBucket = "rate_limit_buckets"
+
+	// pruneInterval is how often buckets that have been refilled to full
+	// are removed, such buckets behave the same as missing ones
+	pruneInterval = time.Minute
+)
+
+type MySQLLimiterConfig struct {
+	DB *sql.DB `validate:"nonnil"`
+}
+
+// MySQLLimiter keeps the buckets in MySQL so the counters are shared
+// between server replicas.
+type MySQLLimiter struct {
+	MySQLLimiterConfig
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func NewMySQLLimiter(cfg MySQLLimiterConfig) (*MySQLLimiter, error) {
+	err := validator.Validate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &MySQLLimiter{
+		MySQLLimiterConfig: cfg,
+		lastPrune:          time.Now(),
+	}, nil
+}
+
+func (l *MySQLLimiter) Take(ctx context.Context, key string, limit core.RateLimit, n int) (bool, time.Duration, error) {
+	now := time.Now()
+	l.prune(ctx, now)
+
+	tx, err := l.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// make sure the bucket exists so it can be locked
+	query := fmt.Sprintf("INSERT IGNORE INTO %s (bucket_key, tokens, updated_at, full_at) VALUES (?, ?, ?, ?)", tableRateLimitBucket)
+	_, err = tx.ExecContext(ctx, query, key, limit.Limit, now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to init bucket: %w", err)
+	}
+
+	var (
+		tokens    float64
+		updatedAt int64
+	)
+	query = fmt.Sprintf("SELECT tokens, updated_at FROM %s WHERE bucket_key = ? FOR UPDATE", tableRateLimitBucket)
+	err = tx.QueryRowContext(ctx, query, key).Scan(&tokens, &updatedAt)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get bucket: %w", err)
+	}
+
+	tokens, retryAfter, allowed := limit.Take(tokens, time.UnixMilli(updatedAt), now, n)
+	fullAt := now.Add(time.Duration((float64(limit.Limit) - tokens) * float64(limit.Period/time.Duration(limit.Limit))))
+
+	query = fmt.Sprintf("UPDATE %s SET tokens = ?, updated_at = ?, full_at = ? WHERE bucket_key = ?", tableRateLimitBucket)
+	_, err = tx.ExecContext(ctx, query, tokens, now.UnixMilli(), fullAt.UnixMilli(), key)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to update bucket: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return allowed, retryAfter, nil
+}
+
+func (l *MySQLLimiter) prune(ctx context.Context, now time.Time) {
+	l.mu.Lock()
+	if now.Sub(l.lastPrune) < pruneInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPrune = now
+	l.mu.Unlock()
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE full_at < ?", tableRateLimitBucket)
+	_, err := l.DB.ExecContext(ctx, query, now.UnixMilli())
+	if err != nil {
+		log.Printf("failed to prune rate limit buckets: %v", err)
+	}
+}
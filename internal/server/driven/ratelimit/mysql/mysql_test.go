@@ -0,0 +1,57 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func TestMySQLLimiterTake(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("mysql", os.Getenv(testutil.EnvKeyMysqlDsn))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("TRUNCATE TABLE rate_limit_buckets")
+	require.NoError(t, err)
+
+	limiter, err := NewMySQLLimiter(MySQLLimiterConfig{DB: db})
+	require.NoError(t, err)
+
+	limit := core.RateLimit{Limit: 2, Period: time.Hour}
+
+	// Test bucket allows up to limit
+	for i := 0; i < 2; i++ {
+		allowed, _, err := limiter.Take(ctx, "email:test@example.com", limit, 1)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	// Test empty bucket returns time until next token
+	allowed, retryAfter, err := limiter.Take(ctx, "email:test@example.com", limit, 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.InDelta(t, 30*time.Minute, retryAfter, float64(time.Minute))
+
+	// Test bucket is shared with other limiter instance, e.g other replica
+	otherLimiter, err := NewMySQLLimiter(MySQLLimiterConfig{DB: db})
+	require.NoError(t, err)
+
+	allowed, _, err = otherLimiter.Take(ctx, "email:test@example.com", limit, 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Test other keys have their own bucket
+	allowed, _, err = otherLimiter.Take(ctx, "email:other@example.com", limit, 1)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
@@ -3,7 +3,10 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
 	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
@@ -11,7 +14,19 @@ import (
 )
 
 const (
-	tableApproval = "approvals"
+	tableApproval                = "approvals"
+	tableApprovalToken           = "approval_tokens"
+	tableApprovalTokenRevocation = "approval_token_revocations"
+	tableAPIKey                  = "api_keys"
+	tableIdempotencyKey          = "idempotency_keys"
+
+	tokenStateConsumed = "consumed"
+	tokenStateRevoked  = "revoked"
+
+	// idempotencyReservationTimeout is how long a reservation without result
+	// is kept, after that the request is considered abandoned (e.g server
+	// crashed) and the key can be reserved again
+	idempotencyReservationTimeout = 5 * time.Minute
 )
 
 type MySQLStorage struct {
@@ -32,24 +47,34 @@ func NewMySQLStorage(cfg MySQLStorageConfig) (*MySQLStorage, error) {
 	}, nil
 }
 
-func (s *MySQLStorage) GetApprovalState(ctx context.Context, messageID string) (core.ApprovalState, error) {
-	var state string
-	query := fmt.Sprintf("SELECT state FROM %s WHERE message_id = ?", tableApproval)
-	err := s.DB.QueryRowContext(ctx, query, messageID).Scan(&state)
+// UpdateApprovalState moves the pending approval to the given state, it
+// returns conflict error when the approval has already left pending state
+// (e.g expired by the sweeper) since it was read.
+func (s *MySQLStorage) UpdateApprovalState(ctx context.Context, messageID string, state core.ApprovalState) error {
+	query := fmt.Sprintf("UPDATE %s SET state = ? WHERE message_id = ? AND state = ?", tableApproval)
+	result, err := s.DB.ExecContext(ctx, query, state, messageID, core.ApprovalStatePending)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", shcore.NewBadRequestError("approval not found")
-		}
-		return "", fmt.Errorf("failed to get approval state: %w", err)
+		return fmt.Errorf("failed to update approval state: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return s.notPendingError(ctx, messageID)
 	}
-	return core.ApprovalState(state), nil
+
+	return nil
 }
 
-func (s *MySQLStorage) UpdateApprovalState(ctx context.Context, messageID string, state core.ApprovalState) error {
-	query := fmt.Sprintf("UPDATE %s SET state = ? WHERE message_id = ?", tableApproval)
-	result, err := s.DB.ExecContext(ctx, query, state, messageID)
+// RejectApproval rejects the pending approval, it returns conflict error
+// when the approval has already left pending state since it was read.
+func (s *MySQLStorage) RejectApproval(ctx context.Context, messageID string, reason core.RejectReason) error {
+	query := fmt.Sprintf("UPDATE %s SET state = ?, reject_reason = NULLIF(?, '') WHERE message_id = ? AND state = ?", tableApproval)
+	result, err := s.DB.ExecContext(ctx, query, core.ApprovalStateRejected, reason, messageID, core.ApprovalStatePending)
 	if err != nil {
-		return fmt.Errorf("failed to update approval state: %w", err)
+		return fmt.Errorf("failed to reject approval: %w", err)
 	}
 
 	affected, err := result.RowsAffected()
@@ -57,25 +82,41 @@ func (s *MySQLStorage) UpdateApprovalState(ctx context.Context, messageID string
 		return fmt.Errorf("failed to get affected rows: %w", err)
 	}
 	if affected == 0 {
-		return shcore.NewBadRequestError(fmt.Sprintf("no approval request found with ID: %s", messageID))
+		return s.notPendingError(ctx, messageID)
 	}
 
 	return nil
 }
 
+// notPendingError returns the error of updating the approval which is not
+// pending, either because it doesn't exist or it has been decided.
+func (s *MySQLStorage) notPendingError(ctx context.Context, messageID string) error {
+	var state string
+	query := fmt.Sprintf("SELECT state FROM %s WHERE message_id = ?", tableApproval)
+	err := s.DB.QueryRowContext(ctx, query, messageID).Scan(&state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return shcore.NewBadRequestError(fmt.Sprintf("no approval request found with ID: %s", messageID))
+		}
+		return fmt.Errorf("failed to get approval state: %w", err)
+	}
+
+	return shcore.NewConflictError(fmt.Sprintf("approval is no longer pending, it has been %s", state))
+}
+
 func (s *MySQLStorage) SaveApprovalRequest(ctx context.Context, messageID string, req shcore.SubmitRequest) error {
 	data := req.ToJSON()
-	query := fmt.Sprintf("INSERT INTO %s (message_id, state, request_data) VALUES (?, ?, ?)", tableApproval)
-	_, err := s.DB.ExecContext(ctx, query, messageID, core.ApprovalStatePending, data)
+	query := fmt.Sprintf("INSERT INTO %s (message_id, state, kind, request_data, normalized_apply_url) VALUES (?, ?, ?, ?, ?)", tableApproval)
+	_, err := s.DB.ExecContext(ctx, query, messageID, core.ApprovalStatePending, core.ApprovalKindSingle, data, shcore.NormalizeApplyURL(req.ApplyURL))
 	if err != nil {
 		return fmt.Errorf("failed to save approval request: %w", err)
 	}
 	return nil
 }
 
-func (s *MySQLStorage) SaveBulkApprovalRequest(ctx context.Context, req shcore.SubmitRequest, messageIDs []string) error {
+func (s *MySQLStorage) SaveBulkApprovalRequest(ctx context.Context, batchID string, reqs []shcore.SubmitRequest, messageIDs []string) error {
 	// Validate the request
-	if len(req.BulkVacancies) != len(messageIDs) {
+	if len(reqs) != len(messageIDs) {
 		return fmt.Errorf("number of vacancies must match number of message IDs")
 	}
 
@@ -85,7 +126,7 @@ func (s *MySQLStorage) SaveBulkApprovalRequest(ctx context.Context, req shcore.S
 	}
 
 	// Prepare the query for bulk insertion
-	query := fmt.Sprintf("INSERT INTO %s (message_id, state, request_data) VALUES (?, ?, ?)", tableApproval)
+	query := fmt.Sprintf("INSERT INTO %s (message_id, state, kind, batch_id, request_data, normalized_apply_url) VALUES (?, ?, ?, ?, ?, ?)", tableApproval)
 	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		tx.Rollback()
@@ -95,12 +136,7 @@ func (s *MySQLStorage) SaveBulkApprovalRequest(ctx context.Context, req shcore.S
 
 	// Save each message ID with the corresponding request data
 	for idx, messageID := range messageIDs {
-		data := shcore.SubmitRequest{
-			SubmissionType:  req.SubmissionType,
-			SubmissionEmail: req.SubmissionEmail,
-			Vacancy:         req.BulkVacancies[idx],
-		}
-		_, err := stmt.ExecContext(ctx, messageID, core.ApprovalStatePending, data.ToJSON())
+		_, err := stmt.ExecContext(ctx, messageID, core.ApprovalStatePending, core.ApprovalKindBulk, batchID, reqs[idx].ToJSON(), shcore.NormalizeApplyURL(reqs[idx].ApplyURL))
 		if err != nil {
 			tx.Rollback()
 			return fmt.Errorf("failed to save bulk approval request: %w", err)
@@ -115,3 +151,532 @@ func (s *MySQLStorage) SaveBulkApprovalRequest(ctx context.Context, req shcore.S
 
 	return nil
 }
+
+func (s *MySQLStorage) UpdateApprovalRequest(ctx context.Context, messageID string, req shcore.SubmitRequest) error {
+	// the assignments are evaluated from left to right, so the request data
+	// is copied to original_request_data before being overwritten, the copy
+	// only happens on the first edit to keep the submitted version intact
+	query := fmt.Sprintf(
+		"UPDATE %s SET original_request_data = COALESCE(original_request_data, request_data), request_data = ?, normalized_apply_url = ? WHERE message_id = ? AND state = ?",
+		tableApproval,
+	)
+	_, err := s.DB.ExecContext(ctx, query, req.ToJSON(), shcore.NormalizeApplyURL(req.ApplyURL), messageID, core.ApprovalStatePending)
+	if err != nil {
+		return fmt.Errorf("failed to update approval request: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStorage) SaveApprovalToken(ctx context.Context, messageID string, token string) error {
+	query := fmt.Sprintf("UPDATE %s SET token = ? WHERE message_id = ?", tableApproval)
+	_, err := s.DB.ExecContext(ctx, query, token, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to save approval token: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStorage) GetApproval(ctx context.Context, messageID string) (*core.ApprovalRecord, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE message_id = ?", approvalColumns, tableApproval)
+	rec, err := scanApprovalRecord(s.DB.QueryRowContext(ctx, query, messageID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, shcore.NewNotFoundError("approval not found")
+		}
+		return nil, fmt.Errorf("failed to get approval: %w", err)
+	}
+	return rec, nil
+}
+
+func (s *MySQLStorage) DecideBatchApprovals(ctx context.Context, batchID string, messageIDs []string, state core.ApprovalState, reason core.RejectReason) ([]core.ApprovalRecord, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	conds := []string{"batch_id = ?"}
+	args := []interface{}{batchID}
+	if len(messageIDs) > 0 {
+		conds = append(conds, fmt.Sprintf("message_id IN (%s)", placeholders(len(messageIDs))))
+		for _, messageID := range messageIDs {
+			args = append(args, messageID)
+		}
+	}
+	recs, err := lockPendingApprovals(ctx, tx, conds, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(recs) == 0 {
+		return nil, shcore.NewBadRequestError("no pending approval in the batch")
+	}
+	if len(messageIDs) > 0 && len(recs) != countUnique(messageIDs) {
+		return nil, shcore.NewBadRequestError("some of the selected approvals are no longer pending in the batch")
+	}
+
+	err = updateApprovalsState(ctx, tx, recs, state, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return recs, nil
+}
+
+func (s *MySQLStorage) ExpireApprovals(ctx context.Context, minAge time.Duration) ([]core.ApprovalRecord, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	recs, err := lockPendingApprovals(ctx, tx,
+		[]string{"created_at <= NOW() - INTERVAL ? SECOND"},
+		[]interface{}{int64(minAge / time.Second)},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return recs, nil
+	}
+
+	err = updateApprovalsState(ctx, tx, recs, core.ApprovalStateExpired, "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return recs, nil
+}
+
+// lockPendingApprovals returns the pending approvals matching the conditions,
+// the rows are locked so concurrent decision on the same approval waits
+// until the transaction is done.
+func lockPendingApprovals(ctx context.Context, tx *sql.Tx, conds []string, args []interface{}) ([]core.ApprovalRecord, error) {
+	conds = append([]string{"state = ?"}, conds...)
+	args = append([]interface{}{core.ApprovalStatePending}, args...)
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s ORDER BY created_at ASC FOR UPDATE",
+		approvalColumns,
+		tableApproval,
+		strings.Join(conds, " AND "),
+	)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending approvals: %w", err)
+	}
+	defer rows.Close()
+
+	recs := make([]core.ApprovalRecord, 0)
+	for rows.Next() {
+		rec, err := scanApprovalRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		recs = append(recs, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate approvals: %w", err)
+	}
+
+	return recs, nil
+}
+
+// updateApprovalsState moves the approvals locked by lockPendingApprovals to
+// the given state, the records are updated accordingly.
+func updateApprovalsState(ctx context.Context, tx *sql.Tx, recs []core.ApprovalRecord, state core.ApprovalState, reason core.RejectReason) error {
+	args := make([]interface{}, 0, len(recs)+2)
+	args = append(args, state, reason)
+	for _, rec := range recs {
+		args = append(args, rec.MessageID)
+	}
+	query := fmt.Sprintf(
+		"UPDATE %s SET state = ?, reject_reason = NULLIF(?, '') WHERE message_id IN (%s)",
+		tableApproval,
+		placeholders(len(recs)),
+	)
+	_, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update approvals state: %w", err)
+	}
+
+	for i := range recs {
+		recs[i].State = state
+		recs[i].RejectReason = reason
+	}
+
+	return nil
+}
+
+func (s *MySQLStorage) FindApprovalByApplyURL(ctx context.Context, applyURL string) (*core.ApprovalRecord, error) {
+	// rejected approvals are skipped since the vacancy may be resubmitted
+	// after the rejection reason has been fixed
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE normalized_apply_url = ? AND state IN (?, ?) ORDER BY created_at DESC LIMIT 1",
+		approvalColumns,
+		tableApproval,
+	)
+	rec, err := scanApprovalRecord(s.DB.QueryRowContext(ctx, query, shcore.NormalizeApplyURL(applyURL), core.ApprovalStatePending, core.ApprovalStateApproved))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find approval by apply url: %w", err)
+	}
+	return rec, nil
+}
+
+func (s *MySQLStorage) ListPendingApprovals(ctx context.Context, filter core.ApprovalFilter) ([]core.ApprovalRecord, error) {
+	conds := []string{"state = ?"}
+	args := []interface{}{core.ApprovalStatePending}
+	if filter.SubmissionEmail != "" {
+		conds = append(conds, "JSON_UNQUOTE(JSON_EXTRACT(request_data, '$.submission_email')) LIKE ? ESCAPE '\\\\'")
+		args = append(args, "%"+escapeLike(filter.SubmissionEmail)+"%")
+	}
+	if filter.SubmissionType != "" {
+		conds = append(conds, "JSON_UNQUOTE(JSON_EXTRACT(request_data, '$.submission_type')) = ?")
+		args = append(args, filter.SubmissionType)
+	}
+	if filter.BatchID != "" {
+		conds = append(conds, "batch_id = ?")
+		args = append(args, filter.BatchID)
+	}
+	if filter.MinAge > 0 {
+		conds = append(conds, "created_at <= NOW() - INTERVAL ? SECOND")
+		args = append(args, int64(filter.MinAge/time.Second))
+	}
+	if filter.MaxAge > 0 {
+		conds = append(conds, "created_at >= NOW() - INTERVAL ? SECOND")
+		args = append(args, int64(filter.MaxAge/time.Second))
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s ORDER BY created_at ASC",
+		approvalColumns,
+		tableApproval,
+		strings.Join(conds, " AND "),
+	)
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending approvals: %w", err)
+	}
+	defer rows.Close()
+
+	recs := make([]core.ApprovalRecord, 0)
+	for rows.Next() {
+		rec, err := scanApprovalRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		recs = append(recs, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate approvals: %w", err)
+	}
+
+	return recs, nil
+}
+
+func (s *MySQLStorage) ConsumeToken(ctx context.Context, tokenID string) (bool, error) {
+	// the primary key on token_id makes sure only the first consumer wins,
+	// revoked tokens already have a row so they cannot be consumed either
+	query := fmt.Sprintf("INSERT IGNORE INTO %s (token_id, state) VALUES (?, ?)", tableApprovalToken)
+	result, err := s.DB.ExecContext(ctx, query, tokenID, tokenStateConsumed)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+func (s *MySQLStorage) RevokeToken(ctx context.Context, tokenID string) error {
+	query := fmt.Sprintf("INSERT IGNORE INTO %s (token_id, state) VALUES (?, ?)", tableApprovalToken)
+	_, err := s.DB.ExecContext(ctx, query, tokenID, tokenStateRevoked)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStorage) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE token_id = ? AND state = ?", tableApprovalToken)
+	err := s.DB.QueryRowContext(ctx, query, tokenID, tokenStateRevoked).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *MySQLStorage) RevokeTokensIssuedBefore(ctx context.Context, t time.Time) error {
+	// millisecond precision to match the issued at of approval token
+	query := fmt.Sprintf("INSERT INTO %s (revoked_before) VALUES (FROM_UNIXTIME(? / 1000))", tableApprovalTokenRevocation)
+	_, err := s.DB.ExecContext(ctx, query, t.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStorage) GetTokensRevokedBefore(ctx context.Context) (time.Time, error) {
+	var revokedBefore sql.NullInt64
+	query := fmt.Sprintf("SELECT ROUND(UNIX_TIMESTAMP(MAX(revoked_before)) * 1000) FROM %s", tableApprovalTokenRevocation)
+	err := s.DB.QueryRowContext(ctx, query).Scan(&revokedBefore)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get token revocation: %w", err)
+	}
+	if !revokedBefore.Valid {
+		return time.Time{}, nil
+	}
+	return time.UnixMilli(revokedBefore.Int64).UTC(), nil
+}
+
+func (s *MySQLStorage) SaveAPIKey(ctx context.Context, key core.APIKey) error {
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO %s (id, name, secret_hash, scopes, default_submission_email, created_at) VALUES (?, ?, ?, ?, ?, FROM_UNIXTIME(?))",
+		tableAPIKey,
+	)
+	_, err := s.DB.ExecContext(ctx, query, key.ID, key.Name, key.SecretHash, strings.Join(scopes, ","), key.DefaultSubmissionEmail, key.CreatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save api key: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStorage) GetAPIKey(ctx context.Context, id string) (*core.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", apiKeyColumns, tableAPIKey)
+	key, err := scanAPIKey(s.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, shcore.NewNotFoundError("api key not found")
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *MySQLStorage) ListAPIKeys(ctx context.Context) ([]core.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY created_at ASC", apiKeyColumns, tableAPIKey)
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]core.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (s *MySQLStorage) RevokeAPIKey(ctx context.Context, id string) error {
+	query := fmt.Sprintf("UPDATE %s SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = ?", tableAPIKey)
+	result, err := s.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		// revoking revoked key leaves the row untouched, so make sure
+		// the key doesn't exist before returning not found
+		_, err = s.GetAPIKey(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MySQLStorage) TouchAPIKey(ctx context.Context, id string) error {
+	query := fmt.Sprintf("UPDATE %s SET usage_count = usage_count + 1, last_used_at = NOW() WHERE id = ?", tableAPIKey)
+	_, err := s.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to touch api key: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStorage) ReserveIdempotencyKey(ctx context.Context, key string, requestHash string, ttl time.Duration) (*core.IdempotencyRecord, error) {
+	// remove expired & abandoned keys so they can be reserved again
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE created_at < NOW() - INTERVAL ? SECOND OR (result IS NULL AND created_at < NOW() - INTERVAL ? SECOND)",
+		tableIdempotencyKey,
+	)
+	_, err := s.DB.ExecContext(ctx, query, int64(ttl/time.Second), int64(idempotencyReservationTimeout/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	// the primary key on idempotency_key makes sure only one request wins
+	query = fmt.Sprintf("INSERT IGNORE INTO %s (idempotency_key, request_hash) VALUES (?, ?)", tableIdempotencyKey)
+	result, err := s.DB.ExecContext(ctx, query, key, requestHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 1 {
+		return nil, nil
+	}
+
+	var (
+		rec       core.IdempotencyRecord
+		createdAt int64
+	)
+	query = fmt.Sprintf("SELECT idempotency_key, request_hash, result, UNIX_TIMESTAMP(created_at) FROM %s WHERE idempotency_key = ?", tableIdempotencyKey)
+	err = s.DB.QueryRowContext(ctx, query, key).Scan(&rec.Key, &rec.RequestHash, &rec.Result, &createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	rec.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+	return &rec, nil
+}
+
+func (s *MySQLStorage) CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error {
+	query := fmt.Sprintf("UPDATE %s SET result = ? WHERE idempotency_key = ?", tableIdempotencyKey)
+	_, err := s.DB.ExecContext(ctx, query, result, key)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE idempotency_key = ? AND result IS NULL", tableIdempotencyKey)
+	_, err := s.DB.ExecContext(ctx, query, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+const apiKeyColumns = "id, name, secret_hash, scopes, default_submission_email, usage_count, UNIX_TIMESTAMP(created_at), UNIX_TIMESTAMP(last_used_at), UNIX_TIMESTAMP(revoked_at)"
+
+func scanAPIKey(row rowScanner) (*core.APIKey, error) {
+	var (
+		key        core.APIKey
+		scopes     string
+		createdAt  int64
+		lastUsedAt sql.NullInt64
+		revokedAt  sql.NullInt64
+	)
+	err := row.Scan(&key.ID, &key.Name, &key.SecretHash, &scopes, &key.DefaultSubmissionEmail, &key.UsageCount, &createdAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope != "" {
+			key.Scopes = append(key.Scopes, core.APIKeyScope(scope))
+		}
+	}
+	key.CreatedAt = time.Unix(createdAt, 0).UTC()
+	if lastUsedAt.Valid {
+		t := time.Unix(lastUsedAt.Int64, 0).UTC()
+		key.LastUsedAt = &t
+	}
+	if revokedAt.Valid {
+		t := time.Unix(revokedAt.Int64, 0).UTC()
+		key.RevokedAt = &t
+	}
+
+	return &key, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// likeEscaper escapes the wildcards of LIKE pattern using backslash.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike returns s to be matched literally in LIKE pattern, the query
+// must use backslash as the escape character.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// placeholders returns n comma separated `?`
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func countUnique(vals []string) int {
+	m := make(map[string]bool, len(vals))
+	for _, v := range vals {
+		m[v] = true
+	}
+	return len(m)
+}
+
+const approvalColumns = "message_id, state, kind, batch_id, request_data, original_request_data, reject_reason, token, UNIX_TIMESTAMP(created_at)"
+
+func scanApprovalRecord(row rowScanner) (*core.ApprovalRecord, error) {
+	var (
+		rec          core.ApprovalRecord
+		batchID      sql.NullString
+		requestData  []byte
+		originalData []byte
+		rejectReason sql.NullString
+		token        sql.NullString
+		createdAt    int64
+	)
+	err := row.Scan(&rec.MessageID, &rec.State, &rec.Kind, &batchID, &requestData, &originalData, &rejectReason, &token, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(requestData, &rec.Request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode request data of approval %s: %w", rec.MessageID, err)
+	}
+	if originalData != nil {
+		rec.OriginalRequest = &shcore.SubmitRequest{}
+		err = json.Unmarshal(originalData, rec.OriginalRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode original request data of approval %s: %w", rec.MessageID, err)
+		}
+	}
+	rec.BatchID = batchID.String
+	rec.RejectReason = core.RejectReason(rejectReason.String)
+	rec.Token = token.String
+	rec.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+	return &rec, nil
+}
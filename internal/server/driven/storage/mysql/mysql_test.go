@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
 	sharedcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
@@ -21,37 +22,14 @@ func setupTestDB(t *testing.T) *sql.DB {
 	require.NoError(t, err)
 
 	// Clean up test data
-	_, err = db.Exec("TRUNCATE TABLE approvals")
-	require.NoError(t, err)
+	for _, table := range []string{"approvals", "approval_tokens", "approval_token_revocations", "api_keys", "idempotency_keys"} {
+		_, err = db.Exec("TRUNCATE TABLE " + table)
+		require.NoError(t, err)
+	}
 
 	return db
 }
 
-func TestGetApprovalState(t *testing.T) {
-	ctx := context.Background()
-	db := setupTestDB(t)
-	defer db.Close()
-
-	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
-	require.NoError(t, err)
-
-	// Test getting non-existent approval
-	state, err := storage.GetApprovalState(ctx, "non-existent")
-	assert.Error(t, err)
-	assert.Empty(t, state)
-
-	// Insert test data
-	messageID := "test-message"
-	_, err = db.Exec("INSERT INTO approvals (message_id, state, request_data) VALUES (?, ?, '{}')",
-		messageID, core.ApprovalStateApproved)
-	require.NoError(t, err)
-
-	// Test getting existing approval
-	state, err = storage.GetApprovalState(ctx, messageID)
-	assert.NoError(t, err)
-	assert.Equal(t, core.ApprovalStateApproved, state)
-}
-
 func TestUpdateApprovalState(t *testing.T) {
 	ctx := context.Background()
 	db := setupTestDB(t)
@@ -79,6 +57,12 @@ func TestUpdateApprovalState(t *testing.T) {
 	err = db.QueryRow("SELECT state FROM approvals WHERE message_id = ?", messageID).Scan(&state)
 	require.NoError(t, err)
 	assert.Equal(t, string(core.ApprovalStateApproved), state)
+
+	// Test updating approval which is no longer pending
+	var shErr *sharedcore.Error
+	err = storage.UpdateApprovalState(ctx, messageID, core.ApprovalStateExpired)
+	require.ErrorAs(t, err, &shErr)
+	assert.Equal(t, sharedcore.ErrCodeConflict, shErr.ErrCode)
 }
 
 func TestSaveApprovalRequest(t *testing.T) {
@@ -118,6 +102,18 @@ func TestSaveApprovalRequest(t *testing.T) {
 	// Test saving duplicate request (should fail due to primary key)
 	err = storage.SaveApprovalRequest(ctx, messageID, req)
 	assert.Error(t, err)
+
+	// Test the token issued for the approval is returned along with it
+	rec, err := storage.GetApproval(ctx, messageID)
+	require.NoError(t, err)
+	assert.Empty(t, rec.Token)
+
+	err = storage.SaveApprovalToken(ctx, messageID, "dashboard-token")
+	require.NoError(t, err)
+
+	rec, err = storage.GetApproval(ctx, messageID)
+	require.NoError(t, err)
+	assert.Equal(t, "dashboard-token", rec.Token)
 }
 
 func TestSaveBulkApprovalRequest(t *testing.T) {
@@ -130,61 +126,562 @@ func TestSaveBulkApprovalRequest(t *testing.T) {
 
 	// Create test data with multiple vacancies
 	messageIDs := []string{"bulk-msg-1", "bulk-msg-2", "bulk-msg-3"}
-	req := sharedcore.SubmitRequest{
-		SubmissionType:  sharedcore.SubmitTypeManual,
-		SubmissionEmail: "crawler",
-		BulkVacancies: []sharedcore.Vacancy{
-			{
-				JobTitle:    "Frontend Developer",
-				CompanyName: "Company A",
-				ApplyURL:    "https://example.com/apply/a",
+	reqs := []sharedcore.SubmitRequest{
+		{
+			SubmissionID:    "submission-a",
+			SubmissionType:  sharedcore.SubmitTypeURL,
+			SubmissionEmail: "crawler",
+			Vacancy: sharedcore.Vacancy{
+				ApplyURL: "https://example.com/apply/a",
 			},
-			{
-				JobTitle:    "Backend Developer",
-				CompanyName: "Company B",
-				ApplyURL:    "https://example.com/apply/b",
+		},
+		{
+			SubmissionID:    "submission-b",
+			SubmissionType:  sharedcore.SubmitTypeURL,
+			SubmissionEmail: "crawler",
+			Vacancy: sharedcore.Vacancy{
+				ApplyURL: "https://example.com/apply/b",
 			},
-			{
-				JobTitle:    "DevOps Engineer",
-				CompanyName: "Company C",
-				ApplyURL:    "https://example.com/apply/c",
+		},
+		{
+			SubmissionID:    "submission-c",
+			SubmissionType:  sharedcore.SubmitTypeURL,
+			SubmissionEmail: "crawler",
+			Vacancy: sharedcore.Vacancy{
+				ApplyURL: "https://example.com/apply/c",
 			},
 		},
 	}
 
 	// Test case: Validation error - mismatched array lengths
-	invalidReq := req
-	invalidReq.BulkVacancies = invalidReq.BulkVacancies[:2] // Only 2 vacancies but 3 message IDs
-	err = storage.SaveBulkApprovalRequest(ctx, invalidReq, messageIDs)
+	err = storage.SaveBulkApprovalRequest(ctx, "bulk-batch", reqs[:2], messageIDs) // Only 2 requests but 3 message IDs
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "number of vacancies must match")
 
 	// Test case: Successful bulk insertion
-	err = storage.SaveBulkApprovalRequest(ctx, req, messageIDs)
+	err = storage.SaveBulkApprovalRequest(ctx, "bulk-batch", reqs, messageIDs)
 	assert.NoError(t, err)
 
 	// Verify all requests were saved correctly
 	for i, messageID := range messageIDs {
 		var (
 			state       string
+			kind        string
+			batchID     string
 			requestData []byte
 		)
-		err = db.QueryRow("SELECT state, request_data FROM approvals WHERE message_id = ?", messageID).
-			Scan(&state, &requestData)
+		err = db.QueryRow("SELECT state, kind, batch_id, request_data FROM approvals WHERE message_id = ?", messageID).
+			Scan(&state, &kind, &batchID, &requestData)
 		require.NoError(t, err)
 
-		// Create expected individual request for comparison
-		expectedReq := sharedcore.SubmitRequest{
-			SubmissionType:  req.SubmissionType,
-			SubmissionEmail: req.SubmissionEmail,
-			Vacancy:         req.BulkVacancies[i],
-		}
-
 		assert.Equal(t, string(core.ApprovalStatePending), state)
-		assert.JSONEq(t, string(expectedReq.ToJSON()), string(requestData))
+		assert.Equal(t, string(core.ApprovalKindBulk), kind)
+		assert.Equal(t, "bulk-batch", batchID)
+		assert.JSONEq(t, string(reqs[i].ToJSON()), string(requestData))
+	}
+
+	// Test case: List the items of the batch
+	recs, err := storage.ListPendingApprovals(ctx, core.ApprovalFilter{BatchID: "bulk-batch"})
+	require.NoError(t, err)
+	require.Len(t, recs, len(messageIDs))
+	for _, rec := range recs {
+		assert.Equal(t, core.ApprovalKindBulk, rec.Kind)
+		assert.Equal(t, "bulk-batch", rec.BatchID)
 	}
 
 	// Test case: Duplicate insertion (should fail due to primary key constraint)
-	err = storage.SaveBulkApprovalRequest(ctx, req, messageIDs)
+	err = storage.SaveBulkApprovalRequest(ctx, "bulk-batch", reqs, messageIDs)
+	assert.Error(t, err)
+}
+
+func TestDecideBatchApprovals(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	messageIDs := []string{"batch-msg-1", "batch-msg-2", "batch-msg-3"}
+	reqs := make([]sharedcore.SubmitRequest, 0, len(messageIDs))
+	for _, messageID := range messageIDs {
+		reqs = append(reqs, sharedcore.SubmitRequest{
+			SubmissionID:    "submission-" + messageID,
+			SubmissionType:  sharedcore.SubmitTypeURL,
+			SubmissionEmail: "crawler",
+			Vacancy:         sharedcore.Vacancy{ApplyURL: "https://example.com/apply/" + messageID},
+		})
+	}
+	require.NoError(t, storage.SaveBulkApprovalRequest(ctx, "batch-1", reqs, messageIDs))
+
+	// Test case: Selected item which is not in the batch cancels the decision
+	_, err = storage.DecideBatchApprovals(ctx, "batch-1", []string{"batch-msg-1", "other-msg"}, core.ApprovalStateApproved, "")
+	assert.Error(t, err)
+	rec, err := storage.GetApproval(ctx, "batch-msg-1")
+	require.NoError(t, err)
+	assert.Equal(t, core.ApprovalStatePending, rec.State)
+
+	// Test case: Approve selected
+	recs, err := storage.DecideBatchApprovals(ctx, "batch-1", []string{"batch-msg-2"}, core.ApprovalStateApproved, "")
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "batch-msg-2", recs[0].MessageID)
+	assert.Equal(t, reqs[1], recs[0].Request)
+
+	// Test case: Reject the rest
+	recs, err = storage.DecideBatchApprovals(ctx, "batch-1", nil, core.ApprovalStateRejected, core.RejectReasonNotRemote)
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	for _, messageID := range []string{"batch-msg-1", "batch-msg-3"} {
+		rec, err := storage.GetApproval(ctx, messageID)
+		require.NoError(t, err)
+		assert.Equal(t, core.ApprovalStateRejected, rec.State)
+		assert.Equal(t, core.RejectReasonNotRemote, rec.RejectReason)
+	}
+	rec, err = storage.GetApproval(ctx, "batch-msg-2")
+	require.NoError(t, err)
+	assert.Equal(t, core.ApprovalStateApproved, rec.State)
+
+	// Test case: Nothing left to decide
+	_, err = storage.DecideBatchApprovals(ctx, "batch-1", nil, core.ApprovalStateApproved, "")
 	assert.Error(t, err)
 }
+
+func TestExpireApprovals(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	req := sharedcore.SubmitRequest{
+		SubmissionID:    "submission-old",
+		SubmissionType:  sharedcore.SubmitTypeURL,
+		SubmissionEmail: "john@example.com",
+		Vacancy:         sharedcore.Vacancy{ApplyURL: "https://example.com/apply"},
+	}
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-old", req))
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-new", req))
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-old-approved", req))
+	require.NoError(t, storage.UpdateApprovalState(ctx, "msg-old-approved", core.ApprovalStateApproved))
+
+	_, err = db.Exec("UPDATE approvals SET created_at = NOW() - INTERVAL 8 DAY WHERE message_id IN (?, ?)", "msg-old", "msg-old-approved")
+	require.NoError(t, err)
+
+	// Test only old pending approval is expired
+	recs, err := storage.ExpireApprovals(ctx, 7*24*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "msg-old", recs[0].MessageID)
+	assert.Equal(t, core.ApprovalStateExpired, recs[0].State)
+	assert.Equal(t, req, recs[0].Request)
+
+	for messageID, state := range map[string]core.ApprovalState{
+		"msg-old":          core.ApprovalStateExpired,
+		"msg-new":          core.ApprovalStatePending,
+		"msg-old-approved": core.ApprovalStateApproved,
+	} {
+		rec, err := storage.GetApproval(ctx, messageID)
+		require.NoError(t, err)
+		assert.Equal(t, state, rec.State, messageID)
+	}
+
+	// Test expired approval doesn't block resubmission
+	rec, err := storage.FindApprovalByApplyURL(ctx, req.ApplyURL)
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.NotEqual(t, "msg-old", rec.MessageID)
+
+	// Test nothing left to expire
+	recs, err = storage.ExpireApprovals(ctx, 7*24*time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+}
+
+func TestListPendingApprovals(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	manualReq := sharedcore.SubmitRequest{
+		SubmissionType:  sharedcore.SubmitTypeManual,
+		SubmissionEmail: "john@example.com",
+		Vacancy: sharedcore.Vacancy{
+			JobTitle:    "Software Engineer",
+			CompanyName: "Test Company",
+			ApplyURL:    "https://example.com/apply",
+		},
+	}
+	urlReq := sharedcore.SubmitRequest{
+		SubmissionType:  sharedcore.SubmitTypeURL,
+		SubmissionEmail: "jane@example.com",
+		Vacancy: sharedcore.Vacancy{
+			ApplyURL: "https://example.com/apply/url",
+		},
+	}
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-manual", manualReq))
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-url", urlReq))
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-approved", manualReq))
+	require.NoError(t, storage.UpdateApprovalState(ctx, "msg-approved", core.ApprovalStateApproved))
+
+	// make the manual request older than a day
+	_, err = db.Exec("UPDATE approvals SET created_at = NOW() - INTERVAL 2 DAY WHERE message_id = ?", "msg-manual")
+	require.NoError(t, err)
+
+	// Test listing without filter only returns pending approvals
+	recs, err := storage.ListPendingApprovals(ctx, core.ApprovalFilter{})
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	assert.Equal(t, "msg-manual", recs[0].MessageID)
+	assert.Equal(t, manualReq, recs[0].Request)
+
+	// Test filtering by submitter & type
+	recs, err = storage.ListPendingApprovals(ctx, core.ApprovalFilter{SubmissionEmail: "jane"})
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "msg-url", recs[0].MessageID)
+
+	// the wildcards in the filter are matched literally
+	recs, err = storage.ListPendingApprovals(ctx, core.ApprovalFilter{SubmissionEmail: "%"})
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+
+	recs, err = storage.ListPendingApprovals(ctx, core.ApprovalFilter{SubmissionEmail: "j_ne"})
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+
+	recs, err = storage.ListPendingApprovals(ctx, core.ApprovalFilter{SubmissionType: sharedcore.SubmitTypeManual})
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "msg-manual", recs[0].MessageID)
+
+	// Test filtering by age
+	recs, err = storage.ListPendingApprovals(ctx, core.ApprovalFilter{MinAge: 24 * time.Hour})
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "msg-manual", recs[0].MessageID)
+
+	recs, err = storage.ListPendingApprovals(ctx, core.ApprovalFilter{MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, "msg-url", recs[0].MessageID)
+
+	// Test getting single approval
+	rec, err := storage.GetApproval(ctx, "msg-approved")
+	require.NoError(t, err)
+	assert.Equal(t, core.ApprovalStateApproved, rec.State)
+	assert.Equal(t, core.ApprovalKindSingle, rec.Kind)
+	assert.Empty(t, rec.BatchID)
+
+	_, err = storage.GetApproval(ctx, "non-existent")
+	assert.Error(t, err)
+}
+
+func TestUpdateApprovalRequest(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	messageID := "test-message"
+	original := sharedcore.SubmitRequest{
+		SubmissionType:  sharedcore.SubmitTypeManual,
+		SubmissionEmail: "test@example.com",
+		Vacancy: sharedcore.Vacancy{
+			JobTitle:    "Sofware Enginer",
+			CompanyName: "Test Company",
+			ApplyURL:    "https://example.com/apply",
+		},
+	}
+	require.NoError(t, storage.SaveApprovalRequest(ctx, messageID, original))
+
+	// Test first edit keeps the original version
+	edited := original
+	edited.Vacancy.JobTitle = "Software Engineer"
+	err = storage.UpdateApprovalRequest(ctx, messageID, edited)
+	require.NoError(t, err)
+
+	rec, err := storage.GetApproval(ctx, messageID)
+	require.NoError(t, err)
+	assert.Equal(t, edited, rec.Request)
+	require.NotNil(t, rec.OriginalRequest)
+	assert.Equal(t, original, *rec.OriginalRequest)
+
+	// Test subsequent edit doesn't overwrite the original version
+	edited.Vacancy.CompanyLocation = "Jakarta, Indonesia"
+	err = storage.UpdateApprovalRequest(ctx, messageID, edited)
+	require.NoError(t, err)
+
+	rec, err = storage.GetApproval(ctx, messageID)
+	require.NoError(t, err)
+	assert.Equal(t, edited, rec.Request)
+	assert.Equal(t, original, *rec.OriginalRequest)
+
+	// Test processed approval is not editable
+	require.NoError(t, storage.UpdateApprovalState(ctx, messageID, core.ApprovalStateApproved))
+	err = storage.UpdateApprovalRequest(ctx, messageID, original)
+	require.NoError(t, err)
+
+	rec, err = storage.GetApproval(ctx, messageID)
+	require.NoError(t, err)
+	assert.Equal(t, edited, rec.Request)
+}
+
+func TestRejectApproval(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	req := sharedcore.SubmitRequest{
+		SubmissionType:  sharedcore.SubmitTypeManual,
+		SubmissionEmail: "test@example.com",
+		Vacancy: sharedcore.Vacancy{
+			ApplyURL: "https://example.com/apply",
+		},
+	}
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-reason", req))
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-no-reason", req))
+
+	// Test rejecting with reason
+	err = storage.RejectApproval(ctx, "msg-reason", core.RejectReasonDuplicate)
+	require.NoError(t, err)
+
+	rec, err := storage.GetApproval(ctx, "msg-reason")
+	require.NoError(t, err)
+	assert.Equal(t, core.ApprovalStateRejected, rec.State)
+	assert.Equal(t, core.RejectReasonDuplicate, rec.RejectReason)
+
+	// Test rejecting without reason
+	err = storage.RejectApproval(ctx, "msg-no-reason", "")
+	require.NoError(t, err)
+
+	rec, err = storage.GetApproval(ctx, "msg-no-reason")
+	require.NoError(t, err)
+	assert.Equal(t, core.ApprovalStateRejected, rec.State)
+	assert.Empty(t, rec.RejectReason)
+
+	// Test rejecting non-existent approval
+	err = storage.RejectApproval(ctx, "non-existent", core.RejectReasonSpam)
+	assert.Error(t, err)
+
+	// Test rejecting approval which has been expired by the sweeper
+	_, err = db.Exec("INSERT INTO approvals (message_id, state, request_data) VALUES (?, ?, '{}')",
+		"msg-expired", core.ApprovalStateExpired)
+	require.NoError(t, err)
+	var shErr *sharedcore.Error
+	err = storage.RejectApproval(ctx, "msg-expired", core.RejectReasonSpam)
+	require.ErrorAs(t, err, &shErr)
+	assert.Equal(t, sharedcore.ErrCodeConflict, shErr.ErrCode)
+
+	rec, err = storage.GetApproval(ctx, "msg-expired")
+	require.NoError(t, err)
+	assert.Equal(t, core.ApprovalStateExpired, rec.State)
+}
+
+func TestConsumeToken(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	// Test first consume succeeds
+	ok, err := storage.ConsumeToken(ctx, "token-1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Test replay is rejected
+	ok, err = storage.ConsumeToken(ctx, "token-1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Test revoked token cannot be consumed
+	require.NoError(t, storage.RevokeToken(ctx, "token-2"))
+	ok, err = storage.ConsumeToken(ctx, "token-2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Test revocation is told apart from consumption
+	revoked, err := storage.IsTokenRevoked(ctx, "token-2")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = storage.IsTokenRevoked(ctx, "token-1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Test revoking consumed token is a no-op
+	require.NoError(t, storage.RevokeToken(ctx, "token-1"))
+}
+
+func TestRevokeTokensIssuedBefore(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	// Test no revocation yet
+	revokedBefore, err := storage.GetTokensRevokedBefore(ctx)
+	require.NoError(t, err)
+	assert.True(t, revokedBefore.IsZero())
+
+	// Test latest revocation is returned
+	older := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	newer := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, storage.RevokeTokensIssuedBefore(ctx, newer))
+	require.NoError(t, storage.RevokeTokensIssuedBefore(ctx, older))
+
+	revokedBefore, err = storage.GetTokensRevokedBefore(ctx)
+	require.NoError(t, err)
+	assert.True(t, newer.Equal(revokedBefore))
+}
+
+func TestAPIKey(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	key := core.APIKey{
+		ID:                     "key-1",
+		Name:                   "crawler",
+		Scopes:                 []core.APIKeyScope{core.APIKeyScopeBulk, core.APIKeyScopeAdmin},
+		DefaultSubmissionEmail: "crawler",
+		CreatedAt:              time.Now().UTC().Truncate(time.Second),
+		SecretHash:             "secret-hash",
+	}
+	require.NoError(t, storage.SaveAPIKey(ctx, key))
+
+	// Test getting saved key
+	got, err := storage.GetAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, key, *got)
+
+	// Test usage accounting
+	require.NoError(t, storage.TouchAPIKey(ctx, key.ID))
+	require.NoError(t, storage.TouchAPIKey(ctx, key.ID))
+
+	got, err = storage.GetAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.UsageCount)
+	require.NotNil(t, got.LastUsedAt)
+
+	// Test revoking key
+	require.NoError(t, storage.RevokeAPIKey(ctx, key.ID))
+	require.NoError(t, storage.RevokeAPIKey(ctx, key.ID))
+
+	keys, err := storage.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].RevokedAt)
+
+	// Test non-existent key
+	_, err = storage.GetAPIKey(ctx, "non-existent")
+	assert.Error(t, err)
+	assert.Error(t, storage.RevokeAPIKey(ctx, "non-existent"))
+}
+
+func TestFindApprovalByApplyURL(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	newReq := func(id, applyURL string) sharedcore.SubmitRequest {
+		return sharedcore.SubmitRequest{
+			SubmissionID:   id,
+			SubmissionType: sharedcore.SubmitTypeURL,
+			Vacancy:        sharedcore.Vacancy{ApplyURL: applyURL},
+		}
+	}
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-pending", newReq("sub-1", "https://example.com/jobs/1?utm_source=x")))
+	require.NoError(t, storage.SaveApprovalRequest(ctx, "msg-rejected", newReq("sub-2", "https://example.com/jobs/2")))
+	require.NoError(t, storage.RejectApproval(ctx, "msg-rejected", core.RejectReasonBrokenLink))
+
+	rec, err := storage.FindApprovalByApplyURL(ctx, "HTTPS://Example.com/jobs/1/")
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.Equal(t, "msg-pending", rec.MessageID)
+	assert.Equal(t, "sub-1", rec.Request.SubmissionID)
+
+	// rejected vacancy can be submitted again
+	rec, err = storage.FindApprovalByApplyURL(ctx, "https://example.com/jobs/2")
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+
+	rec, err = storage.FindApprovalByApplyURL(ctx, "https://example.com/jobs/3")
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	storage, err := NewMySQLStorage(MySQLStorageConfig{DB: db})
+	require.NoError(t, err)
+
+	// first request reserves the key
+	rec, err := storage.ReserveIdempotencyKey(ctx, "key-1:abc", "hash-1", time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+
+	// concurrent request finds the key still being processed
+	rec, err = storage.ReserveIdempotencyKey(ctx, "key-1:abc", "hash-1", time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.Equal(t, "hash-1", rec.RequestHash)
+	assert.False(t, rec.IsCompleted())
+
+	// replay after completion returns the stored result
+	require.NoError(t, storage.CompleteIdempotencyKey(ctx, "key-1:abc", []byte(`{"submission_id":"sub-1"}`)))
+	rec, err = storage.ReserveIdempotencyKey(ctx, "key-1:abc", "hash-2", time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.Equal(t, "hash-1", rec.RequestHash)
+	assert.JSONEq(t, `{"submission_id":"sub-1"}`, string(rec.Result))
+
+	// completed key can't be released
+	require.NoError(t, storage.ReleaseIdempotencyKey(ctx, "key-1:abc"))
+	rec, err = storage.ReserveIdempotencyKey(ctx, "key-1:abc", "hash-1", time.Hour)
+	require.NoError(t, err)
+	assert.NotNil(t, rec)
+
+	// released key can be reserved again
+	_, err = storage.ReserveIdempotencyKey(ctx, "key-1:def", "hash-1", time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, storage.ReleaseIdempotencyKey(ctx, "key-1:def"))
+	rec, err = storage.ReserveIdempotencyKey(ctx, "key-1:def", "hash-1", time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+
+	// expired key can be reserved again
+	_, err = db.Exec("UPDATE idempotency_keys SET created_at = NOW() - INTERVAL 2 HOUR WHERE idempotency_key = ?", "key-1:abc")
+	require.NoError(t, err)
+	rec, err = storage.ReserveIdempotencyKey(ctx, "key-1:abc", "hash-2", time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, "john", escapeLike("john"))
+	assert.Equal(t, `100\%`, escapeLike("100%"))
+	assert.Equal(t, `j\_ne`, escapeLike("j_ne"))
+	assert.Equal(t, `a\\b`, escapeLike(`a\b`))
+}
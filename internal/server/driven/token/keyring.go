@@ -0,0 +1,44 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SigningKey is a single secret in the tokenizer keyring, the ID is put in
+// the `kid` header of the tokens signed with it.
+type SigningKey struct {
+	ID     string `validate:"nonzero"`
+	Secret string `validate:"nonzero"`
+	// Retired keys are kept in the keyring but tokens signed with them are
+	// no longer accepted.
+	Retired bool
+}
+
+// ParseKeyring parses keyring in the format of `kid1:secret1,kid2:secret2`,
+// key ids listed in retiredIDs are marked as retired.
+func ParseKeyring(val string, retiredIDs []string) ([]SigningKey, error) {
+	retired := make(map[string]bool, len(retiredIDs))
+	for _, id := range retiredIDs {
+		retired[strings.TrimSpace(id)] = true
+	}
+
+	var keys []SigningKey
+	for _, pair := range strings.Split(val, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid key %q, expected format kid:secret", id)
+		}
+		keys = append(keys, SigningKey{
+			ID:      id,
+			Secret:  secret,
+			Retired: retired[id],
+		})
+	}
+
+	return keys, nil
+}
@@ -1,19 +1,35 @@
 package token
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
+	servercore "github.com/ghazlabs/idn-remote-entry/internal/server/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	jwt "github.com/golang-jwt/jwt/v5"
 	"gopkg.in/validator.v2"
 )
 
+// DefaultTTL is how long the issued token is valid when TTL is not set.
+const DefaultTTL = 7 * 24 * time.Hour
+
 type TokenizerConfig struct {
-	SecretKey string `validate:"nonzero"`
+	// Keys is the keyring used to verify tokens, old keys should be kept
+	// here until the tokens signed with them are expired.
+	Keys []SigningKey `validate:"min=1"`
+	// ActiveKeyID is the id of the key used to sign new tokens.
+	ActiveKeyID string `validate:"nonzero"`
+	// TTL is how long the issued token is valid, default to DefaultTTL.
+	TTL time.Duration
 }
 
 type Tokenizer struct {
 	TokenizerConfig
+	keys map[string]SigningKey
 }
 
 func NewTokenizer(cfg TokenizerConfig) (*Tokenizer, error) {
@@ -21,19 +37,52 @@ func NewTokenizer(cfg TokenizerConfig) (*Tokenizer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+
+	keys := make(map[string]SigningKey, len(cfg.Keys))
+	for _, key := range cfg.Keys {
+		if _, ok := keys[key.ID]; ok {
+			return nil, fmt.Errorf("invalid config: duplicate key id %s", key.ID)
+		}
+		keys[key.ID] = key
+	}
+
+	activeKey, ok := keys[cfg.ActiveKeyID]
+	if !ok {
+		return nil, fmt.Errorf("invalid config: active key %s is not in the keyring", cfg.ActiveKeyID)
+	}
+	if activeKey.Retired {
+		return nil, fmt.Errorf("invalid config: active key %s is retired", cfg.ActiveKeyID)
+	}
 
 	return &Tokenizer{
 		TokenizerConfig: cfg,
+		keys:            keys,
 	}, nil
 }
 
 func (t *Tokenizer) EncodeRequest(req core.SubmitRequest) (string, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token id due to: %w", err)
+	}
+
+	now := time.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":              tokenID,
+		// millisecond precision so the token issued right after bulk
+		// revocation is not taken as revoked
+		"iat":              float64(now.UnixMilli()) / 1000,
+		"exp":              now.Add(t.TTL).Unix(),
+		"submission_id":    req.SubmissionID,
 		"submission_type":  req.SubmissionType,
 		"submission_email": req.SubmissionEmail,
 		"vacancy":          req.Vacancy,
 	})
-	tokenString, err := token.SignedString([]byte(t.SecretKey))
+	token.Header["kid"] = t.ActiveKeyID
+	tokenString, err := token.SignedString([]byte(t.keys[t.ActiveKeyID].Secret))
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token due to: %w", err)
 	}
@@ -41,13 +90,45 @@ func (t *Tokenizer) EncodeRequest(req core.SubmitRequest) (string, error) {
 	return tokenString, nil
 }
 
-func (t *Tokenizer) DecodeToken(tokenStr string) (core.SubmitRequest, error) {
+func (t *Tokenizer) DecodeToken(tokenStr string) (servercore.ApprovalToken, error) {
 	claims, err := t.parseJWT(tokenStr)
 	if err != nil {
-		return core.SubmitRequest{}, fmt.Errorf("failed to parse token due to: %w", err)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return servercore.ApprovalToken{}, core.NewBadRequestError("token has expired")
+		}
+		return servercore.ApprovalToken{}, core.NewBadRequestError(fmt.Sprintf("invalid token: %v", err))
+	}
+
+	tokenID := getString(claims, "jti")
+	if tokenID == "" {
+		return servercore.ApprovalToken{}, core.NewBadRequestError("invalid token: missing token id")
 	}
 
-	return t.mapReqVacancy(claims), nil
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return servercore.ApprovalToken{}, core.NewBadRequestError("invalid token: missing issued at")
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return servercore.ApprovalToken{}, core.NewBadRequestError("invalid token: missing expiration time")
+	}
+
+	return servercore.ApprovalToken{
+		ID:        tokenID,
+		Request:   t.mapReqVacancy(claims),
+		IssuedAt:  preciseIssuedAt(claims, issuedAt.Time),
+		ExpiresAt: expiresAt.Time,
+	}, nil
+}
+
+// preciseIssuedAt returns the issued at claim in millisecond precision,
+// the parsed claim is truncated to second by jwt package.
+func preciseIssuedAt(claims jwt.MapClaims, truncated time.Time) time.Time {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return truncated
+	}
+	return time.UnixMilli(int64(math.Round(iat * 1000)))
 }
 
 func (t *Tokenizer) parseJWT(tokenStr string) (jwt.MapClaims, error) {
@@ -55,8 +136,16 @@ func (t *Tokenizer) parseJWT(tokenStr string) (jwt.MapClaims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(t.SecretKey), nil
-	})
+		keyID, _ := token.Header["kid"].(string)
+		key, ok := t.keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id: %q", keyID)
+		}
+		if key.Retired {
+			return nil, fmt.Errorf("key %s is retired", keyID)
+		}
+		return []byte(key.Secret), nil
+	}, jwt.WithExpirationRequired(), jwt.WithIssuedAt())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
@@ -85,12 +174,22 @@ func (t *Tokenizer) mapReqVacancy(claims jwt.MapClaims) core.SubmitRequest {
 	}
 
 	return core.SubmitRequest{
+		SubmissionID:    getString(claims, "submission_id"),
 		SubmissionType:  submissionType,
 		SubmissionEmail: getString(claims, "submission_email"),
 		Vacancy:         vacancy,
 	}
 }
 
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func getString(m map[string]interface{}, key string) string {
 	if val, ok := m[key].(string); ok {
 		return val
@@ -2,19 +2,26 @@ package token_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/server/driven/token"
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestEncodeDecodeToken(t *testing.T) {
 	secretKey := "secret"
-	tokenizer, err := token.NewTokenizer(token.TokenizerConfig{SecretKey: secretKey})
+	tokenizer, err := token.NewTokenizer(token.TokenizerConfig{
+		Keys:        []token.SigningKey{{ID: "key-1", Secret: secretKey}},
+		ActiveKeyID: "key-1",
+		TTL:         time.Hour,
+	})
 	require.NoError(t, err)
 
 	req := core.SubmitRequest{
+		SubmissionID:    "submission-id",
 		SubmissionType:  core.SubmitTypeManual,
 		SubmissionEmail: "admin@gmail.com",
 		Vacancy: core.Vacancy{
@@ -27,11 +34,248 @@ func TestEncodeDecodeToken(t *testing.T) {
 		},
 	}
 
+	issuedAfter := time.Now().Truncate(time.Millisecond)
 	tokenStr, err := tokenizer.EncodeRequest(req)
 	require.NoError(t, err)
 
-	decodedReq, err := tokenizer.DecodeToken(tokenStr)
+	decoded, err := tokenizer.DecodeToken(tokenStr)
 	require.NoError(t, err)
 
-	assert.Equal(t, req, decodedReq)
+	assert.Equal(t, req, decoded.Request)
+	assert.NotEmpty(t, decoded.ID)
+	// issued at keeps millisecond precision for bulk revocation
+	assert.False(t, decoded.IssuedAt.Before(issuedAfter))
+	assert.WithinDuration(t, time.Now(), decoded.IssuedAt, 2*time.Second)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), decoded.ExpiresAt, 2*time.Second)
+
+	// every token must have its own id
+	otherTokenStr, err := tokenizer.EncodeRequest(req)
+	require.NoError(t, err)
+	other, err := tokenizer.DecodeToken(otherTokenStr)
+	require.NoError(t, err)
+	assert.NotEqual(t, decoded.ID, other.ID)
+}
+
+func TestDecodeTokenInvalid(t *testing.T) {
+	secretKey := "secret"
+	tokenizer, err := token.NewTokenizer(token.TokenizerConfig{
+		Keys:        []token.SigningKey{{ID: "key-1", Secret: secretKey}},
+		ActiveKeyID: "key-1",
+		TTL:         time.Hour,
+	})
+	require.NoError(t, err)
+
+	signToken := func(claims jwt.MapClaims) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		tok.Header["kid"] = "key-1"
+		tokenStr, err := tok.SignedString([]byte(secretKey))
+		require.NoError(t, err)
+		return tokenStr
+	}
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"jti":             "token-id",
+			"iat":             time.Now().Unix(),
+			"exp":             time.Now().Add(time.Hour).Unix(),
+			"submission_type": core.SubmitTypeURL,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		tokenStr string
+	}{
+		{
+			name: "expired token",
+			tokenStr: signToken(jwt.MapClaims{
+				"jti":             "token-id",
+				"iat":             time.Now().Add(-2 * time.Hour).Unix(),
+				"exp":             time.Now().Add(-time.Hour).Unix(),
+				"submission_type": core.SubmitTypeURL,
+			}),
+		},
+		{
+			name: "token without expiry",
+			tokenStr: signToken(jwt.MapClaims{
+				"jti":             "token-id",
+				"iat":             time.Now().Unix(),
+				"submission_type": core.SubmitTypeURL,
+			}),
+		},
+		{
+			name: "token without id",
+			tokenStr: signToken(jwt.MapClaims{
+				"iat":             time.Now().Unix(),
+				"exp":             time.Now().Add(time.Hour).Unix(),
+				"submission_type": core.SubmitTypeURL,
+			}),
+		},
+		{
+			name:     "malformed token",
+			tokenStr: "not-a-token",
+		},
+		{
+			name: "token without key id",
+			tokenStr: func() string {
+				tokenStr, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte(secretKey))
+				require.NoError(t, err)
+				return tokenStr
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tokenizer.DecodeToken(tt.tokenStr)
+			require.Error(t, err)
+
+			var shErr *core.Error
+			require.ErrorAs(t, err, &shErr)
+			assert.Equal(t, core.ErrCodeBadRequest, shErr.ErrCode)
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	req := core.SubmitRequest{
+		SubmissionID:    "submission-id",
+		SubmissionType:  core.SubmitTypeURL,
+		SubmissionEmail: "admin@gmail.com",
+		Vacancy: core.Vacancy{
+			ApplyURL: "https://example.com/apply",
+		},
+	}
+
+	// issue token with the old key
+	oldTokenizer, err := token.NewTokenizer(token.TokenizerConfig{
+		Keys:        []token.SigningKey{{ID: "key-1", Secret: "old-secret"}},
+		ActiveKeyID: "key-1",
+		TTL:         time.Hour,
+	})
+	require.NoError(t, err)
+	oldTokenStr, err := oldTokenizer.EncodeRequest(req)
+	require.NoError(t, err)
+
+	// rotate to the new key, old token must still be accepted
+	rotatedTokenizer, err := token.NewTokenizer(token.TokenizerConfig{
+		Keys: []token.SigningKey{
+			{ID: "key-1", Secret: "old-secret"},
+			{ID: "key-2", Secret: "new-secret"},
+		},
+		ActiveKeyID: "key-2",
+		TTL:         time.Hour,
+	})
+	require.NoError(t, err)
+
+	decoded, err := rotatedTokenizer.DecodeToken(oldTokenStr)
+	require.NoError(t, err)
+	assert.Equal(t, req, decoded.Request)
+
+	newTokenStr, err := rotatedTokenizer.EncodeRequest(req)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newTokenStr, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", parsed.Header["kid"])
+
+	// old tokenizer doesn't know the new key
+	_, err = oldTokenizer.DecodeToken(newTokenStr)
+	assert.Error(t, err)
+
+	// retire the old key, old token must be rejected
+	retiredTokenizer, err := token.NewTokenizer(token.TokenizerConfig{
+		Keys: []token.SigningKey{
+			{ID: "key-1", Secret: "old-secret", Retired: true},
+			{ID: "key-2", Secret: "new-secret"},
+		},
+		ActiveKeyID: "key-2",
+		TTL:         time.Hour,
+	})
+	require.NoError(t, err)
+
+	_, err = retiredTokenizer.DecodeToken(oldTokenStr)
+	assert.Error(t, err)
+
+	_, err = retiredTokenizer.DecodeToken(newTokenStr)
+	assert.NoError(t, err)
+}
+
+func TestNewTokenizerInvalidKeyring(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  token.TokenizerConfig
+	}{
+		{
+			name: "empty keyring",
+			cfg: token.TokenizerConfig{
+				ActiveKeyID: "key-1",
+				TTL:         time.Hour,
+			},
+		},
+		{
+			name: "unknown active key",
+			cfg: token.TokenizerConfig{
+				Keys:        []token.SigningKey{{ID: "key-1", Secret: "secret"}},
+				ActiveKeyID: "key-2",
+				TTL:         time.Hour,
+			},
+		},
+		{
+			name: "retired active key",
+			cfg: token.TokenizerConfig{
+				Keys:        []token.SigningKey{{ID: "key-1", Secret: "secret", Retired: true}},
+				ActiveKeyID: "key-1",
+				TTL:         time.Hour,
+			},
+		},
+		{
+			name: "duplicate key id",
+			cfg: token.TokenizerConfig{
+				Keys: []token.SigningKey{
+					{ID: "key-1", Secret: "secret"},
+					{ID: "key-1", Secret: "other-secret"},
+				},
+				ActiveKeyID: "key-1",
+				TTL:         time.Hour,
+			},
+		},
+		{
+			name: "key without secret",
+			cfg: token.TokenizerConfig{
+				Keys:        []token.SigningKey{{ID: "key-1"}},
+				ActiveKeyID: "key-1",
+				TTL:         time.Hour,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := token.NewTokenizer(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewTokenizerDefaultTTL(t *testing.T) {
+	tokenizer, err := token.NewTokenizer(token.TokenizerConfig{
+		Keys:        []token.SigningKey{{ID: "key-1", Secret: "secret"}},
+		ActiveKeyID: "key-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, token.DefaultTTL, tokenizer.TTL)
+}
+
+func TestParseKeyring(t *testing.T) {
+	keys, err := token.ParseKeyring("key-1:old-secret, key-2:new:secret,", []string{"key-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []token.SigningKey{
+		{ID: "key-1", Secret: "old-secret", Retired: true},
+		{ID: "key-2", Secret: "new:secret"},
+	}, keys)
+
+	_, err = token.ParseKeyring("key-1", nil)
+	assert.Error(t, err)
+
+	_, err = token.ParseKeyring(":secret", nil)
+	assert.Error(t, err)
 }
@@ -0,0 +1,438 @@
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const adminRealm = "IDN Remote Entry Admin"
+
+// ageOptions are the selectable age filters on the dashboard
+var ageOptions = []struct {
+	Value string
+	Label string
+}{
+	{Value: "1h", Label: "1 hour"},
+	{Value: "24h", Label: "1 day"},
+	{Value: "72h", Label: "3 days"},
+	{Value: "168h", Label: "7 days"},
+}
+
+func (a *API) isAdminEnabled() bool {
+	return a.AdminUsername != "" && a.AdminPassword != ""
+}
+
+func (a *API) adminRouter() http.Handler {
+	r := chi.NewRouter()
+
+	// dashboard pages
+	r.Group(func(r chi.Router) {
+		r.Use(a.adminBasicAuth)
+
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/admin/approvals", http.StatusFound)
+		})
+		r.Get("/approvals", a.serveAdminListApprovals)
+		r.Get("/approvals/{message_id}", a.serveAdminApprovalDetail)
+		r.Post("/approvals/{message_id}/approve", a.serveAdminApprove)
+		r.Post("/approvals/{message_id}/reject", a.serveAdminReject)
+		r.Post("/approvals/{message_id}/edit", a.serveAdminEditApprove)
+		r.Post("/batches/{batch_id}/approve", a.serveAdminApproveBatch)
+		r.Post("/batches/{batch_id}/reject", a.serveAdminRejectBatch)
+	})
+
+	// json endpoints, these are also callable by api key with admin scope
+	r.Group(func(r chi.Router) {
+		r.Use(a.adminAPIAuth)
+
+		r.Post("/tokens/revoke", a.serveAdminRevokeTokens)
+		r.Get("/api-keys", a.serveAdminListAPIKeys)
+		r.Post("/api-keys", a.serveAdminCreateAPIKey)
+		r.Post("/api-keys/{id}/revoke", a.serveAdminRevokeAPIKey)
+	})
+
+	return r
+}
+
+// adminBasicAuth authenticates the request using basic auth. Browser sends
+// the cached credentials along with cross-site form post as well, so the
+// state changing request coming from other site is refused.
+func (a *API) adminBasicAuth(next http.Handler) http.Handler {
+	return middleware.BasicAuth(adminRealm, map[string]string{a.AdminUsername: a.AdminPassword})(rejectCrossOrigin(next))
+}
+
+// rejectCrossOrigin refuses the unsafe request sent by browser from other
+// site, it is told by `Sec-Fetch-Site` header or by `Origin` header on older
+// browser. Request without both headers is not sent by browser (e.g curl),
+// so it is let through.
+func rejectCrossOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var crossOrigin bool
+		if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+			crossOrigin = site != "same-origin" && site != "none"
+		} else if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			crossOrigin = err != nil || u.Host != r.Host
+		}
+		if crossOrigin {
+			render.Render(w, r, NewErrorResp(NewForbiddenError("cross-origin request is not allowed")))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminAPIAuth authenticates the request using api key when `X-Api-Key`
+// header is set, otherwise it falls back to basic auth.
+func (a *API) adminAPIAuth(next http.Handler) http.Handler {
+	basicAuth := a.adminBasicAuth(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") == "" {
+			basicAuth.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := a.authenticateAPIKey(r)
+		if err != nil {
+			render.Render(w, r, NewErrorResp(err))
+			return
+		}
+		if !key.HasScope(core.APIKeyScopeAdmin) {
+			render.Render(w, r, NewErrorResp(NewForbiddenError("api key is not allowed to access admin endpoints")))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type adminListPage struct {
+	Approvals []core.ApprovalRecord
+	Submitter string
+	Type      string
+	Batch     string
+	// BatchToken is used for deciding on the listed batch, it is only set
+	// when the list is filtered by batch
+	BatchToken string
+	MinAge     string
+	MaxAge     string
+	AgeOptions interface{}
+	Notice     string
+}
+
+type adminDetailPage struct {
+	Approval            *core.ApprovalRecord
+	RequestJSON         string
+	OriginalRequestJSON string
+}
+
+type adminErrorPage struct {
+	StatusCode int
+	Message    string
+}
+
+func (a *API) serveAdminListApprovals(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page := adminListPage{
+		Submitter:  q.Get("submitter"),
+		Type:       q.Get("type"),
+		Batch:      q.Get("batch"),
+		MinAge:     q.Get("min_age"),
+		MaxAge:     q.Get("max_age"),
+		AgeOptions: ageOptions,
+	}
+	if done := q.Get("done"); done != "" {
+		page.Notice = fmt.Sprintf("Approval %s has been %s.", q.Get("message_id"), done)
+		if count := q.Get("count"); count != "" {
+			page.Notice = fmt.Sprintf("%s approvals of batch %s have been %s.", count, page.Batch, done)
+		}
+	}
+
+	filter := core.ApprovalFilter{
+		SubmissionEmail: page.Submitter,
+		SubmissionType:  shcore.SubmitType(page.Type),
+		BatchID:         page.Batch,
+	}
+	var err error
+	filter.MinAge, err = parseAge(page.MinAge)
+	if err != nil {
+		a.renderAdminError(w, NewBadRequestError(fmt.Sprintf("invalid min_age: %v", err)))
+		return
+	}
+	filter.MaxAge, err = parseAge(page.MaxAge)
+	if err != nil {
+		a.renderAdminError(w, NewBadRequestError(fmt.Sprintf("invalid max_age: %v", err)))
+		return
+	}
+
+	page.Approvals, err = a.Service.ListPendingApprovals(r.Context(), filter)
+	if err != nil {
+		a.renderAdminError(w, err)
+		return
+	}
+	if page.Batch != "" && len(page.Approvals) > 0 {
+		page.BatchToken, err = a.Service.IssueBatchToken(page.Batch)
+		if err != nil {
+			a.renderAdminError(w, err)
+			return
+		}
+	}
+
+	a.renderAdminPage(w, "list", page)
+}
+
+func (a *API) serveAdminApprovalDetail(w http.ResponseWriter, r *http.Request) {
+	rec, err := a.Service.GetApproval(r.Context(), chi.URLParam(r, "message_id"))
+	if err != nil {
+		a.renderAdminError(w, err)
+		return
+	}
+
+	page := adminDetailPage{Approval: rec}
+	reqJSON, _ := json.MarshalIndent(rec.Request, "", "  ")
+	page.RequestJSON = string(reqJSON)
+	if rec.OriginalRequest != nil {
+		origJSON, _ := json.MarshalIndent(rec.OriginalRequest, "", "  ")
+		page.OriginalRequestJSON = string(origJSON)
+	}
+
+	a.renderAdminPage(w, "detail", page)
+}
+
+func (a *API) serveAdminApprove(w http.ResponseWriter, r *http.Request) {
+	messageID := chi.URLParam(r, "message_id")
+	err := a.Service.HandleApprove(r.Context(), core.ApprovalRequest{
+		TokenRequest: r.FormValue("token"),
+		MessageID:    messageID,
+	})
+	if err != nil {
+		a.renderAdminError(w, err)
+		return
+	}
+
+	redirectAfterAdminAction(w, r, messageID, "approved")
+}
+
+func (a *API) serveAdminReject(w http.ResponseWriter, r *http.Request) {
+	messageID := chi.URLParam(r, "message_id")
+	// typed reason takes precedence over the picked one
+	reason := r.FormValue("reason_text")
+	if strings.TrimSpace(reason) == "" {
+		reason = r.FormValue("reason")
+	}
+	err := a.Service.HandleReject(r.Context(), core.ApprovalRequest{
+		TokenRequest: r.FormValue("token"),
+		MessageID:    messageID,
+		RejectReason: core.RejectReason(reason),
+	})
+	if err != nil {
+		a.renderAdminError(w, err)
+		return
+	}
+
+	redirectAfterAdminAction(w, r, messageID, "rejected")
+}
+
+func (a *API) serveAdminApproveBatch(w http.ResponseWriter, r *http.Request) {
+	batchID := chi.URLParam(r, "batch_id")
+	r.ParseForm()
+	// empty selection would otherwise approve the whole batch
+	if r.PostForm.Get("selected") == "true" && len(r.PostForm["message_id"]) == 0 {
+		a.renderAdminError(w, NewBadRequestError("no approval is selected"))
+		return
+	}
+	result, err := a.Service.HandleApproveBatch(r.Context(), core.BatchApprovalRequest{
+		TokenRequest: r.PostForm.Get("token"),
+		BatchID:      batchID,
+		MessageIDs:   r.PostForm["message_id"],
+	})
+	if err != nil {
+		a.renderAdminError(w, err)
+		return
+	}
+
+	redirectAfterAdminBatchAction(w, r, result)
+}
+
+func (a *API) serveAdminRejectBatch(w http.ResponseWriter, r *http.Request) {
+	batchID := chi.URLParam(r, "batch_id")
+	r.ParseForm()
+	// typed reason takes precedence over the picked one
+	reason := r.PostForm.Get("reason_text")
+	if strings.TrimSpace(reason) == "" {
+		reason = r.PostForm.Get("reason")
+	}
+	result, err := a.Service.HandleRejectBatch(r.Context(), core.BatchApprovalRequest{
+		TokenRequest: r.PostForm.Get("token"),
+		BatchID:      batchID,
+		MessageIDs:   r.PostForm["message_id"],
+		RejectReason: core.RejectReason(reason),
+	})
+	if err != nil {
+		a.renderAdminError(w, err)
+		return
+	}
+
+	redirectAfterAdminBatchAction(w, r, result)
+}
+
+func (a *API) serveAdminEditApprove(w http.ResponseWriter, r *http.Request) {
+	messageID := chi.URLParam(r, "message_id")
+	err := a.Service.HandleEditApprove(r.Context(), core.EditApprovalRequest{
+		TokenRequest: r.FormValue("token"),
+		MessageID:    messageID,
+		Vacancy: prepareVacancy(shcore.Vacancy{
+			JobTitle:         strings.TrimSpace(r.FormValue("job_title")),
+			CompanyName:      strings.TrimSpace(r.FormValue("company_name")),
+			CompanyLocation:  strings.TrimSpace(r.FormValue("company_location")),
+			ShortDescription: strings.TrimSpace(r.FormValue("short_description")),
+			RelevantTags:     strings.Split(r.FormValue("relevant_tags"), ","),
+			ApplyURL:         strings.TrimSpace(r.FormValue("apply_url")),
+		}),
+	})
+	if err != nil {
+		a.renderAdminError(w, err)
+		return
+	}
+
+	redirectAfterAdminAction(w, r, messageID, "edited & approved")
+}
+
+// serveAdminRevokeTokens revokes the given approval token, or every
+// outstanding token when no token is given.
+func (a *API) serveAdminRevokeTokens(w http.ResponseWriter, r *http.Request) {
+	err := a.Service.RevokeTokens(r.Context(), core.RevokeTokensRequest{
+		TokenRequest: r.FormValue("token"),
+	})
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	render.Render(w, r, NewSuccessResp(nil))
+}
+
+func (a *API) serveAdminListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := a.Service.ListAPIKeys(r.Context())
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	render.Render(w, r, NewSuccessResp(keys))
+}
+
+func (a *API) serveAdminCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req core.CreateAPIKeyRequest
+	err := decodeRequestBody(w, r, &req)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	result, err := a.Service.CreateAPIKey(r.Context(), req)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	render.Render(w, r, NewSuccessResp(result))
+}
+
+func (a *API) serveAdminRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	err := a.Service.RevokeAPIKey(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	render.Render(w, r, NewSuccessResp(nil))
+}
+
+func (a *API) renderAdminPage(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := adminTemplates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		log.Printf("failed to render admin page %s: %v", name, err)
+	}
+}
+
+func (a *API) renderAdminError(w http.ResponseWriter, err error) {
+	resp := NewErrorResp(err)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(resp.StatusCode)
+	err = adminTemplates.ExecuteTemplate(w, "error", adminErrorPage{
+		StatusCode: resp.StatusCode,
+		Message:    resp.Message,
+	})
+	if err != nil {
+		log.Printf("failed to render admin error page: %v", err)
+	}
+}
+
+// redirectAfterAdminAction sends admin back to the list while keeping
+// the filters used before taking the action.
+func redirectAfterAdminAction(w http.ResponseWriter, r *http.Request, messageID, done string) {
+	q := url.Values{}
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Path == "/admin/approvals" {
+		q = ref.Query()
+	}
+	q.Set("done", done)
+	q.Set("message_id", messageID)
+	http.Redirect(w, r, "/admin/approvals?"+q.Encode(), http.StatusSeeOther)
+}
+
+// redirectAfterAdminBatchAction sends admin back to the batch list so the
+// remaining items can be decided.
+func redirectAfterAdminBatchAction(w http.ResponseWriter, r *http.Request, result *core.BatchApprovalResult) {
+	q := url.Values{}
+	q.Set("batch", result.BatchID)
+	q.Set("done", string(result.State))
+	q.Set("count", strconv.Itoa(len(result.MessageIDs)))
+	http.Redirect(w, r, "/admin/approvals?"+q.Encode(), http.StatusSeeOther)
+}
+
+func parseAge(val string) (time.Duration, error) {
+	if val == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("age cannot be negative")
+	}
+	return d, nil
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
@@ -0,0 +1,194 @@
+package driver
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+)
+
+var adminTemplates = template.Must(
+	template.New("admin").
+		Funcs(template.FuncMap{
+			"formatAge": formatAge,
+			"join":      strings.Join,
+			"rejectReasons": func() []core.RejectReason {
+				return core.RejectReasons
+			},
+		}).
+		Parse(adminTemplateText),
+)
+
+const adminTemplateText = `
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>IDN Remote Entry Admin</title>
+	<style>
+		body { font-family: Arial, sans-serif; margin: 24px; color: #333; }
+		table { border-collapse: collapse; width: 100%; }
+		th, td { border-bottom: 1px solid #ddd; padding: 8px; text-align: left; vertical-align: top; }
+		th { background: #f5f5f5; }
+		form.inline { display: inline; }
+		.filters { margin-bottom: 16px; }
+		.filters label { margin-right: 12px; }
+		.notice { background: #e8f5e9; padding: 8px 12px; margin-bottom: 16px; }
+		.error { background: #ffebee; padding: 8px 12px; margin-bottom: 16px; }
+		.btn { padding: 4px 10px; border: none; border-radius: 4px; color: white; cursor: pointer; }
+		.btn-approve { background-color: #4CAF50; }
+		.btn-reject { background-color: #f44336; }
+		pre { background: #f5f5f5; padding: 12px; overflow-x: auto; }
+		.edit input, .edit textarea { width: 100%; box-sizing: border-box; }
+	</style>
+</head>
+<body>
+	<h1><a href="/admin/approvals">Pending Approvals</a></h1>
+{{end}}
+
+{{define "footer"}}
+</body>
+</html>
+{{end}}
+
+{{define "actions"}}
+	<form class="inline" method="post" action="/admin/approvals/{{.MessageID}}/approve">
+		<input type="hidden" name="token" value="{{.Token}}">
+		<button class="btn btn-approve" type="submit">Approve</button>
+	</form>
+	<form class="inline" method="post" action="/admin/approvals/{{.MessageID}}/reject">
+		<input type="hidden" name="token" value="{{.Token}}">
+		<select name="reason">
+			<option value="">No reason</option>
+			{{range rejectReasons}}<option value="{{.}}">{{.Description}}</option>{{end}}
+		</select>
+		<input type="text" name="reason_text" placeholder="or type a reason">
+		<button class="btn btn-reject" type="submit">Reject</button>
+	</form>
+{{end}}
+
+{{define "batchActions"}}
+	<div class="filters">
+		<form id="batch-selected" class="inline" method="post" action="/admin/batches/{{.Batch}}/approve">
+			<input type="hidden" name="token" value="{{.BatchToken}}">
+			<input type="hidden" name="selected" value="true">
+			<button class="btn btn-approve" type="submit">Approve Selected</button>
+		</form>
+		<form class="inline" method="post" action="/admin/batches/{{.Batch}}/approve">
+			<input type="hidden" name="token" value="{{.BatchToken}}">
+			<button class="btn btn-approve" type="submit">Approve All</button>
+		</form>
+		<form class="inline" method="post" action="/admin/batches/{{.Batch}}/reject">
+			<input type="hidden" name="token" value="{{.BatchToken}}">
+			<select name="reason">
+				<option value="">No reason</option>
+				{{range rejectReasons}}<option value="{{.}}">{{.Description}}</option>{{end}}
+			</select>
+			<input type="text" name="reason_text" placeholder="or type a reason">
+			<button class="btn btn-reject" type="submit">Reject All</button>
+		</form>
+	</div>
+{{end}}
+
+{{define "list"}}{{template "header"}}
+	{{if .Notice}}<div class="notice">{{.Notice}}</div>{{end}}
+	<form class="filters" method="get" action="/admin/approvals">
+		{{if .Batch}}<input type="hidden" name="batch" value="{{.Batch}}">
+		<p>Showing bulk batch <strong>{{.Batch}}</strong> (<a href="/admin/approvals">show all</a>)</p>{{end}}
+		<label>Submitter <input type="text" name="submitter" value="{{.Submitter}}"></label>
+		<label>Type
+			<select name="type">
+				<option value="">All</option>
+				<option value="manual"{{if eq .Type "manual"}} selected{{end}}>manual</option>
+				<option value="url"{{if eq .Type "url"}} selected{{end}}>url</option>
+			</select>
+		</label>
+		<label>Waiting at least
+			<select name="min_age">
+				<option value="">Any</option>
+				{{range .AgeOptions}}<option value="{{.Value}}"{{if eq .Value $.MinAge}} selected{{end}}>{{.Label}}</option>{{end}}
+			</select>
+		</label>
+		<label>Waiting at most
+			<select name="max_age">
+				<option value="">Any</option>
+				{{range .AgeOptions}}<option value="{{.Value}}"{{if eq .Value $.MaxAge}} selected{{end}}>{{.Label}}</option>{{end}}
+			</select>
+		</label>
+		<button type="submit">Filter</button>
+	</form>
+	{{if .Approvals}}
+	{{if .BatchToken}}{{template "batchActions" .}}{{end}}
+	<table>
+		<tr>
+			{{if .BatchToken}}<th></th>{{end}}
+			<th>Waiting</th>
+			<th>Submitter</th>
+			<th>Type</th>
+			<th>Job Title</th>
+			<th>Company</th>
+			<th>Apply URL</th>
+			<th>Actions</th>
+		</tr>
+		{{range .Approvals}}
+		<tr>
+			{{if $.BatchToken}}<td><input type="checkbox" name="message_id" value="{{.MessageID}}" form="batch-selected"></td>{{end}}
+			<td>{{formatAge .Age}}</td>
+			<td>{{.Request.SubmissionEmail}}</td>
+			<td>{{.Request.SubmissionType}}{{if .BatchID}}<br><a href="/admin/approvals?batch={{.BatchID}}">bulk batch</a>{{end}}</td>
+			<td><a href="/admin/approvals/{{.MessageID}}">{{or .Request.Vacancy.JobTitle "(unresolved)"}}</a></td>
+			<td>{{.Request.Vacancy.CompanyName}}</td>
+			<td><a href="{{.Request.Vacancy.ApplyURL}}" target="_blank" rel="noopener">{{.Request.Vacancy.ApplyURL}}</a></td>
+			<td>{{template "actions" .}}</td>
+		</tr>
+		{{end}}
+	</table>
+	{{else}}
+	<p>No pending approvals.</p>
+	{{end}}
+{{template "footer"}}{{end}}
+
+{{define "detail"}}{{template "header"}}
+	<h2>{{or .Approval.Request.Vacancy.JobTitle "(unresolved)"}}</h2>
+	<table>
+		<tr><th>Message ID</th><td>{{.Approval.MessageID}}</td></tr>
+		<tr><th>State</th><td>{{.Approval.State}}</td></tr>
+		<tr><th>Kind</th><td>{{.Approval.Kind}}{{if .Approval.BatchID}} (<a href="/admin/approvals?batch={{.Approval.BatchID}}">{{.Approval.BatchID}}</a>){{end}}</td></tr>
+		{{if .Approval.RejectReason}}<tr><th>Reject Reason</th><td>{{.Approval.RejectReason.Description}}</td></tr>{{end}}
+		<tr><th>Waiting</th><td>{{formatAge .Approval.Age}}</td></tr>
+		<tr><th>Submitter</th><td>{{.Approval.Request.SubmissionEmail}}</td></tr>
+		<tr><th>Type</th><td>{{.Approval.Request.SubmissionType}}</td></tr>
+		<tr><th>Company</th><td>{{.Approval.Request.Vacancy.CompanyName}}</td></tr>
+		<tr><th>Apply URL</th><td><a href="{{.Approval.Request.Vacancy.ApplyURL}}" target="_blank" rel="noopener">{{.Approval.Request.Vacancy.ApplyURL}}</a></td></tr>
+	</table>
+	<h3>Request Data</h3>
+	<pre>{{.RequestJSON}}</pre>
+	{{if .OriginalRequestJSON}}
+	<h3>Original Request Data</h3>
+	<pre>{{.OriginalRequestJSON}}</pre>
+	{{end}}
+	{{if eq .Approval.State "pending"}}
+	{{template "actions" .Approval}}
+	<h3>Edit &amp; Approve</h3>
+	{{with .Approval}}
+	<form class="edit" method="post" action="/admin/approvals/{{.MessageID}}/edit">
+		<input type="hidden" name="token" value="{{.Token}}">
+		<table>
+			<tr><th>Job Title</th><td><input type="text" name="job_title" value="{{.Request.Vacancy.JobTitle}}"></td></tr>
+			<tr><th>Company</th><td><input type="text" name="company_name" value="{{.Request.Vacancy.CompanyName}}"></td></tr>
+			<tr><th>Company Location</th><td><input type="text" name="company_location" value="{{.Request.Vacancy.CompanyLocation}}"></td></tr>
+			<tr><th>Short Description</th><td><textarea name="short_description" rows="5">{{.Request.Vacancy.ShortDescription}}</textarea></td></tr>
+			<tr><th>Relevant Tags</th><td><input type="text" name="relevant_tags" value="{{join .Request.Vacancy.RelevantTags ", "}}"></td></tr>
+			<tr><th>Apply URL</th><td><input type="text" name="apply_url" value="{{.Request.Vacancy.ApplyURL}}"></td></tr>
+		</table>
+		<button class="btn btn-approve" type="submit">Save &amp; Approve</button>
+	</form>
+	{{end}}
+	{{end}}
+{{template "footer"}}{{end}}
+
+{{define "error"}}{{template "header"}}
+	<div class="error"><strong>{{.StatusCode}}</strong> {{.Message}}</div>
+	<p><a href="/admin/approvals">Back to pending approvals</a></p>
+{{template "footer"}}{{end}}
+`
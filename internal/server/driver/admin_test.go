@@ -0,0 +1,560 @@
+package driver_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driver"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
+func newAdminTestAPI(t *testing.T, service *stubService) http.Handler {
+	api, err := driver.NewAPI(driver.APIConfig{
+		Service:       service,
+		ClientApiKey:  "test-api-key",
+		AdminUsername: "admin",
+		AdminPassword: "secret",
+	})
+	require.NoError(t, err)
+	return api.GetHandler()
+}
+
+func TestAPIAdminDisabled(t *testing.T) {
+	api, err := driver.NewAPI(driver.APIConfig{
+		Service:      newStubService(),
+		ClientApiKey: "test-api-key",
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/approvals", nil)
+	req.SetBasicAuth("", "")
+	rec := httptest.NewRecorder()
+	api.GetHandler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// username without password must be rejected
+	_, err = driver.NewAPI(driver.APIConfig{
+		Service:       newStubService(),
+		ClientApiKey:  "test-api-key",
+		AdminUsername: "admin",
+	})
+	assert.Error(t, err)
+}
+
+func TestAPIAdminListApprovals(t *testing.T) {
+	tests := []struct {
+		name           string
+		username       string
+		password       string
+		query          string
+		expectedStatus int
+		expectedBody   []string
+		validateFilter func(t *testing.T, filter core.ApprovalFilter)
+	}{
+		{
+			name:           "success with filters",
+			username:       "admin",
+			password:       "secret",
+			query:          "?submitter=john&type=manual&batch=batch-1&min_age=24h&max_age=168h",
+			expectedStatus: http.StatusOK,
+			expectedBody: []string{
+				"Software Engineer",
+				"john@example.com",
+				`action="/admin/approvals/msg-1@example.com/approve"`,
+				`value="token-1"`,
+				`href="/admin/approvals?batch=batch-1"`,
+			},
+			validateFilter: func(t *testing.T, filter core.ApprovalFilter) {
+				assert.Equal(t, "john", filter.SubmissionEmail)
+				assert.Equal(t, shcore.SubmitTypeManual, filter.SubmissionType)
+				assert.Equal(t, "batch-1", filter.BatchID)
+				assert.Equal(t, 24*time.Hour, filter.MinAge)
+				assert.Equal(t, 168*time.Hour, filter.MaxAge)
+			},
+		},
+		{
+			name:           "invalid credentials",
+			username:       "admin",
+			password:       "wrong",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid age filter",
+			username:       "admin",
+			password:       "secret",
+			query:          "?min_age=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []string{"invalid min_age"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			var gotFilter *core.ApprovalFilter
+			service.listApprovalsFunc = func(ctx context.Context, filter core.ApprovalFilter) ([]core.ApprovalRecord, error) {
+				gotFilter = &filter
+				return []core.ApprovalRecord{
+					{
+						MessageID: "msg-1@example.com",
+						State:     core.ApprovalStatePending,
+						Kind:      core.ApprovalKindBulk,
+						BatchID:   "batch-1",
+						CreatedAt: time.Now().Add(-26 * time.Hour),
+						Token:     "token-1",
+						Request: shcore.SubmitRequest{
+							SubmissionType:  shcore.SubmitTypeManual,
+							SubmissionEmail: "john@example.com",
+							Vacancy: shcore.Vacancy{
+								JobTitle:    "Software Engineer",
+								CompanyName: "Test Company",
+								ApplyURL:    "https://example.com/apply",
+							},
+						},
+					},
+				}, nil
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/approvals"+tt.query, nil)
+			req.SetBasicAuth(tt.username, tt.password)
+			rec := httptest.NewRecorder()
+			newAdminTestAPI(t, service).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			for _, s := range tt.expectedBody {
+				assert.Contains(t, rec.Body.String(), s)
+			}
+			if tt.validateFilter != nil {
+				require.NotNil(t, gotFilter)
+				tt.validateFilter(t, *gotFilter)
+			}
+		})
+	}
+}
+
+func TestAPIAdminApprovalDetail(t *testing.T) {
+	service := newStubService()
+	service.getApprovalFunc = func(ctx context.Context, messageID string) (*core.ApprovalRecord, error) {
+		if messageID != "msg-1@example.com" {
+			return nil, shcore.NewNotFoundError("approval not found")
+		}
+		return &core.ApprovalRecord{
+			MessageID: messageID,
+			State:     core.ApprovalStatePending,
+			CreatedAt: time.Now(),
+			Token:     "token-1",
+			Request: shcore.SubmitRequest{
+				SubmissionEmail: "john@example.com",
+				Vacancy: shcore.Vacancy{
+					JobTitle:         "Software Engineer",
+					ShortDescription: "<script>alert(1)</script>",
+				},
+			},
+		}, nil
+	}
+	handler := newAdminTestAPI(t, service)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/approvals/msg-1@example.com", nil)
+	req.SetBasicAuth("admin", "secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Software Engineer")
+	assert.Contains(t, rec.Body.String(), `value="token-1"`)
+	assert.NotContains(t, rec.Body.String(), "<script>")
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/approvals/unknown", nil)
+	req.SetBasicAuth("admin", "secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAPIAdminActions(t *testing.T) {
+	tests := []struct {
+		name             string
+		action           string
+		serviceResponse  error
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name:             "approve",
+			action:           "approve",
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/admin/approvals?done=approved&message_id=msg-1%40example.com&type=manual",
+		},
+		{
+			name:             "reject",
+			action:           "reject",
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/admin/approvals?done=rejected&message_id=msg-1%40example.com&type=manual",
+		},
+		{
+			name:            "already processed",
+			action:          "approve",
+			serviceResponse: shcore.NewBadRequestError("approval already processed"),
+			expectedStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			service.handleApproveFunc = func(ctx context.Context, req core.ApprovalRequest) error {
+				return tt.serviceResponse
+			}
+			service.handleRejectFunc = func(ctx context.Context, req core.ApprovalRequest) error {
+				return tt.serviceResponse
+			}
+
+			form := url.Values{"token": {"token-1"}, "reason": {"duplicate"}, "reason_text": {""}}
+			req := httptest.NewRequest(http.MethodPost, "/admin/approvals/msg-1@example.com/"+tt.action, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Referer", "http://localhost/admin/approvals?type=manual")
+			req.SetBasicAuth("admin", "secret")
+			rec := httptest.NewRecorder()
+			newAdminTestAPI(t, service).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedLocation != "" {
+				assert.Equal(t, tt.expectedLocation, rec.Header().Get("Location"))
+			}
+
+			var got *core.ApprovalRequest
+			if tt.action == "approve" {
+				got = service.lastApprovalRequest
+			} else {
+				got = service.lastRejectRequest
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, "token-1", got.TokenRequest)
+			assert.Equal(t, "msg-1@example.com", got.MessageID)
+			if tt.action == "reject" {
+				assert.Equal(t, core.RejectReasonDuplicate, got.RejectReason)
+			}
+		})
+	}
+}
+
+func TestAPIAdminBatchActions(t *testing.T) {
+	// batch list shows the batch actions
+	service := newStubService()
+	service.listApprovalsFunc = func(ctx context.Context, filter core.ApprovalFilter) ([]core.ApprovalRecord, error) {
+		return []core.ApprovalRecord{
+			{MessageID: "item-1", State: core.ApprovalStatePending, Kind: core.ApprovalKindBulk, BatchID: filter.BatchID, CreatedAt: time.Now()},
+		}, nil
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/approvals?batch=batch-1", nil)
+	req.SetBasicAuth("admin", "secret")
+	rec := httptest.NewRecorder()
+	newAdminTestAPI(t, service).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `action="/admin/batches/batch-1/approve"`)
+	assert.Contains(t, rec.Body.String(), `value="batch-token-batch-1"`)
+	assert.Contains(t, rec.Body.String(), `name="message_id" value="item-1" form="batch-selected"`)
+
+	tests := []struct {
+		name             string
+		action           string
+		form             url.Values
+		expectedStatus   int
+		expectedLocation string
+		expectedIDs      []string
+	}{
+		{
+			name:             "approve selected",
+			action:           "approve",
+			form:             url.Values{"token": {"batch-token"}, "selected": {"true"}, "message_id": {"item-1", "item-2"}},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/admin/approvals?batch=batch-1&count=2&done=approved",
+			expectedIDs:      []string{"item-1", "item-2"},
+		},
+		{
+			name:             "reject all",
+			action:           "reject",
+			form:             url.Values{"token": {"batch-token"}, "reason": {"duplicate"}},
+			expectedStatus:   http.StatusSeeOther,
+			expectedLocation: "/admin/approvals?batch=batch-1&count=0&done=rejected",
+		},
+		{
+			name:           "nothing selected",
+			action:         "approve",
+			form:           url.Values{"token": {"batch-token"}, "selected": {"true"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			req := httptest.NewRequest(http.MethodPost, "/admin/batches/batch-1/"+tt.action, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth("admin", "secret")
+			rec := httptest.NewRecorder()
+			newAdminTestAPI(t, service).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus != http.StatusSeeOther {
+				assert.Nil(t, service.lastBatchRequest)
+				return
+			}
+			assert.Equal(t, tt.expectedLocation, rec.Header().Get("Location"))
+			require.NotNil(t, service.lastBatchRequest)
+			assert.Equal(t, "batch-token", service.lastBatchRequest.TokenRequest)
+			assert.Equal(t, "batch-1", service.lastBatchRequest.BatchID)
+			assert.Equal(t, tt.expectedIDs, service.lastBatchRequest.MessageIDs)
+			if tt.action == "reject" {
+				assert.Equal(t, core.RejectReasonDuplicate, service.lastBatchRequest.RejectReason)
+			}
+		})
+	}
+}
+
+func TestAPIAdminEditApprove(t *testing.T) {
+	service := newStubService()
+
+	form := url.Values{
+		"token":             {"token-1"},
+		"job_title":         {" Software Engineer "},
+		"company_name":      {"Test Company"},
+		"company_location":  {"Jakarta, Indonesia"},
+		"short_description": {"Build things"},
+		"relevant_tags":     {"go, backend,"},
+		"apply_url":         {"https://example.com/apply"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/admin/approvals/msg-1@example.com/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("admin", "secret")
+	rec := httptest.NewRecorder()
+	newAdminTestAPI(t, service).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	require.NotNil(t, service.lastEditRequest)
+	assert.Equal(t, "token-1", service.lastEditRequest.TokenRequest)
+	assert.Equal(t, "msg-1@example.com", service.lastEditRequest.MessageID)
+	assert.Equal(t, shcore.Vacancy{
+		JobTitle:         "Software Engineer",
+		CompanyName:      "Test Company",
+		CompanyLocation:  "Jakarta, Indonesia",
+		ShortDescription: "Build things",
+		RelevantTags:     []string{"go", "backend"},
+		ApplyURL:         "https://example.com/apply",
+	}, service.lastEditRequest.Vacancy)
+}
+
+func TestAPIAdminRevokeTokens(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		expectedStatus int
+		expectedToken  string
+	}{
+		{
+			name:           "revoke single token",
+			form:           url.Values{"token": {"token-1"}},
+			expectedStatus: http.StatusOK,
+			expectedToken:  "token-1",
+		},
+		{
+			name:           "revoke all tokens",
+			form:           url.Values{},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/tokens/revoke", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth("admin", "secret")
+			rec := httptest.NewRecorder()
+			newAdminTestAPI(t, service).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			require.NotNil(t, service.lastRevokeRequest)
+			assert.Equal(t, tt.expectedToken, service.lastRevokeRequest.TokenRequest)
+		})
+	}
+
+	// revoking requires admin credentials
+	service := newStubService()
+	req := httptest.NewRequest(http.MethodPost, "/admin/tokens/revoke", nil)
+	rec := httptest.NewRecorder()
+	newAdminTestAPI(t, service).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Nil(t, service.lastRevokeRequest)
+}
+
+func TestAPIAdminRejectCrossOrigin(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "cross-site form post revoking tokens",
+			path:           "/admin/tokens/revoke",
+			headers:        map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "cross-site form post revoking api key",
+			path:           "/admin/api-keys/manual-key/revoke",
+			headers:        map[string]string{"Sec-Fetch-Site": "same-site"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "other origin on older browser",
+			path:           "/admin/tokens/revoke",
+			headers:        map[string]string{"Origin": "https://evil.example"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "same origin",
+			path:           "/admin/tokens/revoke",
+			headers:        map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "same origin on older browser",
+			path:           "/admin/tokens/revoke",
+			headers:        map[string]string{"Origin": "http://example.com"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "non browser client",
+			path:           "/admin/api-keys/manual-key/revoke",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			service.revokeAPIKeyFunc = func(ctx context.Context, id string) error {
+				return nil
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.SetBasicAuth("admin", "secret")
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			newAdminTestAPI(t, service).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusForbidden {
+				assert.Nil(t, service.lastRevokeRequest)
+				assert.Empty(t, service.lastRevokedAPIKeyID)
+			}
+		})
+	}
+}
+
+func TestAPIAdminAPIKeys(t *testing.T) {
+	service := newStubService()
+	service.authenticateFunc = func(ctx context.Context, rawKey string) (*core.APIKey, error) {
+		switch rawKey {
+		case "admin-key.secret":
+			return &core.APIKey{ID: "admin-key", Scopes: []core.APIKeyScope{core.APIKeyScopeAdmin}}, nil
+		case "manual-key.secret":
+			return &core.APIKey{ID: "manual-key", Scopes: []core.APIKeyScope{core.APIKeyScopeManual}}, nil
+		}
+		return nil, core.ErrInvalidAPIKey
+	}
+	service.listAPIKeysFunc = func(ctx context.Context) ([]core.APIKey, error) {
+		return []core.APIKey{{ID: "admin-key", Name: "ops", SecretHash: "hash"}}, nil
+	}
+	service.revokeAPIKeyFunc = func(ctx context.Context, id string) error {
+		if id != "manual-key" {
+			return shcore.NewNotFoundError("api key not found")
+		}
+		return nil
+	}
+	handler := newAdminTestAPI(t, service)
+
+	// create key using basic auth
+	body := `{"name":"google form","scopes":["manual","url"],"default_submission_email":"form@idnremote.com"}`
+	req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", strings.NewReader(body))
+	req.SetBasicAuth("admin", "secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"key":"key-id.secret"`)
+	require.NotNil(t, service.lastCreateAPIKey)
+	assert.Equal(t, core.CreateAPIKeyRequest{
+		Name:                   "google form",
+		Scopes:                 []core.APIKeyScope{core.APIKeyScopeManual, core.APIKeyScopeURL},
+		DefaultSubmissionEmail: "form@idnremote.com",
+	}, *service.lastCreateAPIKey)
+
+	// list keys using admin api key, secret hash must not be exposed
+	req = httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+	req.Header.Set("X-Api-Key", "admin-key.secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"ops"`)
+	assert.NotContains(t, rec.Body.String(), "hash")
+
+	// revoke key
+	req = httptest.NewRequest(http.MethodPost, "/admin/api-keys/manual-key/revoke", nil)
+	req.Header.Set("X-Api-Key", "admin-key.secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "manual-key", service.lastRevokedAPIKeyID)
+
+	// revoke unknown key
+	req = httptest.NewRequest(http.MethodPost, "/admin/api-keys/unknown/revoke", nil)
+	req.Header.Set("X-Api-Key", "admin-key.secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// key without admin scope is forbidden
+	req = httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+	req.Header.Set("X-Api-Key", "manual-key.secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// legacy shared key is not an admin key
+	req = httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+	req.Header.Set("X-Api-Key", "test-api-key")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// dashboard pages still require basic auth
+	req = httptest.NewRequest(http.MethodGet, "/admin/approvals", nil)
+	req.Header.Set("X-Api-Key", "admin-key.secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -4,12 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
+
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 )
 
 type Error struct {
 	StatusCode int
 	Err        string
 	Message    string
+	// RetryAfter is sent as `Retry-After` header when it is set
+	RetryAfter time.Duration
+	// Fields lists the invalid fields of the request body
+	Fields []shcore.FieldError
 }
 
 func (e *Error) Error() string {
@@ -21,7 +28,10 @@ func (e *Error) Is(target error) bool {
 	if !errors.As(target, &restErr) {
 		return false
 	}
-	return *e == *restErr
+	return e.StatusCode == restErr.StatusCode &&
+		e.Err == restErr.Err &&
+		e.Message == restErr.Message &&
+		e.RetryAfter == restErr.RetryAfter
 }
 
 func NewInternalServerError(err error) *Error {
@@ -40,6 +50,39 @@ func NewBadRequestError(msg string) *Error {
 	}
 }
 
+func NewInvalidRequestBodyError(fields []shcore.FieldError) *Error {
+	return &Error{
+		StatusCode: http.StatusBadRequest,
+		Err:        "ERR_BAD_REQUEST",
+		Message:    "invalid request body",
+		Fields:     fields,
+	}
+}
+
+func NewNotFoundError(msg string) *Error {
+	return &Error{
+		StatusCode: http.StatusNotFound,
+		Err:        "ERR_NOT_FOUND",
+		Message:    msg,
+	}
+}
+
+func NewConflictError(msg string) *Error {
+	return &Error{
+		StatusCode: http.StatusConflict,
+		Err:        "ERR_CONFLICT",
+		Message:    msg,
+	}
+}
+
+func NewForbiddenError(msg string) *Error {
+	return &Error{
+		StatusCode: http.StatusForbidden,
+		Err:        "ERR_FORBIDDEN",
+		Message:    msg,
+	}
+}
+
 func NewInvalidAPIKeyError() *Error {
 	return &Error{
 		StatusCode: http.StatusUnauthorized,
@@ -47,3 +90,46 @@ func NewInvalidAPIKeyError() *Error {
 		Message:    "Invalid API key",
 	}
 }
+
+func NewIdempotencyKeyReusedError() *Error {
+	return &Error{
+		StatusCode: http.StatusUnprocessableEntity,
+		Err:        "ERR_IDEMPOTENCY_KEY_REUSED",
+		Message:    "idempotency key has been used for different request",
+	}
+}
+
+// NewServiceUnavailableError is used when the request can't be served
+// because upstream service is unavailable or rate limited.
+func NewServiceUnavailableError(errCode, msg string) *Error {
+	return &Error{
+		StatusCode: http.StatusServiceUnavailable,
+		Err:        errCode,
+		Message:    msg,
+	}
+}
+
+func NewBadGatewayError(errCode, msg string) *Error {
+	return &Error{
+		StatusCode: http.StatusBadGateway,
+		Err:        errCode,
+		Message:    msg,
+	}
+}
+
+func NewRequestTooLargeError(limit int64) *Error {
+	return &Error{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Err:        "ERR_REQUEST_TOO_LARGE",
+		Message:    fmt.Sprintf("request body must not exceed %d bytes", limit),
+	}
+}
+
+func NewTooManyRequestsError(retryAfter time.Duration) *Error {
+	return &Error{
+		StatusCode: http.StatusTooManyRequests,
+		Err:        "ERR_TOO_MANY_REQUESTS",
+		Message:    "too many requests, please retry later",
+		RetryAfter: retryAfter,
+	}
+}
@@ -0,0 +1,94 @@
+package driver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
+const (
+	headerIdempotencyKey     = "Idempotency-Key"
+	headerIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength  = 255
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+)
+
+// reserveIdempotencyKey reserves the `Idempotency-Key` of the submission,
+// it returns the reserved key along with the stored result when the same
+// request has been completed before. Empty key is returned when the client
+// doesn't send the header or idempotency is disabled.
+func (a *API) reserveIdempotencyKey(r *http.Request, key *core.APIKey, req shcore.SubmitRequest) (string, json.RawMessage, error) {
+	idemKey := r.Header.Get(headerIdempotencyKey)
+	if a.IdempotencyStorage == nil || idemKey == "" {
+		return "", nil, nil
+	}
+	if len(idemKey) > maxIdempotencyKeyLength {
+		return "", nil, NewBadRequestError(fmt.Sprintf("idempotency key must not be longer than %d characters", maxIdempotencyKeyLength))
+	}
+
+	// scope the key per client so different clients can't collide
+	idemKey = key.ID + ":" + idemKey
+	hash := sha256.Sum256(req.ToJSON())
+	requestHash := hex.EncodeToString(hash[:])
+
+	ttl := a.IdempotencyKeyTTL
+	if ttl == 0 {
+		ttl = defaultIdempotencyKeyTTL
+	}
+	rec, err := a.IdempotencyStorage.ReserveIdempotencyKey(r.Context(), idemKey, requestHash, ttl)
+	if err != nil {
+		return "", nil, NewInternalServerError(err)
+	}
+	if rec == nil {
+		return idemKey, nil, nil
+	}
+
+	if rec.RequestHash != requestHash {
+		return "", nil, NewIdempotencyKeyReusedError()
+	}
+	if !rec.IsCompleted() {
+		return "", nil, NewConflictError("request with the same idempotency key is still being processed")
+	}
+
+	return idemKey, json.RawMessage(rec.Result), nil
+}
+
+// completeIdempotencyKey stores the result for replaying, failure is only
+// logged since the submission itself has succeeded.
+func (a *API) completeIdempotencyKey(ctx context.Context, idemKey string, result *core.SubmitResult) {
+	if idemKey == "" {
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("failed to encode result of idempotency key %s: %v", idemKey, err)
+		return
+	}
+	err = a.IdempotencyStorage.CompleteIdempotencyKey(ctx, idemKey, data)
+	if err != nil {
+		log.Printf("failed to complete idempotency key %s: %v", idemKey, err)
+	}
+}
+
+// releaseIdempotencyKey allows failed submission to be retried with the
+// same key.
+func (a *API) releaseIdempotencyKey(ctx context.Context, idemKey string) {
+	if idemKey == "" {
+		return
+	}
+
+	err := a.IdempotencyStorage.ReleaseIdempotencyKey(ctx, idemKey)
+	if err != nil {
+		log.Printf("failed to release idempotency key %s: %v", idemKey, err)
+	}
+}
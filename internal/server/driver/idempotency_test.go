@@ -0,0 +1,152 @@
+package driver_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driver"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
+func TestAPISubmitVacancyIdempotencyKey(t *testing.T) {
+	service := newStubService()
+	calls := 0
+	service.handleRequestFunc = func(ctx context.Context, req shcore.SubmitRequest) (*core.SubmitResult, error) {
+		calls++
+		if req.ApplyURL == "https://example.com/failed" {
+			return nil, fmt.Errorf("failed to send approval request")
+		}
+		return &core.SubmitResult{SubmissionID: fmt.Sprintf("submission-%d", calls)}, nil
+	}
+	storage := newStubIdempotencyStorage()
+
+	api, err := driver.NewAPI(driver.APIConfig{
+		Service:            service,
+		ClientApiKey:       "test-api-key",
+		IdempotencyStorage: storage,
+	})
+	require.NoError(t, err)
+	handler := api.GetHandler()
+
+	submit := func(idemKey, applyURL string) (*httptest.ResponseRecorder, driver.RespBody) {
+		var body bytes.Buffer
+		err := json.NewEncoder(&body).Encode(shcore.SubmitRequest{
+			SubmissionType: shcore.SubmitTypeURL,
+			Vacancy:        shcore.Vacancy{ApplyURL: applyURL},
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/vacancies", &body)
+		req.Header.Set("X-Api-Key", "test-api-key")
+		if idemKey != "" {
+			req.Header.Set("Idempotency-Key", idemKey)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var respBody driver.RespBody
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &respBody))
+		return rec, respBody
+	}
+
+	// first request is processed
+	rec, respBody := submit("key-1", "https://example.com/apply")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, map[string]interface{}{"submission_id": "submission-1"}, respBody.Data)
+
+	// identical replay returns the original result without processing again
+	rec, respBody = submit("key-1", "https://example.com/apply")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, map[string]interface{}{"submission_id": "submission-1"}, respBody.Data)
+	assert.Equal(t, 1, calls)
+
+	// reusing the key for different request is rejected
+	rec, respBody = submit("key-1", "https://example.com/other")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "ERR_IDEMPOTENCY_KEY_REUSED", respBody.Err)
+	assert.Equal(t, 1, calls)
+
+	// request without key is always processed
+	rec, _ = submit("", "https://example.com/apply")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, calls)
+
+	// failed request can be retried with the same key
+	rec, _ = submit("key-2", "https://example.com/failed")
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	rec, _ = submit("key-2", "https://example.com/failed")
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, 4, calls)
+
+	// request still being processed is rejected
+	hash := sha256.Sum256(shcore.SubmitRequest{
+		SubmissionType: shcore.SubmitTypeURL,
+		Vacancy:        shcore.Vacancy{ApplyURL: "https://example.com/apply"},
+	}.ToJSON())
+	_, err = storage.ReserveIdempotencyKey(context.Background(), "legacy:key-3", hex.EncodeToString(hash[:]), time.Hour)
+	require.NoError(t, err)
+	rec, respBody = submit("key-3", "https://example.com/apply")
+	require.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "ERR_CONFLICT", respBody.Err)
+
+	// key is limited in length
+	rec, respBody = submit(strings.Repeat("a", 256), "https://example.com/apply")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "ERR_BAD_REQUEST", respBody.Err)
+	assert.Equal(t, 4, calls)
+}
+
+type stubIdempotencyStorage struct {
+	mu      sync.Mutex
+	records map[string]*core.IdempotencyRecord
+}
+
+func newStubIdempotencyStorage() *stubIdempotencyStorage {
+	return &stubIdempotencyStorage{records: map[string]*core.IdempotencyRecord{}}
+}
+
+func (s *stubIdempotencyStorage) ReserveIdempotencyKey(ctx context.Context, key string, requestHash string, ttl time.Duration) (*core.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok {
+		copied := *rec
+		return &copied, nil
+	}
+	s.records[key] = &core.IdempotencyRecord{Key: key, RequestHash: requestHash, CreatedAt: time.Now()}
+	return nil, nil
+}
+
+func (s *stubIdempotencyStorage) CompleteIdempotencyKey(ctx context.Context, key string, result []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key].Result = result
+	return nil
+}
+
+func (s *stubIdempotencyStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok && !rec.IsCompleted() {
+		delete(s.records, key)
+	}
+	return nil
+}
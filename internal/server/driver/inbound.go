@@ -0,0 +1,227 @@
+package driver
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"github.com/go-chi/render"
+)
+
+const (
+	headerInboundSecret = "X-Inbound-Secret"
+
+	maxInboundEmailSize = 1 << 20
+)
+
+func (a *API) isInboundEmailEnabled() bool {
+	return a.InboundEmailSecret != ""
+}
+
+// serveInboundEmail receives the raw reply of admin to approval email from
+// the inbound mail provider, then decides on the approval following the
+// command in the reply.
+func (a *API) serveInboundEmail(w http.ResponseWriter, r *http.Request) {
+	if !a.isInboundEmailEnabled() {
+		render.Render(w, r, NewErrorResp(NewNotFoundError("inbound email is disabled")))
+		return
+	}
+	secret := r.Header.Get(headerInboundSecret)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(a.InboundEmailSecret)) != 1 {
+		render.Render(w, r, NewErrorResp(NewForbiddenError("invalid inbound secret")))
+		return
+	}
+
+	reply, err := parseEmailReply(http.MaxBytesReader(w, r.Body, maxInboundEmailSize), a.InboundEmailAuthServID)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	err = a.Service.HandleEmailReply(r.Context(), *reply)
+	if err != nil {
+		if errors.Is(err, core.ErrSenderNotAdmin) || errors.Is(err, core.ErrSenderNotVerified) {
+			err = NewForbiddenError(err.Error())
+		}
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	// return the success response
+	render.Render(w, r, NewSuccessResp(nil))
+}
+
+// parseEmailReply parses the raw RFC 5322 message, only the plain text
+// part of the body is used since it is where the command is written. The
+// sender is verified using the `Authentication-Results` headers added by
+// the inbound mail provider identified by authServID.
+func parseEmailReply(r io.Reader, authServID string) (*core.EmailReply, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, NewBadRequestError(fmt.Sprintf("invalid email message: %v", err))
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return nil, NewBadRequestError(fmt.Sprintf("invalid sender address: %v", err))
+	}
+
+	body, err := readPlainTextBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, NewBadRequestError(fmt.Sprintf("invalid email body: %v", err))
+	}
+
+	// some clients put several ids in the header, the replied one comes first
+	var inReplyTo string
+	if ids := strings.Fields(msg.Header.Get("In-Reply-To")); len(ids) > 0 {
+		inReplyTo = ids[0]
+	}
+
+	return &core.EmailReply{
+		From:           from.Address,
+		InReplyTo:      inReplyTo,
+		Body:           body,
+		SenderVerified: isSenderAuthenticated(msg.Header["Authentication-Results"], authServID, from.Address),
+	}, nil
+}
+
+// isSenderAuthenticated returns true when one of the RFC 8601 results added
+// by authServID passes DMARC, DKIM or SPF for the domain of the from
+// address. The results added by other hosts are ignored since anyone can
+// write them, the provider is expected to remove the forged results which
+// use its authserv-id.
+func isSenderAuthenticated(results []string, authServID, from string) bool {
+	domain := addressDomain(from)
+	if authServID == "" || domain == "" {
+		return false
+	}
+
+	for _, res := range results {
+		id, resInfos, ok := strings.Cut(stripHeaderComments(res), ";")
+		if !ok {
+			continue
+		}
+		// the authserv-id may be followed by version
+		if fields := strings.Fields(id); len(fields) == 0 || !strings.EqualFold(fields[0], authServID) {
+			continue
+		}
+
+		for _, resInfo := range strings.Split(resInfos, ";") {
+			fields := strings.Fields(resInfo)
+			if len(fields) == 0 {
+				continue
+			}
+			method, result, _ := strings.Cut(fields[0], "=")
+			if !strings.EqualFold(result, "pass") {
+				continue
+			}
+			props := make(map[string]string, len(fields)-1)
+			for _, f := range fields[1:] {
+				if k, v, ok := strings.Cut(f, "="); ok {
+					props[strings.ToLower(k)] = strings.Trim(v, `"`)
+				}
+			}
+
+			var passedDomain string
+			switch strings.ToLower(method) {
+			case "dmarc":
+				passedDomain = props["header.from"]
+			case "dkim":
+				passedDomain = props["header.d"]
+			case "spf":
+				passedDomain = addressDomain(props["smtp.mailfrom"])
+			}
+			if passedDomain != "" && strings.EqualFold(passedDomain, domain) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// addressDomain returns the domain part of the address, the address itself
+// is returned when it has no local part.
+func addressDomain(addr string) string {
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		return addr[i+1:]
+	}
+	return addr
+}
+
+// stripHeaderComments removes the parenthesized comments of the header
+// value, e.g `dkim=pass (2048-bit key)`.
+func stripHeaderComments(value string) string {
+	var (
+		sb    strings.Builder
+		depth int
+	)
+	for _, c := range value {
+		switch {
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// readPlainTextBody returns the first text/plain part of the body, nested
+// multipart body is walked depth first.
+func readPlainTextBody(contentType, transferEncoding string, body io.Reader) (string, error) {
+	mediaType := "text/plain"
+	var params map[string]string
+	if contentType != "" {
+		var err error
+		mediaType, params, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return "", errors.New("missing plain text part")
+			}
+			if err != nil {
+				return "", err
+			}
+			// quoted-printable part is decoded by NextPart
+			text, err := readPlainTextBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err == nil {
+				return text, nil
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return "", fmt.Errorf("unexpected content type %s", mediaType)
+	}
+
+	switch strings.ToLower(transferEncoding) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
@@ -0,0 +1,195 @@
+package driver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driver"
+)
+
+func TestAPIServeInboundEmail(t *testing.T) {
+	plainReply := strings.Join([]string{
+		"Authentication-Results: mx.idnremote.com; spf=pass smtp.mailfrom=admin@example.com;",
+		" dkim=pass (2048-bit key) header.d=example.com header.s=s1; dmarc=pass header.from=example.com",
+		"From: Admin <admin@example.com>",
+		"To: IDN Remote Entry <entry@idnremote.com>",
+		"Subject: Re: IDNRemote.com - New Job Vacancy Approval - ID: abc",
+		"In-Reply-To: <abc@idnremote.com>",
+		"References: <abc@idnremote.com>",
+		"",
+		"reject not_remote",
+		"",
+		"> Hello Admin!",
+	}, "\r\n")
+
+	multipartReply := strings.Join([]string{
+		"Authentication-Results: mx.idnremote.com 1; dkim=pass header.d=example.com",
+		"From: admin@example.com",
+		"In-Reply-To: <batch-1@bulk> <abc@idnremote.com>",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/alternative; boundary="b1"`,
+		"",
+		"--b1",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"Approve=0A=0AOn Mon, 1 Jan 2025 IDN Remote Entry wrote:",
+		"--b1",
+		"Content-Type: text/html; charset=UTF-8",
+		"",
+		"<div>Approve</div>",
+		"--b1--",
+	}, "\r\n")
+
+	// the results are written by the sender instead of the provider
+	spoofedReply := strings.Join([]string{
+		"Authentication-Results: mx.attacker.com; dmarc=pass header.from=example.com",
+		"Authentication-Results: mx.idnremote.com; spf=pass smtp.mailfrom=attacker.com; dkim=pass header.d=attacker.com; dmarc=fail header.from=example.com",
+		"From: admin@example.com",
+		"In-Reply-To: <abc@idnremote.com>",
+		"",
+		"approve",
+	}, "\r\n")
+
+	tests := []struct {
+		name              string
+		secret            string
+		body              string
+		serviceErr        error
+		expectedStatus    int
+		expectedErrorCode string
+		expectedReply     *core.EmailReply
+	}{
+		{
+			name:           "plain text reply",
+			secret:         "inbound-secret",
+			body:           plainReply,
+			expectedStatus: http.StatusOK,
+			expectedReply: &core.EmailReply{
+				From:           "admin@example.com",
+				InReplyTo:      "<abc@idnremote.com>",
+				Body:           "reject not_remote\n\n> Hello Admin!",
+				SenderVerified: true,
+			},
+		},
+		{
+			name:           "multipart reply",
+			secret:         "inbound-secret",
+			body:           multipartReply,
+			expectedStatus: http.StatusOK,
+			expectedReply: &core.EmailReply{
+				From:           "admin@example.com",
+				InReplyTo:      "<batch-1@bulk>",
+				Body:           "Approve\n\nOn Mon, 1 Jan 2025 IDN Remote Entry wrote:",
+				SenderVerified: true,
+			},
+		},
+		{
+			name:           "spoofed sender",
+			secret:         "inbound-secret",
+			body:           spoofedReply,
+			expectedStatus: http.StatusOK,
+			expectedReply: &core.EmailReply{
+				From:      "admin@example.com",
+				InReplyTo: "<abc@idnremote.com>",
+				Body:      "approve",
+			},
+		},
+		{
+			name:              "invalid secret",
+			secret:            "wrong-secret",
+			body:              plainReply,
+			expectedStatus:    http.StatusForbidden,
+			expectedErrorCode: "ERR_FORBIDDEN",
+		},
+		{
+			name:              "invalid message",
+			secret:            "inbound-secret",
+			body:              "approve",
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorCode: "ERR_BAD_REQUEST",
+		},
+		{
+			name:              "sender is not admin",
+			secret:            "inbound-secret",
+			body:              plainReply,
+			serviceErr:        core.ErrSenderNotAdmin,
+			expectedStatus:    http.StatusForbidden,
+			expectedErrorCode: "ERR_FORBIDDEN",
+		},
+		{
+			name:              "sender is not verified",
+			secret:            "inbound-secret",
+			body:              spoofedReply,
+			serviceErr:        core.ErrSenderNotVerified,
+			expectedStatus:    http.StatusForbidden,
+			expectedErrorCode: "ERR_FORBIDDEN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			service.emailReplyFunc = func(ctx context.Context, reply core.EmailReply) error {
+				return tt.serviceErr
+			}
+			api, err := driver.NewAPI(driver.APIConfig{
+				Service:                service,
+				ClientApiKey:           "test-api-key",
+				InboundEmailSecret:     "inbound-secret",
+				InboundEmailAuthServID: "mx.idnremote.com",
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/inbound/email", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "message/rfc822")
+			req.Header.Set("X-Inbound-Secret", tt.secret)
+			w := httptest.NewRecorder()
+			api.GetHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			var respBody driver.RespBody
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+			if tt.expectedErrorCode != "" {
+				assert.Equal(t, tt.expectedErrorCode, respBody.Err)
+				return
+			}
+
+			assert.True(t, respBody.OK)
+			assert.Equal(t, tt.expectedReply, service.lastEmailReply)
+		})
+	}
+
+	t.Run("missing authserv-id", func(t *testing.T) {
+		_, err := driver.NewAPI(driver.APIConfig{
+			Service:            newStubService(),
+			ClientApiKey:       "test-api-key",
+			InboundEmailSecret: "inbound-secret",
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		service := newStubService()
+		api, err := driver.NewAPI(driver.APIConfig{
+			Service:      service,
+			ClientApiKey: "test-api-key",
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/inbound/email", strings.NewReader(plainReply))
+		w := httptest.NewRecorder()
+		api.GetHandler().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Nil(t, service.lastEmailReply)
+	})
+}
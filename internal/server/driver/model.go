@@ -1,7 +1,10 @@
 package driver
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
@@ -9,15 +12,22 @@ import (
 )
 
 type RespBody struct {
-	StatusCode int         `json:"-"`
-	OK         bool        `json:"ok"`
-	Data       interface{} `json:"data,omitempty"`
-	Err        string      `json:"err,omitempty"`
-	Message    string      `json:"msg,omitempty"`
-	Timestamp  int64       `json:"ts"`
+	StatusCode int           `json:"-"`
+	RetryAfter time.Duration `json:"-"`
+	OK         bool          `json:"ok"`
+	Data       interface{}   `json:"data,omitempty"`
+	Err        string        `json:"err,omitempty"`
+	Message    string        `json:"msg,omitempty"`
+	// Fields lists the invalid fields of the request body
+	Fields    []core.FieldError `json:"fields,omitempty"`
+	Timestamp int64             `json:"ts"`
 }
 
 func (rb *RespBody) Render(w http.ResponseWriter, r *http.Request) error {
+	if rb.RetryAfter > 0 {
+		// round up so client won't retry too early
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rb.RetryAfter.Seconds()))))
+	}
 	render.Status(r, rb.StatusCode)
 	rb.Timestamp = time.Now().Unix()
 	return nil
@@ -32,25 +42,40 @@ func NewSuccessResp(data interface{}) *RespBody {
 }
 
 func NewErrorResp(err error) *RespBody {
-	var restErr *Error
-	switch v := err.(type) {
-	case *Error:
-		restErr = v
-	case *core.Error:
-		switch v.ErrCode {
+	// the error may be wrapped on its way up, e.g by the resolver when
+	// parsing the vacancy failed
+	var (
+		restErr *Error
+		coreErr *core.Error
+	)
+	switch {
+	case errors.As(err, &restErr):
+	case errors.As(err, &coreErr):
+		switch coreErr.ErrCode {
 		case core.ErrCodeBadRequest:
-			restErr = NewBadRequestError(v.Message)
+			restErr = NewBadRequestError(coreErr.Message)
+		case core.ErrCodeNotFound:
+			restErr = NewNotFoundError(coreErr.Message)
+		case core.ErrCodeConflict:
+			restErr = NewConflictError(coreErr.Message)
+		case core.ErrCodeUpstreamRateLimited, core.ErrCodeUpstreamUnavailable:
+			restErr = NewServiceUnavailableError(coreErr.ErrCode, coreErr.Message)
+		case core.ErrCodeUpstreamRejected:
+			restErr = NewBadGatewayError(coreErr.ErrCode, coreErr.Message)
 		default:
-			restErr = NewInternalServerError(v)
+			restErr = NewInternalServerError(coreErr)
 		}
+		restErr.Fields = coreErr.Fields
 	default:
 		restErr = NewInternalServerError(err)
 	}
 
 	return &RespBody{
 		StatusCode: restErr.StatusCode,
+		RetryAfter: restErr.RetryAfter,
 		OK:         false,
 		Err:        restErr.Err,
 		Message:    restErr.Message,
+		Fields:     restErr.Fields,
 	}
 }
@@ -0,0 +1,90 @@
+package driver_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driver"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
+func TestNewErrorResp(t *testing.T) {
+	fields := []shcore.FieldError{{Field: "apply_url", JSONPath: "apply_url", Rule: "required", Message: "apply_url is required"}}
+	badRequest := shcore.NewBadRequestError("invalid vacancy")
+	badRequest.Fields = fields
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedErr    string
+		expectedMsg    string
+		expectedFields []shcore.FieldError
+	}{
+		{
+			name:           "rest error",
+			err:            driver.NewNotFoundError("submission not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedErr:    "ERR_NOT_FOUND",
+			expectedMsg:    "submission not found",
+		},
+		{
+			name:           "wrapped rest error",
+			err:            fmt.Errorf("failed to check api key: %w", driver.NewForbiddenError("api key is revoked")),
+			expectedStatus: http.StatusForbidden,
+			expectedErr:    "ERR_FORBIDDEN",
+			expectedMsg:    "api key is revoked",
+		},
+		{
+			name:           "core error",
+			err:            badRequest,
+			expectedStatus: http.StatusBadRequest,
+			expectedErr:    "ERR_BAD_REQUEST",
+			expectedMsg:    "invalid vacancy",
+			expectedFields: fields,
+		},
+		{
+			name:           "wrapped core error",
+			err:            fmt.Errorf("failed to parse the vacancy: %w", badRequest),
+			expectedStatus: http.StatusBadRequest,
+			expectedErr:    "ERR_BAD_REQUEST",
+			expectedMsg:    "invalid vacancy",
+			expectedFields: fields,
+		},
+		{
+			name:           "wrapped upstream error",
+			err:            fmt.Errorf("failed to parse the vacancy: %w", shcore.NewUpstreamRateLimitedError(errors.New("openai is busy"))),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedErr:    shcore.ErrCodeUpstreamRateLimited,
+			expectedMsg:    "openai is busy",
+		},
+		{
+			name:           "upstream rejected",
+			err:            shcore.NewUpstreamError(http.StatusUnauthorized, errors.New("invalid openai key")),
+			expectedStatus: http.StatusBadGateway,
+			expectedErr:    shcore.ErrCodeUpstreamRejected,
+			expectedMsg:    "invalid openai key",
+		},
+		{
+			name:           "unknown error",
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedErr:    "ERR_INTERNAL_ERROR",
+			expectedMsg:    "connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := driver.NewErrorResp(tt.err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tt.expectedErr, resp.Err)
+			assert.Equal(t, tt.expectedMsg, resp.Message)
+			assert.Equal(t, tt.expectedFields, resp.Fields)
+		})
+	}
+}
@@ -0,0 +1,516 @@
+package driver
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/go-chi/render"
+	"github.com/invopop/jsonschema"
+)
+
+const (
+	securityAPIKey    = "apiKey"
+	securityBasicAuth = "basicAuth"
+	securityInbound   = "inboundSecret"
+)
+
+type responseKind int
+
+const (
+	responseJSON responseKind = iota
+	responseHTML
+	responseText
+	responseRedirect
+	// responseRawJSON is json response without RespBody envelope
+	responseRawJSON
+)
+
+// apiOperation describes a route registered in GetHandler, both the openapi
+// document & the request body validation are generated from it so they
+// can't drift from each other.
+type apiOperation struct {
+	Method  string
+	Pattern string
+	Summary string
+	// Admin marks the operation which is only served when admin dashboard
+	// is enabled
+	Admin bool
+	// Security lists the accepted authentication, any of them is enough,
+	// empty means the operation is public
+	Security []string
+	// Params lists the query & header parameters, the path parameters are
+	// taken from Pattern
+	Params []openAPIParameter
+	// Body is the value the json request body is decoded into, nil means
+	// the operation doesn't accept json body
+	Body interface{}
+	// Required lists the required top level fields of Body
+	Required []string
+	// Form lists the fields of form encoded request body
+	Form []string
+	// RawBody is the content type of request body which is read as it is
+	RawBody string
+	// Result is the data of the success response, nil means the response
+	// has no data
+	Result   interface{}
+	Response responseKind
+
+	bodySchema *jsonschema.Schema
+}
+
+// apiOperations lists every route registered in GetHandler.
+var apiOperations = initAPIOperations([]apiOperation{
+	{
+		Method:   http.MethodGet,
+		Pattern:  "/",
+		Summary:  "Check whether the server is running",
+		Response: responseText,
+	},
+	{
+		Method:   http.MethodGet,
+		Pattern:  "/openapi.json",
+		Summary:  "Get this OpenAPI document",
+		Response: responseRawJSON,
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/vacancies",
+		Summary:  "Submit vacancy",
+		Security: []string{securityAPIKey},
+		Params: []openAPIParameter{
+			{Name: headerIdempotencyKey, In: "header", Description: "Replays the result of the previous request sent with the same key"},
+		},
+		Body:     shcore.SubmitRequest{},
+		Required: []string{"submission_type"},
+		Result:   core.SubmitResult{},
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/vacancies/preview",
+		Summary:  "Resolve vacancy from apply url without submitting it",
+		Security: []string{securityAPIKey},
+		Body:     core.PreviewRequest{},
+		Required: []string{"apply_url"},
+		Result:   core.PreviewResult{},
+	},
+	{
+		Method:   http.MethodGet,
+		Pattern:  "/vacancies/{id}",
+		Summary:  "Get vacancy submission",
+		Security: []string{securityAPIKey},
+		Result:   shcore.Submission{},
+	},
+	{
+		Method:  http.MethodGet,
+		Pattern: "/vacancies/approve",
+		Summary: "Approve vacancy using the token sent in approval email",
+		Params:  approvalTokenParams,
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/vacancies/approve/edit",
+		Summary:  "Correct then approve vacancy using the token sent in approval email",
+		Params:   approvalTokenParams,
+		Body:     shcore.Vacancy{},
+		Required: []string{"job_title", "company_name", "apply_url"},
+	},
+	{
+		Method:  http.MethodGet,
+		Pattern: "/vacancies/reject",
+		Summary: "Reject vacancy using the token sent in approval email",
+		Params: append(approvalTokenParams, openAPIParameter{
+			Name: "reason", In: "query", Description: "Either one of predefined reasons or free text",
+		}),
+	},
+	{
+		Method:  http.MethodGet,
+		Pattern: "/vacancies/batch/approve",
+		Summary: "Approve every pending vacancy of bulk approval email, or only the selected ones",
+		Params:  batchTokenParams,
+		Result:  core.BatchApprovalResult{},
+	},
+	{
+		Method:  http.MethodGet,
+		Pattern: "/vacancies/batch/approve/selected",
+		Summary: "Approve the vacancies selected in bulk approval email, at least one message_id is required",
+		Params:  batchTokenParams,
+		Result:  core.BatchApprovalResult{},
+	},
+	{
+		Method:  http.MethodGet,
+		Pattern: "/vacancies/batch/reject",
+		Summary: "Reject every pending vacancy of bulk approval email, or only the selected ones",
+		Params: append(batchTokenParams, openAPIParameter{
+			Name: "reason", In: "query", Description: "Either one of predefined reasons or free text",
+		}),
+		Result: core.BatchApprovalResult{},
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/inbound/email",
+		Summary:  "Approve or reject vacancy following admin reply to approval email, forwarded by inbound mail provider",
+		Security: []string{securityInbound},
+		RawBody:  "message/rfc822",
+	},
+	{
+		Method:   http.MethodGet,
+		Pattern:  "/admin/",
+		Summary:  "Redirect to approval list page",
+		Admin:    true,
+		Security: []string{securityBasicAuth},
+		Response: responseRedirect,
+	},
+	{
+		Method:   http.MethodGet,
+		Pattern:  "/admin/approvals",
+		Summary:  "Show pending approval list page",
+		Admin:    true,
+		Security: []string{securityBasicAuth},
+		Params: []openAPIParameter{
+			{Name: "submitter", In: "query"},
+			{Name: "type", In: "query"},
+			{Name: "batch", In: "query", Description: "Batch id of bulk approval email"},
+			{Name: "min_age", In: "query", Description: "Go duration, e.g 24h"},
+			{Name: "max_age", In: "query", Description: "Go duration, e.g 24h"},
+		},
+		Response: responseHTML,
+	},
+	{
+		Method:   http.MethodGet,
+		Pattern:  "/admin/approvals/{message_id}",
+		Summary:  "Show approval detail page",
+		Admin:    true,
+		Security: []string{securityBasicAuth},
+		Response: responseHTML,
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/admin/approvals/{message_id}/approve",
+		Summary:  "Approve vacancy from dashboard",
+		Admin:    true,
+		Security: []string{securityBasicAuth},
+		Form:     []string{"token"},
+		Response: responseRedirect,
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/admin/approvals/{message_id}/reject",
+		Summary:  "Reject vacancy from dashboard",
+		Admin:    true,
+		Security: []string{securityBasicAuth},
+		Form:     []string{"token", "reason", "reason_text"},
+		Response: responseRedirect,
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/admin/approvals/{message_id}/edit",
+		Summary:  "Correct then approve vacancy from dashboard",
+		Admin:    true,
+		Security: []string{securityBasicAuth},
+		Form: []string{
+			"token", "job_title", "company_name", "company_location",
+			"short_description", "relevant_tags", "apply_url",
+		},
+		Response: responseRedirect,
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/admin/batches/{batch_id}/approve",
+		Summary:  "Approve every pending vacancy of bulk batch, or only the selected ones, from dashboard",
+		Admin:    true,
+		Security: []string{securityBasicAuth},
+		Form:     []string{"token", "message_id", "selected"},
+		Response: responseRedirect,
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/admin/batches/{batch_id}/reject",
+		Summary:  "Reject every pending vacancy of bulk batch, or only the selected ones, from dashboard",
+		Admin:    true,
+		Security: []string{securityBasicAuth},
+		Form:     []string{"token", "message_id", "reason", "reason_text"},
+		Response: responseRedirect,
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/admin/tokens/revoke",
+		Summary:  "Revoke the given approval token or every outstanding token",
+		Admin:    true,
+		Security: []string{securityBasicAuth, securityAPIKey},
+		Form:     []string{"token"},
+	},
+	{
+		Method:   http.MethodGet,
+		Pattern:  "/admin/api-keys",
+		Summary:  "List api keys",
+		Admin:    true,
+		Security: []string{securityBasicAuth, securityAPIKey},
+		Result:   []core.APIKey{},
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/admin/api-keys",
+		Summary:  "Create api key",
+		Admin:    true,
+		Security: []string{securityBasicAuth, securityAPIKey},
+		Body:     core.CreateAPIKeyRequest{},
+		Required: []string{"name", "scopes"},
+		Result:   core.CreateAPIKeyResult{},
+	},
+	{
+		Method:   http.MethodPost,
+		Pattern:  "/admin/api-keys/{id}/revoke",
+		Summary:  "Revoke api key",
+		Admin:    true,
+		Security: []string{securityBasicAuth, securityAPIKey},
+	},
+})
+
+var approvalTokenParams = []openAPIParameter{
+	{Name: "data", In: "query", Required: true, Description: "Approval token"},
+	{Name: "message_id", In: "query", Description: "Message id of the approval email"},
+}
+
+var batchTokenParams = []openAPIParameter{
+	{Name: "data", In: "query", Required: true, Description: "Batch token"},
+	{Name: "batch_id", In: "query", Required: true, Description: "Batch id of the bulk approval email"},
+	{Name: "message_id", In: "query", Description: "Message id of the selected vacancy, can be repeated"},
+}
+
+func initAPIOperations(ops []apiOperation) []apiOperation {
+	for i := range ops {
+		if ops[i].Body != nil {
+			ops[i].bodySchema = reflectSchema(ops[i].Body)
+			ops[i].bodySchema.Required = ops[i].Required
+		}
+	}
+	return ops
+}
+
+// findAPIOperation returns nil when the route is not documented.
+func findAPIOperation(method, pattern string) *apiOperation {
+	for i := range apiOperations {
+		if apiOperations[i].Method == method && apiOperations[i].Pattern == pattern {
+			return &apiOperations[i]
+		}
+	}
+	return nil
+}
+
+var schemaReflector = &jsonschema.Reflector{
+	Anonymous:      true,
+	DoNotReference: true,
+	// fields are only required when the operation says so since the same
+	// struct is used by different operations
+	RequiredFromJSONSchemaTags: true,
+	Mapper:                     mapEnumSchema,
+}
+
+func reflectSchema(v interface{}) *jsonschema.Schema {
+	s := schemaReflector.Reflect(v)
+	s.Version = ""
+	return s
+}
+
+func mapEnumSchema(t reflect.Type) *jsonschema.Schema {
+	switch t {
+	case reflect.TypeOf(shcore.SubmitType("")):
+		return enumSchema(shcore.SubmitTypeManual, shcore.SubmitTypeURL, shcore.SubmitTypeBulk)
+	case reflect.TypeOf(core.APIKeyScope("")):
+		return enumSchema(core.APIKeyScopes...)
+	}
+	return nil
+}
+
+func enumSchema[T ~string](values ...T) *jsonschema.Schema {
+	s := &jsonschema.Schema{Type: "string"}
+	for _, v := range values {
+		s.Enum = append(s.Enum, string(v))
+	}
+	return s
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	Summary     string                     `json:"summary"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string             `json:"name"`
+	In          string             `json:"in"`
+	Description string             `json:"description,omitempty"`
+	Required    bool               `json:"required,omitempty"`
+	Schema      *jsonschema.Schema `json:"schema,omitempty"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonschema.Schema `json:"schema,omitempty"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIComponents struct {
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// newOpenAPIDocument generates the document from apiOperations, admin
+// operations are only included when withAdmin is true.
+func newOpenAPIDocument(withAdmin bool) *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:   "IDN Remote Entry API",
+			Version: "1.0.0",
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				securityAPIKey:    {Type: "apiKey", In: "header", Name: "X-Api-Key"},
+				securityBasicAuth: {Type: "http", Scheme: "basic"},
+				securityInbound:   {Type: "apiKey", In: "header", Name: headerInboundSecret},
+			},
+		},
+	}
+
+	for _, op := range apiOperations {
+		if op.Admin && !withAdmin {
+			continue
+		}
+		if doc.Paths[op.Pattern] == nil {
+			doc.Paths[op.Pattern] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[op.Pattern][strings.ToLower(op.Method)] = newOpenAPIOperation(op)
+	}
+
+	return doc
+}
+
+func newOpenAPIOperation(op apiOperation) *openAPIOperation {
+	oop := &openAPIOperation{
+		Summary:   op.Summary,
+		Responses: map[string]openAPIResponse{},
+	}
+	for _, sec := range op.Security {
+		oop.Security = append(oop.Security, map[string][]string{sec: {}})
+	}
+
+	stringSchema := &jsonschema.Schema{Type: "string"}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.Pattern, -1) {
+		oop.Parameters = append(oop.Parameters, openAPIParameter{
+			Name: match[1], In: "path", Required: true, Schema: stringSchema,
+		})
+	}
+	for _, param := range op.Params {
+		param.Schema = stringSchema
+		oop.Parameters = append(oop.Parameters, param)
+	}
+
+	switch {
+	case op.bodySchema != nil:
+		oop.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  map[string]openAPIMediaType{"application/json": {Schema: op.bodySchema}},
+		}
+	case len(op.Form) > 0:
+		form := &jsonschema.Schema{Type: "object", Properties: jsonschema.NewProperties()}
+		for _, field := range op.Form {
+			form.Properties.Set(field, stringSchema)
+		}
+		oop.RequestBody = &openAPIRequestBody{
+			Content: map[string]openAPIMediaType{"application/x-www-form-urlencoded": {Schema: form}},
+		}
+	case op.RawBody != "":
+		oop.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  map[string]openAPIMediaType{op.RawBody: {Schema: stringSchema}},
+		}
+	}
+
+	switch op.Response {
+	case responseText:
+		oop.Responses["200"] = openAPIResponse{
+			Description: "OK",
+			Content:     map[string]openAPIMediaType{"text/plain": {Schema: stringSchema}},
+		}
+	case responseHTML:
+		oop.Responses["200"] = openAPIResponse{
+			Description: "OK",
+			Content:     map[string]openAPIMediaType{"text/html": {Schema: stringSchema}},
+		}
+	case responseRedirect:
+		oop.Responses["302"] = openAPIResponse{Description: "Redirect to approval list page"}
+		oop.Responses["303"] = openAPIResponse{Description: "Redirect to approval list page"}
+	case responseRawJSON:
+		oop.Responses["200"] = openAPIResponse{
+			Description: "OK",
+			Content:     map[string]openAPIMediaType{"application/json": {Schema: &jsonschema.Schema{Type: "object"}}},
+		}
+	default:
+		var data *jsonschema.Schema
+		if op.Result != nil {
+			data = reflectSchema(op.Result)
+		}
+		oop.Responses["200"] = openAPIResponse{
+			Description: "OK",
+			Content:     map[string]openAPIMediaType{"application/json": {Schema: respBodySchema(data)}},
+		}
+	}
+	oop.Responses["default"] = openAPIResponse{
+		Description: "Error",
+		Content:     map[string]openAPIMediaType{"application/json": {Schema: respBodySchema(nil)}},
+	}
+
+	return oop
+}
+
+// respBodySchema returns the schema of RespBody with the given data, nil
+// data removes the data field.
+func respBodySchema(data *jsonschema.Schema) *jsonschema.Schema {
+	s := reflectSchema(RespBody{})
+	if data == nil {
+		s.Properties.Delete("data")
+	} else {
+		s.Properties.Set("data", data)
+	}
+	return s
+}
+
+func (a *API) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, a.openAPI)
+}
@@ -0,0 +1,147 @@
+package driver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driver"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
+func TestAPIServeOpenAPI(t *testing.T) {
+	getDocument := func(t *testing.T, handler http.Handler) map[string]map[string]interface{} {
+		req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var doc struct {
+			OpenAPI string                            `json:"openapi"`
+			Paths   map[string]map[string]interface{} `json:"paths"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+		require.True(t, strings.HasPrefix(doc.OpenAPI, "3."))
+		return doc.Paths
+	}
+
+	// every registered route must be documented
+	handler := newAdminTestAPI(t, newStubService())
+	paths := getDocument(t, handler)
+	routeCount := 0
+	err := chi.Walk(handler.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routeCount++
+		assert.Contains(t, paths[route], strings.ToLower(method), "route %s %s is not documented", method, route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	documentedCount := 0
+	for _, ops := range paths {
+		documentedCount += len(ops)
+	}
+	assert.Equal(t, routeCount, documentedCount, "documented route is not registered")
+
+	// admin routes are not documented when the dashboard is disabled
+	api, err := driver.NewAPI(driver.APIConfig{
+		Service:      newStubService(),
+		ClientApiKey: "test-api-key",
+	})
+	require.NoError(t, err)
+	paths = getDocument(t, api.GetHandler())
+	assert.Contains(t, paths, "/vacancies")
+	for path := range paths {
+		assert.False(t, strings.HasPrefix(path, "/admin"), "admin path %s is documented", path)
+	}
+}
+
+func TestAPIValidateRequestBody(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedFields []shcore.FieldError
+	}{
+		{
+			name: "unknown field",
+			path: "/vacancies",
+			body: `{"submission_type":"url","apply_url":"https://example.com/apply","salary":"1000"}`,
+			expectedFields: []shcore.FieldError{
+				{Field: "salary", JSONPath: "salary", Rule: "additional_properties", Message: "`salary` is not allowed"},
+			},
+		},
+		{
+			name: "internal field",
+			path: "/vacancies",
+			body: `{"submission_type":"url","apply_url":"https://example.com/apply","retries":5}`,
+			expectedFields: []shcore.FieldError{
+				{Field: "retries", JSONPath: "retries", Rule: "additional_properties", Message: "`retries` is not allowed"},
+			},
+		},
+		{
+			name: "missing submission type",
+			path: "/vacancies",
+			body: `{"apply_url":"https://example.com/apply"}`,
+			expectedFields: []shcore.FieldError{
+				{Field: "submission_type", JSONPath: "submission_type", Rule: "required", Message: "`submission_type` is required"},
+			},
+		},
+		{
+			name: "unknown submission type",
+			path: "/vacancies",
+			body: `{"submission_type":"fax","apply_url":"https://example.com/apply"}`,
+			expectedFields: []shcore.FieldError{
+				{Field: "submission_type", JSONPath: "submission_type", Rule: "enum", Message: "`submission_type` must be one of manual, url, bulk"},
+			},
+		},
+		{
+			name: "malformed bulk vacancies",
+			path: "/vacancies",
+			body: `{"submission_type":"bulk","bulk_vacancies":[{"apply_url":"https://example.com/1"},{"apply_url":1,"relevant_tags":["go",2]}]}`,
+			expectedFields: []shcore.FieldError{
+				{Field: "apply_url", JSONPath: "bulk_vacancies[1].apply_url", Rule: "type", Message: "`bulk_vacancies[1].apply_url` must be a string"},
+				{Field: "relevant_tags", JSONPath: "bulk_vacancies[1].relevant_tags[1]", Rule: "type", Message: "`bulk_vacancies[1].relevant_tags[1]` must be a string"},
+			},
+		},
+		{
+			name: "body is not an object",
+			path: "/vacancies/preview",
+			body: `["https://example.com/apply"]`,
+			expectedFields: []shcore.FieldError{
+				{Rule: "type", Message: "value must be an object"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			api, err := driver.NewAPI(driver.APIConfig{
+				Service:      service,
+				ClientApiKey: "test-api-key",
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-Api-Key", "test-api-key")
+			rec := httptest.NewRecorder()
+			api.GetHandler().ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var respBody driver.RespBody
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &respBody))
+			assert.Equal(t, "ERR_BAD_REQUEST", respBody.Err)
+			assert.Equal(t, tt.expectedFields, respBody.Fields)
+
+			// the invalid request must not reach the service
+			assert.Nil(t, service.lastSubmitRequest)
+			assert.Nil(t, service.lastPreviewRequest)
+		})
+	}
+}
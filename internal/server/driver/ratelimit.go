@@ -0,0 +1,116 @@
+package driver
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
+// RateLimits configures the limits of vacancy submission, zero value
+// limit disables the limit.
+type RateLimits struct {
+	PerAPIKey          core.RateLimit
+	PerSubmissionEmail core.RateLimit
+	PerIP              core.RateLimit
+	// BulkItemsPerAPIKey limits the vacancies of bulk submissions, every
+	// vacancy in the bulk takes a token on top of the submission itself
+	BulkItemsPerAPIKey core.RateLimit
+	// PreviewPerAPIKey & PreviewPerIP limit vacancy preview, it has its
+	// own buckets so previewing doesn't use up the submission limits
+	PreviewPerAPIKey core.RateLimit
+	PreviewPerIP     core.RateLimit
+}
+
+type rateLimitBucket struct {
+	key   string
+	limit core.RateLimit
+	// cost is the number of tokens taken from the bucket, default to 1
+	cost int
+}
+
+// checkSubmitRateLimit takes the tokens from every bucket the submission
+// belongs to, it returns too many requests error when one of them doesn't
+// have enough tokens.
+func (a *API) checkSubmitRateLimit(r *http.Request, key *core.APIKey, req shcore.SubmitRequest) error {
+	buckets := []rateLimitBucket{
+		{key: "ip:" + a.clientIP(r), limit: a.RateLimits.PerIP},
+		{key: "key:" + key.ID, limit: a.RateLimits.PerAPIKey},
+	}
+	if req.SubmissionEmail != "" {
+		buckets = append(buckets, rateLimitBucket{
+			key:   "email:" + strings.ToLower(req.SubmissionEmail),
+			limit: a.RateLimits.PerSubmissionEmail,
+		})
+	}
+	if req.SubmissionType == shcore.SubmitTypeBulk {
+		buckets = append(buckets, rateLimitBucket{
+			key:   "bulk:" + key.ID,
+			limit: a.RateLimits.BulkItemsPerAPIKey,
+			cost:  len(req.BulkVacancies),
+		})
+	}
+
+	return a.checkRateLimit(r, buckets)
+}
+
+// checkPreviewRateLimit takes a token from the preview buckets of the api
+// key & client ip.
+func (a *API) checkPreviewRateLimit(r *http.Request, key *core.APIKey) error {
+	return a.checkRateLimit(r, []rateLimitBucket{
+		{key: "preview-ip:" + a.clientIP(r), limit: a.RateLimits.PreviewPerIP},
+		{key: "preview-key:" + key.ID, limit: a.RateLimits.PreviewPerAPIKey},
+	})
+}
+
+func (a *API) checkRateLimit(r *http.Request, buckets []rateLimitBucket) error {
+	if a.RateLimiter == nil {
+		return nil
+	}
+
+	for _, b := range buckets {
+		if !b.limit.IsEnabled() {
+			continue
+		}
+		cost := max(b.cost, 1)
+		if cost > b.limit.Limit {
+			// the bucket never holds enough tokens, so retrying won't help
+			return NewBadRequestError(fmt.Sprintf("request must not have more than %d vacancies", b.limit.Limit))
+		}
+		allowed, retryAfter, err := a.RateLimiter.Take(r.Context(), b.key, b.limit, cost)
+		if err != nil {
+			// don't block submission when the limiter is unavailable
+			log.Printf("failed to check rate limit of %s: %v", b.key, err)
+			continue
+		}
+		if !allowed {
+			return NewTooManyRequestsError(retryAfter)
+		}
+	}
+
+	return nil
+}
+
+// clientIP returns the ip of the client, proxy headers are only used when
+// the server is configured to trust them.
+func (a *API) clientIP(r *http.Request) string {
+	if a.TrustProxyHeaders {
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return strings.TrimSpace(ip)
+		}
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(ip)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
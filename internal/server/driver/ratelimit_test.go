@@ -0,0 +1,187 @@
+package driver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driven/ratelimit/memory"
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driver"
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
+func TestAPISubmitVacancyRateLimit(t *testing.T) {
+	type submission struct {
+		email        string
+		remoteAddr   string
+		forwardedFor string
+		// bulkItems makes the submission a bulk of the given vacancies
+		bulkItems int
+		// preview makes the call to vacancy preview instead
+		preview        bool
+		expectedStatus int
+	}
+
+	tests := []struct {
+		name              string
+		rateLimits        driver.RateLimits
+		trustProxyHeaders bool
+		submissions       []submission
+	}{
+		{
+			name:       "per ip",
+			rateLimits: driver.RateLimits{PerIP: core.RateLimit{Limit: 1, Period: time.Hour}},
+			submissions: []submission{
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusOK},
+				{email: "b@example.com", remoteAddr: "10.0.0.1:5678", expectedStatus: http.StatusTooManyRequests},
+				{email: "a@example.com", remoteAddr: "10.0.0.2:1234", expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name:       "per ip ignores untrusted proxy headers",
+			rateLimits: driver.RateLimits{PerIP: core.RateLimit{Limit: 1, Period: time.Hour}},
+			submissions: []submission{
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", forwardedFor: "1.1.1.1", expectedStatus: http.StatusOK},
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", forwardedFor: "2.2.2.2", expectedStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:              "per ip using trusted proxy headers",
+			rateLimits:        driver.RateLimits{PerIP: core.RateLimit{Limit: 1, Period: time.Hour}},
+			trustProxyHeaders: true,
+			submissions: []submission{
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", forwardedFor: "1.1.1.1, 10.0.0.1", expectedStatus: http.StatusOK},
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", forwardedFor: "2.2.2.2", expectedStatus: http.StatusOK},
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", forwardedFor: "1.1.1.1", expectedStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:       "per submission email",
+			rateLimits: driver.RateLimits{PerSubmissionEmail: core.RateLimit{Limit: 1, Period: time.Hour}},
+			submissions: []submission{
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusOK},
+				{email: "A@example.com", remoteAddr: "10.0.0.2:1234", expectedStatus: http.StatusTooManyRequests},
+				{email: "b@example.com", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name:       "per api key",
+			rateLimits: driver.RateLimits{PerAPIKey: core.RateLimit{Limit: 2, Period: time.Hour}},
+			submissions: []submission{
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusOK},
+				{email: "b@example.com", remoteAddr: "10.0.0.2:1234", expectedStatus: http.StatusOK},
+				{email: "c@example.com", remoteAddr: "10.0.0.3:1234", expectedStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:       "bulk charged by vacancies",
+			rateLimits: driver.RateLimits{BulkItemsPerAPIKey: core.RateLimit{Limit: 3, Period: time.Hour}},
+			submissions: []submission{
+				{remoteAddr: "10.0.0.1:1234", bulkItems: 2, expectedStatus: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1234", bulkItems: 2, expectedStatus: http.StatusTooManyRequests},
+				{remoteAddr: "10.0.0.1:1234", bulkItems: 1, expectedStatus: http.StatusOK},
+				// single submission is not counted as bulk vacancy
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name:       "bulk exceeding the limit",
+			rateLimits: driver.RateLimits{BulkItemsPerAPIKey: core.RateLimit{Limit: 3, Period: time.Hour}},
+			submissions: []submission{
+				{remoteAddr: "10.0.0.1:1234", bulkItems: 4, expectedStatus: http.StatusBadRequest},
+				{remoteAddr: "10.0.0.1:1234", bulkItems: 3, expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name: "preview has its own buckets",
+			rateLimits: driver.RateLimits{
+				PerIP:        core.RateLimit{Limit: 1, Period: time.Hour},
+				PreviewPerIP: core.RateLimit{Limit: 1, Period: time.Hour},
+			},
+			submissions: []submission{
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1234", preview: true, expectedStatus: http.StatusOK},
+				{remoteAddr: "10.0.0.1:1234", preview: true, expectedStatus: http.StatusTooManyRequests},
+				{email: "a@example.com", remoteAddr: "10.0.0.1:1234", expectedStatus: http.StatusTooManyRequests},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, err := driver.NewAPI(driver.APIConfig{
+				Service:           newStubService(),
+				ClientApiKey:      "test-api-key",
+				RateLimiter:       memory.NewMemoryLimiter(),
+				RateLimits:        tt.rateLimits,
+				TrustProxyHeaders: tt.trustProxyHeaders,
+			})
+			require.NoError(t, err)
+			handler := api.GetHandler()
+
+			for _, sub := range tt.submissions {
+				path := "/vacancies"
+				var reqBody interface{} = shcore.SubmitRequest{
+					SubmissionType:  shcore.SubmitTypeURL,
+					SubmissionEmail: sub.email,
+					Vacancy:         shcore.Vacancy{ApplyURL: "https://example.com/apply"},
+				}
+				switch {
+				case sub.preview:
+					path = "/vacancies/preview"
+					reqBody = core.PreviewRequest{ApplyURL: "https://example.com/apply"}
+				case sub.bulkItems > 0:
+					bulkReq := shcore.SubmitRequest{SubmissionType: shcore.SubmitTypeBulk}
+					for i := 0; i < sub.bulkItems; i++ {
+						bulkReq.BulkVacancies = append(bulkReq.BulkVacancies, shcore.BulkVacancy{
+							SubmissionType: shcore.SubmitTypeURL,
+							Vacancy:        shcore.Vacancy{ApplyURL: fmt.Sprintf("https://example.com/apply/%d", i)},
+						})
+					}
+					reqBody = bulkReq
+				}
+
+				var body bytes.Buffer
+				err := json.NewEncoder(&body).Encode(reqBody)
+				require.NoError(t, err)
+
+				req := httptest.NewRequest(http.MethodPost, path, &body)
+				req.RemoteAddr = sub.remoteAddr
+				req.Header.Set("X-Api-Key", "test-api-key")
+				if sub.forwardedFor != "" {
+					req.Header.Set("X-Forwarded-For", sub.forwardedFor)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				assert.Equal(t, sub.expectedStatus, rec.Code)
+				if sub.expectedStatus == http.StatusTooManyRequests {
+					assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+					var respBody driver.RespBody
+					require.NoError(t, json.NewDecoder(rec.Body).Decode(&respBody))
+					assert.Equal(t, "ERR_TOO_MANY_REQUESTS", respBody.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestRespBodyRetryAfter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	resp := driver.NewErrorResp(driver.NewTooManyRequestsError(1500 * time.Millisecond))
+	require.NoError(t, resp.Render(rec, req))
+
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
@@ -1,20 +1,74 @@
 package driver
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"strings"
 
 	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/go-chi/chi/v5"
 )
 
+// maxRequestBodySize is the size limit of json request body, it is large
+// enough for bulk submission of a crawl.
+const maxRequestBodySize = 1 << 20
+
+// decodeRequestBody validates the json request body against the schema of
+// the route in the openapi document, then decodes it into dst.
+func decodeRequestBody(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	pattern := chi.RouteContext(r.Context()).RoutePattern()
+	op := findAPIOperation(r.Method, pattern)
+	if op == nil || op.bodySchema == nil {
+		return NewInternalServerError(fmt.Errorf("missing request body schema of %s %s", r.Method, pattern))
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return NewRequestTooLargeError(maxBytesErr.Limit)
+		}
+		return NewBadRequestError(err.Error())
+	}
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err = dec.Decode(&value)
+	if err != nil {
+		return NewBadRequestError(err.Error())
+	}
+
+	fieldErrs := validateSchema(op.bodySchema, value, "", "")
+	if len(fieldErrs) > 0 {
+		return NewInvalidRequestBodyError(fieldErrs)
+	}
+
+	err = json.Unmarshal(data, dst)
+	if err != nil {
+		return NewBadRequestError(err.Error())
+	}
+	return nil
+}
+
 func prepareSubmitVacancyRequest(req shcore.SubmitRequest) shcore.SubmitRequest {
+	req.Vacancy = prepareVacancy(req.Vacancy)
+
+	return req
+}
+
+func prepareVacancy(v shcore.Vacancy) shcore.Vacancy {
 	relevantTags := []string{}
-	for _, tag := range req.RelevantTags {
+	for _, tag := range v.RelevantTags {
 		tag = strings.TrimSpace(tag)
 		if tag != "" {
 			relevantTags = append(relevantTags, tag)
 		}
 	}
-	req.RelevantTags = relevantTags
+	v.RelevantTags = relevantTags
 
-	return req
+	return v
 }
@@ -1,9 +1,11 @@
 package driver
 
 import (
-	"encoding/json"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
 	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
@@ -14,13 +16,51 @@ import (
 	"gopkg.in/validator.v2"
 )
 
+// legacyAPIKey is the key returned on authenticating with ClientApiKey.
+var legacyAPIKey = core.APIKey{
+	ID:     "legacy",
+	Name:   "legacy",
+	Scopes: []core.APIKeyScope{core.APIKeyScopeManual, core.APIKeyScopeURL, core.APIKeyScopeBulk},
+}
+
 type API struct {
 	APIConfig
+
+	openAPI *openAPIDocument
 }
 
 type APIConfig struct {
-	Service      core.Service `validate:"nonnil"`
-	ClientApiKey string       `validate:"nonzero"`
+	Service core.Service `validate:"nonnil"`
+	// ClientApiKey is the legacy shared key, it is granted every submission
+	// scope except admin, leave it empty once every client has its own key
+	ClientApiKey string
+
+	// AdminUsername & AdminPassword are the basic auth credentials for
+	// the admin dashboard, the dashboard is disabled when they are empty
+	AdminUsername string
+	AdminPassword string
+
+	// RateLimiter throttles vacancy submission using RateLimits, the
+	// submission is not throttled when it is nil
+	RateLimiter core.RateLimiter
+	RateLimits  RateLimits
+	// TrustProxyHeaders makes client ip taken from `X-Real-IP` or
+	// `X-Forwarded-For` header, only enable it behind trusted proxy
+	TrustProxyHeaders bool
+
+	// IdempotencyStorage stores the result of vacancy submission sent with
+	// `Idempotency-Key` header, the header is ignored when it is nil
+	IdempotencyStorage core.IdempotencyStorage
+	// IdempotencyKeyTTL is how long the result is replayed, default to 24h
+	IdempotencyKeyTTL time.Duration
+
+	// InboundEmailSecret authenticates the inbound mail provider forwarding
+	// admin replies, replying approval email is disabled when it is empty
+	InboundEmailSecret string
+	// InboundEmailAuthServID is the authserv-id of `Authentication-Results`
+	// header added by the inbound mail provider, only the results of this
+	// id are trusted for verifying the sender of admin replies
+	InboundEmailAuthServID string
 }
 
 func NewAPI(cfg APIConfig) (*API, error) {
@@ -28,7 +68,15 @@ func NewAPI(cfg APIConfig) (*API, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid API config: %w", err)
 	}
-	return &API{APIConfig: cfg}, nil
+	if (cfg.AdminUsername == "") != (cfg.AdminPassword == "") {
+		return nil, fmt.Errorf("invalid API config: admin username & password must be set together")
+	}
+	if cfg.InboundEmailSecret != "" && cfg.InboundEmailAuthServID == "" {
+		return nil, fmt.Errorf("invalid API config: inbound email authserv-id must be set along with inbound email secret")
+	}
+	a := &API{APIConfig: cfg}
+	a.openAPI = newOpenAPIDocument(a.isAdminEnabled())
+	return a, nil
 }
 
 func (a *API) GetHandler() http.Handler {
@@ -41,9 +89,21 @@ func (a *API) GetHandler() http.Handler {
 	r.Use(render.SetContentType(render.ContentTypeJSON))
 
 	r.Get("/", a.serveIndex)
+	r.Get("/openapi.json", a.serveOpenAPI)
 	r.Post("/vacancies", a.serveSubmitVacancy)
+	r.Post("/vacancies/preview", a.servePreviewVacancy)
+	r.Get("/vacancies/{id}", a.serveGetVacancySubmission)
 	r.Get("/vacancies/approve", a.serveApproveVacancy)
+	r.Post("/vacancies/approve/edit", a.serveEditApproveVacancy)
 	r.Get("/vacancies/reject", a.serveRejectVacancy)
+	r.Get("/vacancies/batch/approve", a.serveApproveVacancyBatch)
+	r.Get("/vacancies/batch/approve/selected", a.serveApproveSelectedVacancyBatch)
+	r.Get("/vacancies/batch/reject", a.serveRejectVacancyBatch)
+	r.Post("/inbound/email", a.serveInboundEmail)
+
+	if a.isAdminEnabled() {
+		r.Mount("/admin", a.adminRouter())
+	}
 
 	return r
 }
@@ -54,30 +114,128 @@ func (a *API) serveIndex(w http.ResponseWriter, r *http.Request) {
 
 func (a *API) serveSubmitVacancy(w http.ResponseWriter, r *http.Request) {
 	// validate the API key
-	val := r.Header.Get("X-Api-Key")
-	if val != a.ClientApiKey {
-		render.Render(w, r, NewErrorResp(NewInvalidAPIKeyError()))
+	key, err := a.authenticateAPIKey(r)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
 		return
 	}
 
 	// decode the request
 	var req shcore.SubmitRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err = decodeRequestBody(w, r, &req)
 	if err != nil {
-		render.Render(w, r, NewErrorResp(NewBadRequestError(err.Error())))
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	// make sure the key is allowed to submit this kind of vacancy
+	if !key.HasScope(core.SubmitScope(req.SubmissionType)) {
+		msg := fmt.Sprintf("api key is not allowed to submit %s vacancy", core.SubmitScope(req.SubmissionType))
+		render.Render(w, r, NewErrorResp(NewForbiddenError(msg)))
+		return
+	}
+	if req.SubmissionEmail == "" {
+		req.SubmissionEmail = key.DefaultSubmissionEmail
+	}
+	req.APIKeyID = key.ID
+
+	// replay the result when the client retries the same request
+	idemKey, replayResult, err := a.reserveIdempotencyKey(r, key, req)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+	if replayResult != nil {
+		w.Header().Set(headerIdempotentReplayed, "true")
+		render.Render(w, r, NewSuccessResp(replayResult))
+		return
+	}
+
+	// throttle the submission since it triggers parser & email sending
+	err = a.checkSubmitRateLimit(r, key, req)
+	if err != nil {
+		a.releaseIdempotencyKey(r.Context(), idemKey)
+		render.Render(w, r, NewErrorResp(err))
 		return
 	}
 
 	// handle the request
 	req = prepareSubmitVacancyRequest(req)
-	err = a.Service.HandleRequest(r.Context(), req)
+	result, err := a.Service.HandleRequest(r.Context(), req)
 	if err != nil {
+		a.releaseIdempotencyKey(r.Context(), idemKey)
 		render.Render(w, r, NewErrorResp(err))
 		return
 	}
+	a.completeIdempotencyKey(r.Context(), idemKey, result)
 
 	// return the success response
-	render.Render(w, r, NewSuccessResp(nil))
+	render.Render(w, r, NewSuccessResp(result))
+}
+
+func (a *API) servePreviewVacancy(w http.ResponseWriter, r *http.Request) {
+	// validate the API key
+	key, err := a.authenticateAPIKey(r)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	// preview runs the same parser as url vacancy submission
+	if !key.HasScope(core.APIKeyScopeURL) {
+		msg := fmt.Sprintf("api key is not allowed to preview %s vacancy", core.APIKeyScopeURL)
+		render.Render(w, r, NewErrorResp(NewForbiddenError(msg)))
+		return
+	}
+
+	// decode the request
+	var req core.PreviewRequest
+	err = decodeRequestBody(w, r, &req)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	// throttle the preview since the parser is as expensive as submission
+	err = a.checkPreviewRateLimit(r, key)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	result, err := a.Service.PreviewVacancy(r.Context(), req)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	// return the success response
+	render.Render(w, r, NewSuccessResp(result))
+}
+
+func (a *API) serveGetVacancySubmission(w http.ResponseWriter, r *http.Request) {
+	// validate the API key
+	key, err := a.authenticateAPIKey(r)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	sub, err := a.Service.GetSubmission(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	// the submission is only visible to the key which submitted it, it is
+	// reported as not found otherwise so the ids can't be probed
+	if sub.APIKeyID != key.ID && !key.HasScope(core.APIKeyScopeAdmin) {
+		render.Render(w, r, NewErrorResp(NewNotFoundError("submission not found")))
+		return
+	}
+
+	// return the success response
+	render.Render(w, r, NewSuccessResp(sub))
 }
 
 func (a *API) serveApproveVacancy(w http.ResponseWriter, r *http.Request) {
@@ -101,6 +259,35 @@ func (a *API) serveApproveVacancy(w http.ResponseWriter, r *http.Request) {
 	render.Render(w, r, NewSuccessResp(nil))
 }
 
+func (a *API) serveEditApproveVacancy(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("data")
+	if token == "" {
+		render.Render(w, r, NewErrorResp(NewBadRequestError("token is required")))
+		return
+	}
+
+	// decode the corrected vacancy
+	var vacancy shcore.Vacancy
+	err := decodeRequestBody(w, r, &vacancy)
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	err = a.Service.HandleEditApprove(r.Context(), core.EditApprovalRequest{
+		TokenRequest: token,
+		MessageID:    r.URL.Query().Get("message_id"),
+		Vacancy:      prepareVacancy(vacancy),
+	})
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	// return the success response
+	render.Render(w, r, NewSuccessResp(nil))
+}
+
 func (a *API) serveRejectVacancy(w http.ResponseWriter, r *http.Request) {
 	token := r.URL.Query().Get("data")
 	if token == "" {
@@ -112,6 +299,7 @@ func (a *API) serveRejectVacancy(w http.ResponseWriter, r *http.Request) {
 	err := a.Service.HandleReject(r.Context(), core.ApprovalRequest{
 		TokenRequest: token,
 		MessageID:    messageID,
+		RejectReason: core.RejectReason(r.URL.Query().Get("reason")),
 	})
 	if err != nil {
 		render.Render(w, r, NewErrorResp(err))
@@ -121,3 +309,96 @@ func (a *API) serveRejectVacancy(w http.ResponseWriter, r *http.Request) {
 	// return the success response
 	render.Render(w, r, NewSuccessResp(nil))
 }
+
+func (a *API) serveApproveVacancyBatch(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("data")
+	if token == "" {
+		render.Render(w, r, NewErrorResp(NewBadRequestError("token is required")))
+		return
+	}
+
+	result, err := a.Service.HandleApproveBatch(r.Context(), core.BatchApprovalRequest{
+		TokenRequest: token,
+		BatchID:      r.URL.Query().Get("batch_id"),
+		MessageIDs:   r.URL.Query()["message_id"],
+	})
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	render.Render(w, r, NewSuccessResp(result))
+}
+
+// serveApproveSelectedVacancyBatch approves only the checked items of the
+// bulk approval email, unlike serveApproveVacancyBatch the selection is
+// required so submitting the form without checking anything approves
+// nothing.
+func (a *API) serveApproveSelectedVacancyBatch(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("data")
+	if token == "" {
+		render.Render(w, r, NewErrorResp(NewBadRequestError("token is required")))
+		return
+	}
+	messageIDs := r.URL.Query()["message_id"]
+	if len(messageIDs) == 0 {
+		render.Render(w, r, NewErrorResp(NewBadRequestError("no approval is selected")))
+		return
+	}
+
+	result, err := a.Service.HandleApproveBatch(r.Context(), core.BatchApprovalRequest{
+		TokenRequest: token,
+		BatchID:      r.URL.Query().Get("batch_id"),
+		MessageIDs:   messageIDs,
+	})
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	render.Render(w, r, NewSuccessResp(result))
+}
+
+func (a *API) serveRejectVacancyBatch(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("data")
+	if token == "" {
+		render.Render(w, r, NewErrorResp(NewBadRequestError("token is required")))
+		return
+	}
+
+	result, err := a.Service.HandleRejectBatch(r.Context(), core.BatchApprovalRequest{
+		TokenRequest: token,
+		BatchID:      r.URL.Query().Get("batch_id"),
+		MessageIDs:   r.URL.Query()["message_id"],
+		RejectReason: core.RejectReason(r.URL.Query().Get("reason")),
+	})
+	if err != nil {
+		render.Render(w, r, NewErrorResp(err))
+		return
+	}
+
+	render.Render(w, r, NewSuccessResp(result))
+}
+
+// authenticateAPIKey returns the key submitted in `X-Api-Key` header,
+// invalid key results in invalid api key error.
+func (a *API) authenticateAPIKey(r *http.Request) (*core.APIKey, error) {
+	rawKey := r.Header.Get("X-Api-Key")
+	if rawKey == "" {
+		return nil, NewInvalidAPIKeyError()
+	}
+	if a.ClientApiKey != "" && subtle.ConstantTimeCompare([]byte(rawKey), []byte(a.ClientApiKey)) == 1 {
+		key := legacyAPIKey
+		return &key, nil
+	}
+
+	key, err := a.Service.AuthenticateAPIKey(r.Context(), rawKey)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidAPIKey) {
+			return nil, NewInvalidAPIKeyError()
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -109,17 +111,17 @@ func TestAPIServeSubmitVacancy(t *testing.T) {
 			requestBody: shcore.SubmitRequest{
 				SubmissionType:  shcore.SubmitTypeBulk,
 				SubmissionEmail: "crawler@system.com",
-				BulkVacancies: []shcore.Vacancy{
-					{
+				BulkVacancies: []shcore.BulkVacancy{
+					{Vacancy: shcore.Vacancy{
 						JobTitle:    "Frontend Developer",
 						CompanyName: "Company A",
 						ApplyURL:    "https://example.com/jobs/1",
-					},
-					{
+					}},
+					{Vacancy: shcore.Vacancy{
 						JobTitle:    "Backend Engineer",
 						CompanyName: "Company B",
 						ApplyURL:    "https://example.com/jobs/2",
-					},
+					}},
 				},
 			},
 			serviceResponse: nil,
@@ -144,6 +146,43 @@ func TestAPIServeSubmitVacancy(t *testing.T) {
 			expectedErrorCode: "ERR_INVALID_API_KEY",
 			validateParams:    nil, // No validation needed as service won't be called
 		},
+		{
+			name:   "success with scoped api key using default submitter",
+			apiKey: "url-key-id.secret",
+			requestBody: shcore.SubmitRequest{
+				SubmissionType: shcore.SubmitTypeURL,
+				Vacancy: shcore.Vacancy{
+					ApplyURL: "https://jobs.company.com/123",
+				},
+			},
+			serviceResponse: nil,
+			expectedStatus:  http.StatusOK,
+			validateParams: func(t *testing.T, req *shcore.SubmitRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, "form@idnremote.com", req.SubmissionEmail)
+				// the submission is owned by the key
+				assert.Equal(t, "url-key-id", req.APIKeyID)
+			},
+		},
+		{
+			name:   "api key without required scope",
+			apiKey: "url-key-id.secret",
+			requestBody: shcore.SubmitRequest{
+				SubmissionType:  shcore.SubmitTypeBulk,
+				SubmissionEmail: "crawler",
+				BulkVacancies: []shcore.BulkVacancy{
+					{Vacancy: shcore.Vacancy{
+						JobTitle:    "Frontend Developer",
+						CompanyName: "Company A",
+						ApplyURL:    "https://example.com/jobs/1",
+					}},
+				},
+			},
+			serviceResponse:   nil,
+			expectedStatus:    http.StatusForbidden,
+			expectedErrorCode: "ERR_FORBIDDEN",
+			validateParams:    nil, // No validation needed as service won't be called
+		},
 		{
 			name:              "invalid request body",
 			apiKey:            "test-api-key",
@@ -153,6 +192,15 @@ func TestAPIServeSubmitVacancy(t *testing.T) {
 			expectedErrorCode: "ERR_BAD_REQUEST",
 			validateParams:    nil, // No validation needed as service won't be called
 		},
+		{
+			name:              "request body too large",
+			apiKey:            "test-api-key",
+			requestBody:       `{"submission_type":"manual","short_description":"` + strings.Repeat("a", 1<<20) + `"}`,
+			serviceResponse:   nil,
+			expectedStatus:    http.StatusRequestEntityTooLarge,
+			expectedErrorCode: "ERR_REQUEST_TOO_LARGE",
+			validateParams:    nil, // No validation needed as service won't be called
+		},
 		{
 			name:   "service returns error",
 			apiKey: "test-api-key",
@@ -169,14 +217,39 @@ func TestAPIServeSubmitVacancy(t *testing.T) {
 				assert.Equal(t, "https://example.com/apply", req.Vacancy.ApplyURL)
 			},
 		},
+		{
+			name:   "duplicate vacancy",
+			apiKey: "test-api-key",
+			requestBody: shcore.SubmitRequest{
+				SubmissionType: shcore.SubmitTypeURL,
+				Vacancy:        shcore.Vacancy{ApplyURL: "https://example.com/apply"},
+			},
+			serviceResponse:   shcore.NewConflictError("vacancy has already been posted at https://idnremote.com/jobs/page-id"),
+			expectedStatus:    http.StatusConflict,
+			expectedErrorCode: "ERR_CONFLICT",
+			validateParams:    nil,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create stub service with desired response
 			service := newStubService()
-			service.handleRequestFunc = func(ctx context.Context, req shcore.SubmitRequest) error {
-				return tt.serviceResponse
+			service.handleRequestFunc = func(ctx context.Context, req shcore.SubmitRequest) (*core.SubmitResult, error) {
+				if tt.serviceResponse != nil {
+					return nil, tt.serviceResponse
+				}
+				return &core.SubmitResult{SubmissionID: "submission-123"}, nil
+			}
+			service.authenticateFunc = func(ctx context.Context, rawKey string) (*core.APIKey, error) {
+				if rawKey != "url-key-id.secret" {
+					return nil, core.ErrInvalidAPIKey
+				}
+				return &core.APIKey{
+					ID:                     "url-key-id",
+					Scopes:                 []core.APIKeyScope{core.APIKeyScopeURL},
+					DefaultSubmissionEmail: "form@idnremote.com",
+				}, nil
 			}
 
 			api, err := driver.NewAPI(driver.APIConfig{
@@ -219,6 +292,7 @@ func TestAPIServeSubmitVacancy(t *testing.T) {
 				assert.False(t, respBody.OK)
 			} else {
 				assert.True(t, respBody.OK)
+				assert.Equal(t, map[string]interface{}{"submission_id": "submission-123"}, respBody.Data)
 			}
 
 			// Validate the parameters passed to the service
@@ -229,6 +303,259 @@ func TestAPIServeSubmitVacancy(t *testing.T) {
 	}
 }
 
+func TestAPIServeSubmitVacancyInvalidVacancy(t *testing.T) {
+	service := newStubService()
+	service.handleRequestFunc = func(ctx context.Context, req shcore.SubmitRequest) (*core.SubmitResult, error) {
+		return nil, shcore.NewValidationError(req.Validate())
+	}
+	api, err := driver.NewAPI(driver.APIConfig{
+		Service:      service,
+		ClientApiKey: "test-api-key",
+	})
+	require.NoError(t, err)
+
+	body := `{"submission_type":"bulk","bulk_vacancies":[{"submission_type":"manual","job_title":"Backend Engineer","company_name":"Company A","apply_url":"https://example.com/jobs/1"},{"submission_type":"manual","job_title":"Frontend Engineer","company_name":"Company B"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/vacancies", strings.NewReader(body))
+	req.Header.Set("X-Api-Key", "test-api-key")
+	rec := httptest.NewRecorder()
+	api.GetHandler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var respBody driver.RespBody
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &respBody))
+	assert.Equal(t, "ERR_BAD_REQUEST", respBody.Err)
+	assert.Equal(t, "invalid request: `bulk_vacancies[1].apply_url` is required", respBody.Message)
+	assert.Equal(t, []shcore.FieldError{
+		{Field: "apply_url", JSONPath: "bulk_vacancies[1].apply_url", Rule: "nonzero", Message: "`bulk_vacancies[1].apply_url` is required"},
+	}, respBody.Fields)
+}
+
+func TestAPIServeGetVacancySubmission(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiKey            string
+		submissionID      string
+		ownerKeyID        string
+		serviceResponse   error
+		expectedStatus    int
+		expectedErrorCode string
+	}{
+		{
+			name:           "success",
+			apiKey:         "test-api-key",
+			submissionID:   "submission-123",
+			ownerKeyID:     "legacy",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "submission of another api key",
+			apiKey:            "test-api-key",
+			submissionID:      "submission-123",
+			ownerKeyID:        "other-key-id",
+			expectedStatus:    http.StatusNotFound,
+			expectedErrorCode: "ERR_NOT_FOUND",
+		},
+		{
+			name:           "admin key can see any submission",
+			apiKey:         "admin-key-id.secret",
+			submissionID:   "submission-123",
+			ownerKeyID:     "other-key-id",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "invalid api key",
+			apiKey:            "invalid-api-key",
+			submissionID:      "submission-123",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedErrorCode: "ERR_INVALID_API_KEY",
+		},
+		{
+			name:              "submission not found",
+			apiKey:            "test-api-key",
+			submissionID:      "unknown",
+			serviceResponse:   shcore.NewNotFoundError("submission not found"),
+			expectedStatus:    http.StatusNotFound,
+			expectedErrorCode: "ERR_NOT_FOUND",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			service.getSubmissionFunc = func(ctx context.Context, id string) (*shcore.Submission, error) {
+				if tt.serviceResponse != nil {
+					return nil, tt.serviceResponse
+				}
+				return &shcore.Submission{
+					ID:        id,
+					State:     shcore.SubmissionStateSaved,
+					PublicURL: "https://idnremote.com/jobs/123",
+					APIKeyID:  tt.ownerKeyID,
+				}, nil
+			}
+			service.authenticateFunc = func(ctx context.Context, rawKey string) (*core.APIKey, error) {
+				if rawKey != "admin-key-id.secret" {
+					return nil, core.ErrInvalidAPIKey
+				}
+				return &core.APIKey{ID: "admin-key-id", Scopes: []core.APIKeyScope{core.APIKeyScopeAdmin}}, nil
+			}
+
+			api, err := driver.NewAPI(driver.APIConfig{
+				Service:      service,
+				ClientApiKey: "test-api-key",
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/vacancies/"+tt.submissionID, nil)
+			req.Header.Set("X-Api-Key", tt.apiKey)
+			w := httptest.NewRecorder()
+
+			api.GetHandler().ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var respBody struct {
+				OK   bool              `json:"ok"`
+				Err  string            `json:"err"`
+				Data shcore.Submission `json:"data"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			require.NoError(t, err)
+
+			if tt.expectedErrorCode != "" {
+				assert.Equal(t, tt.expectedErrorCode, respBody.Err)
+				assert.False(t, respBody.OK)
+				return
+			}
+
+			assert.True(t, respBody.OK)
+			assert.Equal(t, tt.submissionID, respBody.Data.ID)
+			assert.Equal(t, shcore.SubmissionStateSaved, respBody.Data.State)
+			assert.Equal(t, "https://idnremote.com/jobs/123", respBody.Data.PublicURL)
+		})
+	}
+}
+
+func TestAPIServePreviewVacancy(t *testing.T) {
+	tests := []struct {
+		name              string
+		apiKey            string
+		requestBody       interface{}
+		serviceResponse   error
+		expectedStatus    int
+		expectedErrorCode string
+	}{
+		{
+			name:           "success",
+			apiKey:         "test-api-key",
+			requestBody:    core.PreviewRequest{ApplyURL: "https://boards.greenhouse.io/test/jobs/1"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "invalid api key",
+			apiKey:            "invalid-api-key",
+			requestBody:       core.PreviewRequest{ApplyURL: "https://boards.greenhouse.io/test/jobs/1"},
+			expectedStatus:    http.StatusUnauthorized,
+			expectedErrorCode: "ERR_INVALID_API_KEY",
+		},
+		{
+			name:              "api key without url scope",
+			apiKey:            "manual-key-id.secret",
+			requestBody:       core.PreviewRequest{ApplyURL: "https://boards.greenhouse.io/test/jobs/1"},
+			expectedStatus:    http.StatusForbidden,
+			expectedErrorCode: "ERR_FORBIDDEN",
+		},
+		{
+			name:              "invalid request body",
+			apiKey:            "test-api-key",
+			requestBody:       "invalid json",
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorCode: "ERR_BAD_REQUEST",
+		},
+		{
+			name:              "service returns error",
+			apiKey:            "test-api-key",
+			requestBody:       core.PreviewRequest{},
+			serviceResponse:   shcore.NewBadRequestError("missing `apply_url`"),
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorCode: "ERR_BAD_REQUEST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			service.previewFunc = func(ctx context.Context, req core.PreviewRequest) (*core.PreviewResult, error) {
+				if tt.serviceResponse != nil {
+					return nil, tt.serviceResponse
+				}
+				return &core.PreviewResult{
+					Vacancy: shcore.Vacancy{
+						JobTitle:    "Software Engineer",
+						CompanyName: "Test Company",
+						ApplyURL:    req.ApplyURL,
+					},
+					Parser: "greenhouse",
+				}, nil
+			}
+			service.authenticateFunc = func(ctx context.Context, rawKey string) (*core.APIKey, error) {
+				if rawKey != "manual-key-id.secret" {
+					return nil, core.ErrInvalidAPIKey
+				}
+				return &core.APIKey{ID: "manual-key-id", Scopes: []core.APIKeyScope{core.APIKeyScopeManual}}, nil
+			}
+
+			api, err := driver.NewAPI(driver.APIConfig{
+				Service:      service,
+				ClientApiKey: "test-api-key",
+			})
+			require.NoError(t, err)
+
+			var body bytes.Buffer
+			if s, ok := tt.requestBody.(string); ok {
+				body.WriteString(s)
+			} else {
+				require.NoError(t, json.NewEncoder(&body).Encode(tt.requestBody))
+			}
+			req := httptest.NewRequest(http.MethodPost, "/vacancies/preview", &body)
+			req.Header.Set("X-Api-Key", tt.apiKey)
+			w := httptest.NewRecorder()
+
+			api.GetHandler().ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var respBody struct {
+				OK   bool               `json:"ok"`
+				Err  string             `json:"err"`
+				Data core.PreviewResult `json:"data"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			require.NoError(t, err)
+
+			if tt.expectedErrorCode != "" {
+				assert.Equal(t, tt.expectedErrorCode, respBody.Err)
+				assert.False(t, respBody.OK)
+				return
+			}
+
+			assert.True(t, respBody.OK)
+			assert.Equal(t, "greenhouse", respBody.Data.Parser)
+			assert.Equal(t, "Software Engineer", respBody.Data.Vacancy.JobTitle)
+			assert.Equal(t, "https://boards.greenhouse.io/test/jobs/1", respBody.Data.Vacancy.ApplyURL)
+
+			// preview must not submit the vacancy
+			assert.Nil(t, service.lastSubmitRequest)
+		})
+	}
+}
+
 func TestAPIServeApproveVacancy(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -335,11 +662,113 @@ func TestAPIServeApproveVacancy(t *testing.T) {
 	}
 }
 
+func TestAPIServeEditApproveVacancy(t *testing.T) {
+	tests := []struct {
+		name              string
+		token             string
+		messageID         string
+		requestBody       string
+		serviceResponse   error
+		expectedStatus    int
+		expectedErrorCode string
+		validateParams    func(t *testing.T, req *core.EditApprovalRequest)
+	}{
+		{
+			name:            "success",
+			token:           "valid-token",
+			messageID:       "message-123",
+			requestBody:     `{"job_title":"Software Engineer","company_name":"Test Company","relevant_tags":[" go ",""],"apply_url":"https://example.com/apply"}`,
+			serviceResponse: nil,
+			expectedStatus:  http.StatusOK,
+			validateParams: func(t *testing.T, req *core.EditApprovalRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, "valid-token", req.TokenRequest)
+				assert.Equal(t, "message-123", req.MessageID)
+				assert.Equal(t, "Software Engineer", req.Vacancy.JobTitle)
+				assert.Equal(t, []string{"go"}, req.Vacancy.RelevantTags)
+			},
+		},
+		{
+			name:              "missing token",
+			token:             "",
+			messageID:         "message-123",
+			requestBody:       `{}`,
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorCode: "ERR_BAD_REQUEST",
+		},
+		{
+			name:              "invalid body",
+			token:             "valid-token",
+			messageID:         "message-123",
+			requestBody:       `{invalid`,
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorCode: "ERR_BAD_REQUEST",
+		},
+		{
+			name:              "service returns error",
+			token:             "valid-token",
+			messageID:         "message-123",
+			requestBody:       `{"job_title":"Software Engineer"}`,
+			serviceResponse:   createBadRequestError("approval already processed"),
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorCode: "ERR_BAD_REQUEST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create stub service with desired response
+			service := newStubService()
+			service.handleEditFunc = func(ctx context.Context, req core.EditApprovalRequest) error {
+				return tt.serviceResponse
+			}
+
+			api, err := driver.NewAPI(driver.APIConfig{
+				Service:      service,
+				ClientApiKey: "test-api-key",
+			})
+			require.NoError(t, err)
+
+			// Create request
+			req := httptest.NewRequest(http.MethodPost, "/vacancies/approve/edit?data="+tt.token+"&message_id="+tt.messageID, bytes.NewBufferString(tt.requestBody))
+			w := httptest.NewRecorder()
+
+			// Call the handler
+			handler := api.GetHandler()
+			handler.ServeHTTP(w, req)
+
+			// Check the status code
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			// Parse the response
+			var respBody driver.RespBody
+			err = json.NewDecoder(resp.Body).Decode(&respBody)
+			require.NoError(t, err)
+
+			if tt.expectedErrorCode != "" {
+				assert.Equal(t, tt.expectedErrorCode, respBody.Err)
+				assert.False(t, respBody.OK)
+			} else {
+				assert.True(t, respBody.OK)
+			}
+
+			// Validate the parameters passed to the service
+			if tt.validateParams != nil {
+				tt.validateParams(t, service.lastEditRequest)
+			}
+		})
+	}
+}
+
 func TestAPIServeRejectVacancy(t *testing.T) {
 	tests := []struct {
 		name              string
 		token             string
 		messageID         string
+		reason            string
 		serviceResponse   error
 		expectedStatus    int
 		expectedErrorCode string
@@ -357,6 +786,18 @@ func TestAPIServeRejectVacancy(t *testing.T) {
 				assert.Equal(t, "message-123", req.MessageID)
 			},
 		},
+		{
+			name:            "success with reason",
+			token:           "valid-token",
+			messageID:       "message-123",
+			reason:          "not_remote",
+			serviceResponse: nil,
+			expectedStatus:  http.StatusOK,
+			validateParams: func(t *testing.T, req *core.ApprovalRequest) {
+				require.NotNil(t, req)
+				assert.Equal(t, core.RejectReasonNotRemote, req.RejectReason)
+			},
+		},
 		{
 			name:              "missing token",
 			token:             "",
@@ -408,7 +849,7 @@ func TestAPIServeRejectVacancy(t *testing.T) {
 			require.NoError(t, err)
 
 			// Create request
-			req := httptest.NewRequest(http.MethodGet, "/vacancies/reject?data="+tt.token+"&message_id="+tt.messageID, nil)
+			req := httptest.NewRequest(http.MethodGet, "/vacancies/reject?data="+tt.token+"&message_id="+tt.messageID+"&reason="+tt.reason, nil)
 			w := httptest.NewRecorder()
 
 			// Call the handler
@@ -441,21 +882,131 @@ func TestAPIServeRejectVacancy(t *testing.T) {
 	}
 }
 
+func TestAPIServeVacancyBatch(t *testing.T) {
+	tests := []struct {
+		name              string
+		path              string
+		expectedStatus    int
+		expectedErrorCode string
+		validateParams    func(t *testing.T, req *core.BatchApprovalRequest)
+	}{
+		{
+			name:           "approve all",
+			path:           "/vacancies/batch/approve?data=batch-token&batch_id=batch-1",
+			expectedStatus: http.StatusOK,
+			validateParams: func(t *testing.T, req *core.BatchApprovalRequest) {
+				assert.Equal(t, "batch-token", req.TokenRequest)
+				assert.Equal(t, "batch-1", req.BatchID)
+				assert.Empty(t, req.MessageIDs)
+			},
+		},
+		{
+			name:           "approve selected",
+			path:           "/vacancies/batch/approve?data=batch-token&batch_id=batch-1&message_id=item-1&message_id=item-3",
+			expectedStatus: http.StatusOK,
+			validateParams: func(t *testing.T, req *core.BatchApprovalRequest) {
+				assert.Equal(t, []string{"item-1", "item-3"}, req.MessageIDs)
+			},
+		},
+		{
+			name:           "reject all with reason",
+			path:           "/vacancies/batch/reject?data=batch-token&batch_id=batch-1&reason=not_remote",
+			expectedStatus: http.StatusOK,
+			validateParams: func(t *testing.T, req *core.BatchApprovalRequest) {
+				assert.Equal(t, "batch-1", req.BatchID)
+				assert.Equal(t, core.RejectReasonNotRemote, req.RejectReason)
+			},
+		},
+		{
+			name:           "approve selected endpoint",
+			path:           "/vacancies/batch/approve/selected?data=batch-token&batch_id=batch-1&message_id=item-2",
+			expectedStatus: http.StatusOK,
+			validateParams: func(t *testing.T, req *core.BatchApprovalRequest) {
+				assert.Equal(t, "batch-1", req.BatchID)
+				assert.Equal(t, []string{"item-2"}, req.MessageIDs)
+			},
+		},
+		{
+			name:              "approve selected without selection",
+			path:              "/vacancies/batch/approve/selected?data=batch-token&batch_id=batch-1",
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorCode: "ERR_BAD_REQUEST",
+		},
+		{
+			name:              "missing token",
+			path:              "/vacancies/batch/approve?batch_id=batch-1",
+			expectedStatus:    http.StatusBadRequest,
+			expectedErrorCode: "ERR_BAD_REQUEST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newStubService()
+			api, err := driver.NewAPI(driver.APIConfig{
+				Service:      service,
+				ClientApiKey: "test-api-key",
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			api.GetHandler().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			var respBody driver.RespBody
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+			if tt.expectedErrorCode != "" {
+				assert.Equal(t, tt.expectedErrorCode, respBody.Err)
+				assert.Nil(t, service.lastBatchRequest)
+				return
+			}
+
+			assert.True(t, respBody.OK)
+			require.NotNil(t, service.lastBatchRequest)
+			tt.validateParams(t, service.lastBatchRequest)
+		})
+	}
+}
+
 // stubService is a simple implementation of core.Service for testing
 type stubService struct {
-	handleRequestFunc func(ctx context.Context, req shcore.SubmitRequest) error
+	handleRequestFunc func(ctx context.Context, req shcore.SubmitRequest) (*core.SubmitResult, error)
+	previewFunc       func(ctx context.Context, req core.PreviewRequest) (*core.PreviewResult, error)
 	handleApproveFunc func(ctx context.Context, req core.ApprovalRequest) error
 	handleRejectFunc  func(ctx context.Context, req core.ApprovalRequest) error
+	getSubmissionFunc func(ctx context.Context, id string) (*shcore.Submission, error)
+	handleEditFunc    func(ctx context.Context, req core.EditApprovalRequest) error
+	approveBatchFunc  func(ctx context.Context, req core.BatchApprovalRequest) (*core.BatchApprovalResult, error)
+	rejectBatchFunc   func(ctx context.Context, req core.BatchApprovalRequest) (*core.BatchApprovalResult, error)
+	emailReplyFunc    func(ctx context.Context, reply core.EmailReply) error
+	getApprovalFunc   func(ctx context.Context, messageID string) (*core.ApprovalRecord, error)
+	listApprovalsFunc func(ctx context.Context, filter core.ApprovalFilter) ([]core.ApprovalRecord, error)
+	revokeTokensFunc  func(ctx context.Context, req core.RevokeTokensRequest) error
+	authenticateFunc  func(ctx context.Context, rawKey string) (*core.APIKey, error)
+	createAPIKeyFunc  func(ctx context.Context, req core.CreateAPIKeyRequest) (*core.CreateAPIKeyResult, error)
+	listAPIKeysFunc   func(ctx context.Context) ([]core.APIKey, error)
+	revokeAPIKeyFunc  func(ctx context.Context, id string) error
 	// Capture last received parameters for validation
 	lastSubmitRequest   *shcore.SubmitRequest
+	lastPreviewRequest  *core.PreviewRequest
 	lastApprovalRequest *core.ApprovalRequest
 	lastRejectRequest   *core.ApprovalRequest
+	lastEditRequest     *core.EditApprovalRequest
+	lastBatchRequest    *core.BatchApprovalRequest
+	lastEmailReply      *core.EmailReply
+	lastRevokeRequest   *core.RevokeTokensRequest
+	lastCreateAPIKey    *core.CreateAPIKeyRequest
+	lastRevokedAPIKeyID string
 }
 
 func newStubService() *stubService {
 	return &stubService{
-		handleRequestFunc: func(ctx context.Context, req shcore.SubmitRequest) error {
-			return nil
+		handleRequestFunc: func(ctx context.Context, req shcore.SubmitRequest) (*core.SubmitResult, error) {
+			return &core.SubmitResult{}, nil
+		},
+		previewFunc: func(ctx context.Context, req core.PreviewRequest) (*core.PreviewResult, error) {
+			return &core.PreviewResult{Vacancy: shcore.Vacancy{ApplyURL: req.ApplyURL}, Parser: "ocr"}, nil
 		},
 		handleApproveFunc: func(ctx context.Context, req core.ApprovalRequest) error {
 			return nil
@@ -463,15 +1014,57 @@ func newStubService() *stubService {
 		handleRejectFunc: func(ctx context.Context, req core.ApprovalRequest) error {
 			return nil
 		},
+		getSubmissionFunc: func(ctx context.Context, id string) (*shcore.Submission, error) {
+			return &shcore.Submission{ID: id}, nil
+		},
+		handleEditFunc: func(ctx context.Context, req core.EditApprovalRequest) error {
+			return nil
+		},
+		approveBatchFunc: func(ctx context.Context, req core.BatchApprovalRequest) (*core.BatchApprovalResult, error) {
+			return &core.BatchApprovalResult{BatchID: req.BatchID, State: core.ApprovalStateApproved, MessageIDs: req.MessageIDs}, nil
+		},
+		rejectBatchFunc: func(ctx context.Context, req core.BatchApprovalRequest) (*core.BatchApprovalResult, error) {
+			return &core.BatchApprovalResult{BatchID: req.BatchID, State: core.ApprovalStateRejected, MessageIDs: req.MessageIDs}, nil
+		},
+		emailReplyFunc: func(ctx context.Context, reply core.EmailReply) error {
+			return nil
+		},
+		getApprovalFunc: func(ctx context.Context, messageID string) (*core.ApprovalRecord, error) {
+			return &core.ApprovalRecord{MessageID: messageID, State: core.ApprovalStatePending}, nil
+		},
+		listApprovalsFunc: func(ctx context.Context, filter core.ApprovalFilter) ([]core.ApprovalRecord, error) {
+			return nil, nil
+		},
+		revokeTokensFunc: func(ctx context.Context, req core.RevokeTokensRequest) error {
+			return nil
+		},
+		authenticateFunc: func(ctx context.Context, rawKey string) (*core.APIKey, error) {
+			return nil, core.ErrInvalidAPIKey
+		},
+		createAPIKeyFunc: func(ctx context.Context, req core.CreateAPIKeyRequest) (*core.CreateAPIKeyResult, error) {
+			return &core.CreateAPIKeyResult{APIKey: core.APIKey{ID: "key-id", Name: req.Name, Scopes: req.Scopes}, Key: "key-id.secret"}, nil
+		},
+		listAPIKeysFunc: func(ctx context.Context) ([]core.APIKey, error) {
+			return nil, nil
+		},
+		revokeAPIKeyFunc: func(ctx context.Context, id string) error {
+			return nil
+		},
 	}
 }
 
-func (s *stubService) HandleRequest(ctx context.Context, req shcore.SubmitRequest) error {
+func (s *stubService) HandleRequest(ctx context.Context, req shcore.SubmitRequest) (*core.SubmitResult, error) {
 	// Capture request for later inspection
 	s.lastSubmitRequest = &req
 	return s.handleRequestFunc(ctx, req)
 }
 
+func (s *stubService) PreviewVacancy(ctx context.Context, req core.PreviewRequest) (*core.PreviewResult, error) {
+	// Capture request for later inspection
+	s.lastPreviewRequest = &req
+	return s.previewFunc(ctx, req)
+}
+
 func (s *stubService) HandleApprove(ctx context.Context, req core.ApprovalRequest) error {
 	// Capture request for later inspection
 	s.lastApprovalRequest = &req
@@ -484,6 +1077,80 @@ func (s *stubService) HandleReject(ctx context.Context, req core.ApprovalRequest
 	return s.handleRejectFunc(ctx, req)
 }
 
+func (s *stubService) HandleEditApprove(ctx context.Context, req core.EditApprovalRequest) error {
+	// Capture request for later inspection
+	s.lastEditRequest = &req
+	return s.handleEditFunc(ctx, req)
+}
+
+func (s *stubService) HandleApproveBatch(ctx context.Context, req core.BatchApprovalRequest) (*core.BatchApprovalResult, error) {
+	// Capture request for later inspection
+	s.lastBatchRequest = &req
+	return s.approveBatchFunc(ctx, req)
+}
+
+func (s *stubService) HandleRejectBatch(ctx context.Context, req core.BatchApprovalRequest) (*core.BatchApprovalResult, error) {
+	// Capture request for later inspection
+	s.lastBatchRequest = &req
+	return s.rejectBatchFunc(ctx, req)
+}
+
+func (s *stubService) IssueBatchToken(batchID string) (string, error) {
+	return "batch-token-" + batchID, nil
+}
+
+func (s *stubService) HandleEmailReply(ctx context.Context, reply core.EmailReply) error {
+	// Capture reply for later inspection
+	s.lastEmailReply = &reply
+	return s.emailReplyFunc(ctx, reply)
+}
+
+func (s *stubService) ExpireStaleApprovals(ctx context.Context, digestWithin time.Duration) (int, error) {
+	return 0, nil
+}
+
+func (s *stubService) SendPendingDigest(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (s *stubService) GetSubmission(ctx context.Context, id string) (*shcore.Submission, error) {
+	return s.getSubmissionFunc(ctx, id)
+}
+
+func (s *stubService) GetApproval(ctx context.Context, messageID string) (*core.ApprovalRecord, error) {
+	return s.getApprovalFunc(ctx, messageID)
+}
+
+func (s *stubService) ListPendingApprovals(ctx context.Context, filter core.ApprovalFilter) ([]core.ApprovalRecord, error) {
+	return s.listApprovalsFunc(ctx, filter)
+}
+
+func (s *stubService) RevokeTokens(ctx context.Context, req core.RevokeTokensRequest) error {
+	// Capture request for later inspection
+	s.lastRevokeRequest = &req
+	return s.revokeTokensFunc(ctx, req)
+}
+
+func (s *stubService) AuthenticateAPIKey(ctx context.Context, rawKey string) (*core.APIKey, error) {
+	return s.authenticateFunc(ctx, rawKey)
+}
+
+func (s *stubService) CreateAPIKey(ctx context.Context, req core.CreateAPIKeyRequest) (*core.CreateAPIKeyResult, error) {
+	// Capture request for later inspection
+	s.lastCreateAPIKey = &req
+	return s.createAPIKeyFunc(ctx, req)
+}
+
+func (s *stubService) ListAPIKeys(ctx context.Context) ([]core.APIKey, error) {
+	return s.listAPIKeysFunc(ctx)
+}
+
+func (s *stubService) RevokeAPIKey(ctx context.Context, id string) error {
+	// Capture request for later inspection
+	s.lastRevokedAPIKeyID = id
+	return s.revokeAPIKeyFunc(ctx, id)
+}
+
 // CreateBadRequestError is a helper to create error similar to core.NewBadRequestError
 func createBadRequestError(message string) error {
 	return shcore.NewBadRequestError(message)
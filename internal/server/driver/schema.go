@@ -0,0 +1,107 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/invopop/jsonschema"
+)
+
+// validateSchema validates the json value decoded using json.Number against
+// the schema, it only supports the keywords generated by schemaReflector.
+// The field & path are of the validated value, empty for the root value.
+func validateSchema(s *jsonschema.Schema, value interface{}, field, path string) []shcore.FieldError {
+	if value == nil {
+		// null is decoded as zero value just like missing field
+		return nil
+	}
+	if s.Type != "" && !matchSchemaType(s.Type, value) {
+		return []shcore.FieldError{shcore.NewFieldError(field, path, "type", fmt.Sprintf("must be %s", schemaTypeName(s.Type)))}
+	}
+	if len(s.Enum) > 0 && !matchSchemaEnum(s.Enum, value) {
+		values := make([]string, 0, len(s.Enum))
+		for _, v := range s.Enum {
+			values = append(values, fmt.Sprint(v))
+		}
+		return []shcore.FieldError{shcore.NewFieldError(field, path, "enum", fmt.Sprintf("must be one of %s", strings.Join(values, ", ")))}
+	}
+
+	var fieldErrs []shcore.FieldError
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if v[name] == nil {
+				fieldErrs = append(fieldErrs, shcore.NewFieldError(name, shcore.JoinJSONPath(path, name), "required", "is required"))
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var propSchema *jsonschema.Schema
+			if s.Properties != nil {
+				propSchema, _ = s.Properties.Get(name)
+			}
+			if propSchema == nil {
+				if s.AdditionalProperties == jsonschema.FalseSchema {
+					fieldErrs = append(fieldErrs, shcore.NewFieldError(name, shcore.JoinJSONPath(path, name), "additional_properties", "is not allowed"))
+				}
+				continue
+			}
+			fieldErrs = append(fieldErrs, validateSchema(propSchema, v[name], name, shcore.JoinJSONPath(path, name))...)
+		}
+	case []interface{}:
+		if s.Items == nil {
+			break
+		}
+		for i, item := range v {
+			fieldErrs = append(fieldErrs, validateSchema(s.Items, item, field, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return fieldErrs
+}
+
+func matchSchemaType(schemaType string, value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return schemaType == "object"
+	case []interface{}:
+		return schemaType == "array"
+	case string:
+		return schemaType == "string"
+	case bool:
+		return schemaType == "boolean"
+	case json.Number:
+		if schemaType == "integer" {
+			_, err := v.Int64()
+			return err == nil
+		}
+		return schemaType == "number"
+	}
+	return false
+}
+
+func matchSchemaEnum(enum []interface{}, value interface{}) bool {
+	for _, v := range enum {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaTypeName(schemaType string) string {
+	switch schemaType {
+	case "object", "array", "integer":
+		return "an " + schemaType
+	default:
+		return "a " + schemaType
+	}
+}
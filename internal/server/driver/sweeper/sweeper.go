@@ -0,0 +1,91 @@
+package sweeper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"gopkg.in/validator.v2"
+)
+
+const defaultInterval = time.Hour
+
+type Sweeper struct {
+	Config
+}
+
+// Leader tells whether this server replica is the one running background
+// jobs, so the jobs are not repeated by every replica.
+type Leader interface {
+	IsLeader(ctx context.Context) (bool, error)
+}
+
+type Config struct {
+	Service core.Service `validate:"nonnil"`
+	// Leader is optional, when set the stale approvals are only expired by
+	// the leader replica
+	Leader Leader
+	// Interval is how often the stale approvals are expired, default to 1h
+	Interval time.Duration
+	// SendDigest makes admin receive the approvals which will be expired
+	// on the next sweep, so every approval appears in a single digest
+	SendDigest bool
+}
+
+func New(cfg Config) (*Sweeper, error) {
+	// validate config
+	err := validator.Validate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+
+	return &Sweeper{Config: cfg}, nil
+}
+
+// Run expires the stale approvals on every interval and blocks until ctx
+// is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *Sweeper) sweep(ctx context.Context) {
+	if s.Leader != nil {
+		isLeader, err := s.Leader.IsLeader(ctx)
+		if err != nil {
+			log.Printf("failed to check leader: %v", err)
+			return
+		}
+		if !isLeader {
+			return
+		}
+	}
+
+	var digestWithin time.Duration
+	if s.SendDigest {
+		digestWithin = s.Interval
+	}
+
+	count, err := s.Service.ExpireStaleApprovals(ctx, digestWithin)
+	if err != nil {
+		log.Printf("failed to expire stale approvals: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("expired %d stale approvals", count)
+	}
+}
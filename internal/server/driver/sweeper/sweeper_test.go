@@ -0,0 +1,121 @@
+package sweeper_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/server/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/server/driver/sweeper"
+)
+
+func TestSweeperRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		sendDigest      bool
+		expDigestWithin time.Duration
+	}{
+		{
+			name:            "with digest",
+			sendDigest:      true,
+			expDigestWithin: 10 * time.Millisecond,
+		},
+		{
+			name:            "without digest",
+			expDigestWithin: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubService{calls: make(chan time.Duration, 1)}
+			s, err := sweeper.New(sweeper.Config{
+				Service:    svc,
+				Interval:   10 * time.Millisecond,
+				SendDigest: tt.sendDigest,
+			})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				s.Run(ctx)
+				close(done)
+			}()
+
+			select {
+			case digestWithin := <-svc.calls:
+				assert.Equal(t, tt.expDigestWithin, digestWithin)
+			case <-time.After(time.Second):
+				t.Fatal("stale approvals are not expired")
+			}
+
+			// the sweeper must stop once the context is done
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("sweeper doesn't stop")
+			}
+		})
+	}
+}
+
+func TestSweeperRunNotLeader(t *testing.T) {
+	svc := &stubService{calls: make(chan time.Duration, 1)}
+	leader := &stubLeader{calls: make(chan struct{}, 1)}
+	s, err := sweeper.New(sweeper.Config{
+		Service:  svc,
+		Interval: 10 * time.Millisecond,
+		Leader:   leader,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	select {
+	case <-leader.calls:
+	case <-time.After(time.Second):
+		t.Fatal("leader is not checked")
+	}
+
+	// other replica is the leader, so the approvals must not be expired
+	select {
+	case <-svc.calls:
+		t.Fatal("stale approvals are expired by non leader replica")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// stubLeader always tells that other replica is the leader
+type stubLeader struct {
+	calls chan struct{}
+}
+
+func (l *stubLeader) IsLeader(ctx context.Context) (bool, error) {
+	select {
+	case l.calls <- struct{}{}:
+	default:
+	}
+	return false, nil
+}
+
+// stubService only implements ExpireStaleApprovals, calling other methods
+// panics since they are not expected to be called by the sweeper
+type stubService struct {
+	core.Service
+	calls chan time.Duration
+}
+
+func (s *stubService) ExpireStaleApprovals(ctx context.Context, digestWithin time.Duration) (int, error) {
+	select {
+	case s.calls <- digestWithin:
+	default:
+	}
+	return 0, nil
+}
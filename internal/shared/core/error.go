@@ -1,21 +1,88 @@
 package core
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
 
 const (
 	ErrCodeBadRequest    = "ERR_BAD_REQUEST"
+	ErrCodeNotFound      = "ERR_NOT_FOUND"
+	ErrCodeConflict      = "ERR_CONFLICT"
 	ErrCodeInternalError = "ERR_INTERNAL_ERROR"
+	// ErrCodeUpstreamRateLimited is used when upstream service such as
+	// OpenAI or Notion refuses the call because of its rate limit
+	ErrCodeUpstreamRateLimited = "ERR_UPSTREAM_RATE_LIMITED"
+	// ErrCodeUpstreamUnavailable is used when upstream service can't be
+	// reached or fails on its own side
+	ErrCodeUpstreamUnavailable = "ERR_UPSTREAM_UNAVAILABLE"
+	// ErrCodeUpstreamRejected is used when upstream service refuses the
+	// call itself, e.g invalid credential or missing page, so retrying it
+	// gives the same result
+	ErrCodeUpstreamRejected = "ERR_UPSTREAM_REJECTED"
 )
 
 type Error struct {
 	ErrCode string `json:"err"`
 	Message string `json:"msg"`
+	// Fields lists the invalid fields when the error is caused by invalid
+	// request body
+	Fields []FieldError `json:"fields,omitempty"`
+
+	// cause is the underlying error, internal error is classified using it
+	cause error
+}
+
+// FieldError describes a single invalid field of the request body.
+type FieldError struct {
+	// Field is the json name of the invalid field, e.g `apply_url`
+	Field string `json:"field"`
+	// JSONPath is the location of the field in the request body, e.g
+	// `bulk_vacancies[3].apply_url`
+	JSONPath string `json:"json_path"`
+	// Rule is the name of the violated rule, e.g `required`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 func (e *Error) Error() string {
 	return fmt.Sprintf("%v - %v", e.ErrCode, e.Message)
 }
 
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Retryable tells whether the failed operation may succeed when it is
+// retried later. Bad request, not found & conflict errors are permanent
+// since retrying gives the same result. Internal error is assumed transient
+// unless it is caused by permanent error.
+func (e *Error) Retryable() bool {
+	switch e.ErrCode {
+	case ErrCodeUpstreamRateLimited, ErrCodeUpstreamUnavailable:
+		return true
+	case ErrCodeInternalError:
+		var cause *Error
+		if errors.As(e.cause, &cause) {
+			return cause.Retryable()
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable tells whether the operation failed with err may succeed when
+// it is retried later, err which is not *Error is assumed transient.
+func IsRetryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Retryable()
+	}
+	return true
+}
+
 func NewBadRequestError(msg string) *Error {
 	return &Error{
 		ErrCode: ErrCodeBadRequest,
@@ -23,9 +90,64 @@ func NewBadRequestError(msg string) *Error {
 	}
 }
 
+func NewNotFoundError(msg string) *Error {
+	return &Error{
+		ErrCode: ErrCodeNotFound,
+		Message: msg,
+	}
+}
+
+func NewConflictError(msg string) *Error {
+	return &Error{
+		ErrCode: ErrCodeConflict,
+		Message: msg,
+	}
+}
+
 func NewInternalError(err error) *Error {
 	return &Error{
 		ErrCode: ErrCodeInternalError,
 		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+func NewUpstreamRateLimitedError(err error) *Error {
+	return &Error{
+		ErrCode: ErrCodeUpstreamRateLimited,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+func NewUpstreamUnavailableError(err error) *Error {
+	return &Error{
+		ErrCode: ErrCodeUpstreamUnavailable,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+func NewUpstreamRejectedError(err error) *Error {
+	return &Error{
+		ErrCode: ErrCodeUpstreamRejected,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+// NewUpstreamError classifies the failed call to upstream service using the
+// http status code of its response, zero status code means the service
+// can't be reached at all.
+func NewUpstreamError(statusCode int, err error) *Error {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return NewUpstreamRateLimitedError(err)
+	case statusCode == 0 || statusCode >= http.StatusInternalServerError:
+		return NewUpstreamUnavailableError(err)
+	case statusCode >= http.StatusBadRequest:
+		return NewUpstreamRejectedError(err)
+	default:
+		return NewInternalError(err)
 	}
 }
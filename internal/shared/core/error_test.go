@@ -0,0 +1,85 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "bad request",
+			err:      NewBadRequestError("not a vacancy page"),
+			expected: false,
+		},
+		{
+			name:     "wrapped bad request",
+			err:      fmt.Errorf("failed to parse the vacancy: %w", NewBadRequestError("not a vacancy page")),
+			expected: false,
+		},
+		{
+			name:     "not found",
+			err:      NewNotFoundError("page is not found"),
+			expected: false,
+		},
+		{
+			name:     "upstream rate limited",
+			err:      NewUpstreamError(http.StatusTooManyRequests, errors.New("rate limited")),
+			expected: true,
+		},
+		{
+			name:     "upstream unavailable",
+			err:      NewUpstreamError(http.StatusBadGateway, errors.New("bad gateway")),
+			expected: true,
+		},
+		{
+			name:     "upstream unauthorized",
+			err:      NewUpstreamError(http.StatusUnauthorized, errors.New("invalid api key")),
+			expected: false,
+		},
+		{
+			name:     "upstream not found",
+			err:      NewUpstreamError(http.StatusNotFound, errors.New("page is not found")),
+			expected: false,
+		},
+		{
+			name:     "upstream unreachable",
+			err:      NewUpstreamError(0, errors.New("connection refused")),
+			expected: true,
+		},
+		{
+			name:     "internal",
+			err:      NewInternalError(errors.New("unexpected error")),
+			expected: true,
+		},
+		{
+			name:     "internal caused by bad request",
+			err:      NewInternalError(fmt.Errorf("failed to save vacancy: %w", NewBadRequestError("invalid property"))),
+			expected: false,
+		},
+		{
+			name:     "internal caused by upstream unavailable",
+			err:      NewInternalError(fmt.Errorf("failed to save vacancy: %w", NewUpstreamUnavailableError(errors.New("timeout")))),
+			expected: true,
+		},
+		{
+			name:     "unclassified",
+			err:      errors.New("unexpected error"),
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsRetryable(tt.err))
+		})
+	}
+}
@@ -3,23 +3,26 @@ package core
 import (
 	"encoding/json"
 	"fmt"
-
-	"gopkg.in/validator.v2"
+	"net/mail"
+	"time"
 )
 
+// Vacancy is validated using the rules registered in validation.go, such as
+// `applyurl`, `location` & `tags`.
 type Vacancy struct {
-	JobTitle         string   `json:"job_title,omitempty" validate:"nonzero"`
-	CompanyName      string   `json:"company_name,omitempty" validate:"nonzero"`
-	CompanyLocation  string   `json:"company_location,omitempty"`
-	ShortDescription string   `json:"short_description,omitempty"`
-	RelevantTags     []string `json:"relevant_tags,omitempty"`
-	ApplyURL         string   `json:"apply_url" validate:"nonzero"`
+	JobTitle         string   `json:"job_title,omitempty" validate:"nonzero,max=150"`
+	CompanyName      string   `json:"company_name,omitempty" validate:"nonzero,max=100"`
+	CompanyLocation  string   `json:"company_location,omitempty" validate:"max=100,location"`
+	ShortDescription string   `json:"short_description,omitempty" validate:"max=10000"`
+	RelevantTags     []string `json:"relevant_tags,omitempty" validate:"max=10,tags"`
+	ApplyURL         string   `json:"apply_url" validate:"nonzero,max=2048,applyurl"`
 }
 
+// Validate returns *ValidationError when the vacancy is invalid.
 func (r Vacancy) Validate() error {
-	err := validator.Validate(r)
-	if err != nil {
-		return fmt.Errorf("invalid vacancy: %w", err)
+	fieldErrs := validateFields(r, "")
+	if len(fieldErrs) > 0 {
+		return &ValidationError{Subject: "vacancy", Fields: fieldErrs}
 	}
 	return nil
 }
@@ -36,7 +39,8 @@ type VacancyRecord struct {
 }
 
 type Notification struct {
-	Retries int `json:"retries"`
+	SubmissionID    string `json:"submission_id,omitempty"`
+	SubmissionEmail string `json:"submission_email,omitempty"`
 	VacancyRecord
 }
 
@@ -54,30 +58,117 @@ const (
 )
 
 type SubmitRequest struct {
-	SubmissionType  SubmitType `json:"submission_type"`
-	SubmissionEmail string     `json:"submission_email"`
-	Retries         int        `json:"retries"`
-	BulkVacancies   []Vacancy  `json:"bulk_vacancies,omitempty"`
+	// SubmissionID is set internally, it is excluded from the api schema so
+	// clients can't send it
+	SubmissionID    string        `json:"submission_id,omitempty" jsonschema:"-"`
+	SubmissionType  SubmitType    `json:"submission_type"`
+	SubmissionEmail string        `json:"submission_email"`
+	BulkVacancies   []BulkVacancy `json:"bulk_vacancies,omitempty"`
+	// APIKeyID is the id of the api key which submitted the request, it is
+	// set internally & only used for recording the submission owner
+	APIKeyID string `json:"-"`
 	Vacancy
 }
 
+// Validate returns *ValidationError when the request is invalid, the
+// invalid bulk vacancies are reported with their index, e.g
+// `bulk_vacancies[3].apply_url`.
 func (r SubmitRequest) Validate() error {
-	if r.SubmissionType == SubmitTypeBulk {
+	var fieldErrs []FieldError
+	switch r.SubmissionType {
+	case SubmitTypeManual, SubmitTypeURL:
+		fieldErrs = validateVacancyOfType(r.SubmissionType, r.Vacancy, "")
+	case SubmitTypeBulk:
 		if len(r.BulkVacancies) == 0 {
-			return fmt.Errorf("bulk_vacancies cannot be empty")
+			fieldErrs = append(fieldErrs, NewFieldError("bulk_vacancies", "bulk_vacancies", "nonzero", "cannot be empty"))
 		}
-		for _, v := range r.BulkVacancies {
-			// Validate each vacancy in the bulk submission
-			if err := v.Validate(); err != nil {
-				return fmt.Errorf("invalid bulk vacancy: %w", err)
+		for i, v := range r.BulkVacancies {
+			// validate each vacancy in the bulk submission
+			path := fmt.Sprintf("bulk_vacancies[%d]", i)
+			switch v.Type() {
+			case SubmitTypeManual, SubmitTypeURL:
+				fieldErrs = append(fieldErrs, validateVacancyOfType(v.Type(), v.Vacancy, path)...)
+			default:
+				fieldErrs = append(fieldErrs, NewFieldError("submission_type", JoinJSONPath(path, "submission_type"), "enum", "must be either manual or url"))
 			}
 		}
 	}
+	if len(fieldErrs) > 0 {
+		return &ValidationError{Subject: "request", Fields: fieldErrs}
+	}
 
 	return nil
 }
 
+// BulkVacancy is a vacancy in bulk submission, its SubmissionType tells how
+// it is processed once approved: `manual` vacancy is published as it is
+// while `url` vacancy is resolved from its apply url.
+type BulkVacancy struct {
+	// SubmissionType is optional, empty value is handled as `url` since
+	// bulk submission used to only support url vacancy
+	SubmissionType SubmitType `json:"submission_type,omitempty"`
+	Vacancy
+}
+
+// Type returns the submission type of the vacancy, see SubmissionType.
+func (v BulkVacancy) Type() SubmitType {
+	if v.SubmissionType == "" {
+		return SubmitTypeURL
+	}
+	return v.SubmissionType
+}
+
+// IsEmailAddress returns true when the value is a plain email address, it is
+// used to tell real submitters apart from placeholders such as "crawler".
+func IsEmailAddress(val string) bool {
+	addr, err := mail.ParseAddress(val)
+	if err != nil {
+		return false
+	}
+	return addr.Address == val
+}
+
 func (r SubmitRequest) ToJSON() []byte {
 	data, _ := json.Marshal(r)
 	return data
 }
+
+type SubmissionState string
+
+const (
+	SubmissionStateReceived        SubmissionState = "received"
+	SubmissionStatePendingApproval SubmissionState = "pending_approval"
+	SubmissionStateApproved        SubmissionState = "approved"
+	SubmissionStateRejected        SubmissionState = "rejected"
+	SubmissionStateExpired         SubmissionState = "expired"
+	SubmissionStateResolving       SubmissionState = "resolving"
+	SubmissionStateSaved           SubmissionState = "saved"
+	SubmissionStateNotified        SubmissionState = "notified"
+	SubmissionStateFailed          SubmissionState = "failed"
+)
+
+// Submission tracks the lifecycle of a single submitted vacancy across
+// server, vacancy-worker and notification-worker.
+type Submission struct {
+	ID              string          `json:"id"`
+	State           SubmissionState `json:"state"`
+	SubmissionType  SubmitType      `json:"submission_type"`
+	SubmissionEmail string          `json:"submission_email,omitempty"`
+	ApplyURL        string          `json:"apply_url"`
+	PublicURL       string          `json:"public_url,omitempty"`
+	ErrMessage      string          `json:"err_msg,omitempty"`
+	// APIKeyID is the id of the api key which submitted the vacancy, only
+	// that key can look the submission up
+	APIKeyID  string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SubmissionUpdate describes a state transition of a submission, empty
+// PublicURL leaves the stored value untouched.
+type SubmissionUpdate struct {
+	ID         string
+	State      SubmissionState
+	PublicURL  string
+	ErrMessage string
+}
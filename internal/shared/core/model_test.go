@@ -0,0 +1,205 @@
+package core_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+)
+
+func TestVacancyValidate(t *testing.T) {
+	valid := core.Vacancy{
+		JobTitle:         "Software Engineer",
+		CompanyName:      "Test Company",
+		CompanyLocation:  "London, United Kingdom",
+		ShortDescription: "Test Description",
+		RelevantTags:     []string{"golang", "c++", "node.js", "ui/ux", "machine learning"},
+		ApplyURL:         "https://example.com/apply",
+	}
+
+	testCases := []struct {
+		Name     string
+		Update   func(v *core.Vacancy)
+		ExpRules map[string]string
+	}{
+		{
+			Name:   "valid vacancy",
+			Update: func(v *core.Vacancy) {},
+		},
+		{
+			Name: "email apply url",
+			Update: func(v *core.Vacancy) {
+				v.ApplyURL = "hr@example.com"
+			},
+		},
+		{
+			Name: "mailto apply url",
+			Update: func(v *core.Vacancy) {
+				v.ApplyURL = "mailto:hr@example.com"
+			},
+		},
+		{
+			Name: "remote & city state location",
+			Update: func(v *core.Vacancy) {
+				v.CompanyLocation = "Global Remote"
+			},
+		},
+		{
+			Name: "city state location",
+			Update: func(v *core.Vacancy) {
+				v.CompanyLocation = "Singapore"
+			},
+		},
+		{
+			Name: "location with state",
+			Update: func(v *core.Vacancy) {
+				v.CompanyLocation = "San Francisco, CA, United States"
+			},
+		},
+		{
+			Name: "missing required fields",
+			Update: func(v *core.Vacancy) {
+				v.JobTitle = ""
+				v.CompanyName = ""
+				v.ApplyURL = ""
+			},
+			ExpRules: map[string]string{"job_title": "nonzero", "company_name": "nonzero", "apply_url": "nonzero"},
+		},
+		{
+			Name: "non http apply url",
+			Update: func(v *core.Vacancy) {
+				v.ApplyURL = "ftp://example.com/apply"
+			},
+			ExpRules: map[string]string{"apply_url": "applyurl"},
+		},
+		{
+			Name: "relative apply url",
+			Update: func(v *core.Vacancy) {
+				v.ApplyURL = "example.com/apply"
+			},
+			ExpRules: map[string]string{"apply_url": "applyurl"},
+		},
+		{
+			Name: "too long fields",
+			Update: func(v *core.Vacancy) {
+				v.JobTitle = strings.Repeat("a", 151)
+				v.ShortDescription = strings.Repeat("a", 10001)
+			},
+			ExpRules: map[string]string{"job_title": "max", "short_description": "max"},
+		},
+		{
+			Name: "location without country",
+			Update: func(v *core.Vacancy) {
+				v.CompanyLocation = "Jakarta"
+			},
+			ExpRules: map[string]string{"company_location": "location"},
+		},
+		{
+			Name: "location with invalid character",
+			Update: func(v *core.Vacancy) {
+				v.CompanyLocation = "Jakarta, <b>Indonesia</b>"
+			},
+			ExpRules: map[string]string{"company_location": "location"},
+		},
+		{
+			Name: "too many tags",
+			Update: func(v *core.Vacancy) {
+				v.RelevantTags = strings.Split("a,b,c,d,e,f,g,h,i,j,k", ",")
+			},
+			ExpRules: map[string]string{"relevant_tags": "max"},
+		},
+		{
+			Name: "invalid tag",
+			Update: func(v *core.Vacancy) {
+				v.RelevantTags = []string{"golang", "#hiring"}
+			},
+			ExpRules: map[string]string{"relevant_tags": "tags"},
+		},
+		{
+			Name: "too long tag",
+			Update: func(v *core.Vacancy) {
+				v.RelevantTags = []string{strings.Repeat("a", 31)}
+			},
+			ExpRules: map[string]string{"relevant_tags": "tags"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			v := valid
+			testCase.Update(&v)
+
+			err := v.Validate()
+			if len(testCase.ExpRules) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErr *core.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			rules := map[string]string{}
+			for _, fieldErr := range validationErr.Fields {
+				require.Equal(t, fieldErr.Field, fieldErr.JSONPath)
+				rules[fieldErr.Field] = fieldErr.Rule
+			}
+			require.Equal(t, testCase.ExpRules, rules)
+		})
+	}
+}
+
+func TestSubmitRequestValidate(t *testing.T) {
+	// url submission only needs valid apply url
+	err := core.SubmitRequest{
+		SubmissionType: core.SubmitTypeURL,
+		Vacancy:        core.Vacancy{ApplyURL: "https://example.com/apply"},
+	}.Validate()
+	require.NoError(t, err)
+
+	err = core.SubmitRequest{
+		SubmissionType: core.SubmitTypeURL,
+		Vacancy:        core.Vacancy{ApplyURL: "not a url"},
+	}.Validate()
+	var validationErr *core.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, []core.FieldError{
+		{Field: "apply_url", JSONPath: "apply_url", Rule: "applyurl", Message: "`apply_url` must be http(s) url or email address"},
+	}, validationErr.Fields)
+
+	err = core.SubmitRequest{
+		SubmissionType: core.SubmitTypeURL,
+		Vacancy:        core.Vacancy{ApplyURL: "hr@example.com"},
+	}.Validate()
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, "applyurl", validationErr.Fields[0].Rule)
+
+	// manual submission needs complete vacancy
+	err = core.SubmitRequest{
+		SubmissionType: core.SubmitTypeManual,
+		Vacancy:        core.Vacancy{ApplyURL: "https://example.com/apply"},
+	}.Validate()
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Fields, 2)
+
+	// bulk vacancies are reported with their index
+	err = core.SubmitRequest{
+		SubmissionType: core.SubmitTypeBulk,
+		BulkVacancies: []core.BulkVacancy{
+			{SubmissionType: core.SubmitTypeManual, Vacancy: core.Vacancy{JobTitle: "Backend Engineer", CompanyName: "Company A", ApplyURL: "https://example.com/jobs/1"}},
+			{SubmissionType: core.SubmitTypeManual, Vacancy: core.Vacancy{JobTitle: "Frontend Engineer", CompanyName: "Company B", ApplyURL: "https://example.com/jobs/2", CompanyLocation: "Bandung"}},
+		},
+	}.Validate()
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, "bulk_vacancies[1].company_location", validationErr.Fields[0].JSONPath)
+	require.Equal(t, "company_location", validationErr.Fields[0].Field)
+
+	// bulk vacancy without submission type is resolved from its url
+	err = core.SubmitRequest{
+		SubmissionType: core.SubmitTypeBulk,
+		BulkVacancies: []core.BulkVacancy{
+			{Vacancy: core.Vacancy{ApplyURL: "https://example.com/jobs/1"}},
+			{SubmissionType: core.SubmitTypeURL, Vacancy: core.Vacancy{ApplyURL: "https://example.com/jobs/2"}},
+		},
+	}.Validate()
+	require.NoError(t, err)
+}
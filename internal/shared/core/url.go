@@ -0,0 +1,57 @@
+package core
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query params added by job boards & social media to
+// track where the visitor comes from, they don't change the vacancy.
+var trackingParams = map[string]bool{
+	"gclid":      true,
+	"fbclid":     true,
+	"msclkid":    true,
+	"mc_cid":     true,
+	"mc_eid":     true,
+	"gh_src":     true,
+	"ref":        true,
+	"refid":      true,
+	"trk":        true,
+	"trackingid": true,
+	"lipi":       true,
+}
+
+// NormalizeApplyURL returns the canonical form of apply url so the same
+// vacancy can be recognized even when it is shared with different links.
+// Scheme & host are lowercased, fragment, tracking params & trailing slash
+// are removed, and the remaining query params are sorted. Value which is
+// not an absolute url is returned trimmed as it is.
+func NormalizeApplyURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "https" && u.Port() == "443") || (u.Scheme == "http" && u.Port() == "80") {
+		u.Host = u.Hostname()
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		lowerKey := strings.ToLower(key)
+		if strings.HasPrefix(lowerKey, "utm_") || trackingParams[lowerKey] {
+			query.Del(key)
+		}
+	}
+	// Encode sorts the params by key
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,264 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/validator.v2"
+)
+
+const maxTagLength = 30
+
+var (
+	ErrInvalidApplyURL = validator.TextErr{Err: errors.New("invalid apply url")}
+	ErrInvalidLocation = validator.TextErr{Err: errors.New("invalid location")}
+	ErrInvalidTag      = validator.TextErr{Err: errors.New("invalid tag")}
+
+	// locationPartPattern matches a part of location, e.g `New York` or
+	// `St. John's`
+	locationPartPattern = regexp.MustCompile(`^\p{L}[\p{L}\p{M}0-9 .'()\-]*$`)
+	// cityStates are the locations accepted without country part
+	cityStates = map[string]bool{
+		"singapore":    true,
+		"hong kong":    true,
+		"macau":        true,
+		"monaco":       true,
+		"vatican city": true,
+		"gibraltar":    true,
+	}
+	// tagPattern matches tag such as `golang`, `c++`, `node.js` or `ui/ux`
+	tagPattern = regexp.MustCompile(`^[\p{L}0-9][\p{L}0-9 +#./\-]*$`)
+)
+
+func init() {
+	validator.SetValidationFunc("applyurl", validateApplyURL)
+	validator.SetValidationFunc("location", validateLocation)
+	validator.SetValidationFunc("tags", validateTags)
+}
+
+// ValidationError is returned by Validate methods, it lists every invalid
+// field so client can map them back to its inputs.
+type ValidationError struct {
+	// Subject is the validated thing, e.g `vacancy`
+	Subject string
+	Fields  []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Message)
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Subject, strings.Join(msgs, ", "))
+}
+
+// NewValidationError returns bad request error for the error returned by
+// Validate methods, the invalid fields are kept in the error.
+func NewValidationError(err error) *Error {
+	e := NewBadRequestError(err.Error())
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		e.Fields = validationErr.Fields
+	}
+	return e
+}
+
+// validateFields validates struct v using its `validate` tags, the invalid
+// fields are reported using their json names under the given json path.
+func validateFields(v interface{}, path string) []FieldError {
+	err := validator.Validate(v)
+	if err == nil {
+		return nil
+	}
+	errMap, ok := err.(validator.ErrorMap)
+	if !ok {
+		return []FieldError{NewFieldError("", path, "invalid", err.Error())}
+	}
+
+	// follow the order of struct fields so the result is stable
+	var fieldErrs []FieldError
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		errs, ok := errMap[sf.Name]
+		if !ok {
+			continue
+		}
+		name := jsonFieldName(sf)
+		for _, err := range errs {
+			rule, msg := describeValidationError(sf, err)
+			fieldErrs = append(fieldErrs, NewFieldError(name, JoinJSONPath(path, name), rule, msg))
+		}
+	}
+
+	return fieldErrs
+}
+
+// validateVacancyOfType validates the vacancy located at the given json path
+// according to its submission type.
+func validateVacancyOfType(submitType SubmitType, v Vacancy, path string) []FieldError {
+	if submitType == SubmitTypeURL {
+		// the other fields are resolved from the apply url
+		return validateResolvableURL(v.ApplyURL, path)
+	}
+	return validateFields(v, path)
+}
+
+// validateResolvableURL validates the apply url of the vacancy located at
+// the given json path, email address is not accepted since the vacancy is
+// resolved from the url.
+func validateResolvableURL(applyURL, path string) []FieldError {
+	var fieldErrs []FieldError
+	for _, fieldErr := range validateFields(Vacancy{ApplyURL: applyURL}, path) {
+		if fieldErr.Field == "apply_url" {
+			fieldErrs = append(fieldErrs, fieldErr)
+		}
+	}
+	if len(fieldErrs) == 0 && !isHTTPURL(applyURL) {
+		fieldErrs = append(fieldErrs, NewFieldError("apply_url", JoinJSONPath(path, "apply_url"), "applyurl", "must be http(s) url"))
+	}
+	return fieldErrs
+}
+
+// NewFieldError returns the error of the field located at the given json
+// path, the message is prefixed by the path or `value` for the root value.
+func NewFieldError(field, path, rule, msg string) FieldError {
+	subject := "value"
+	if path != "" {
+		subject = fmt.Sprintf("`%s`", path)
+	}
+	return FieldError{
+		Field:    field,
+		JSONPath: path,
+		Rule:     rule,
+		Message:  fmt.Sprintf("%s %s", subject, msg),
+	}
+}
+
+// describeValidationError returns the rule name & message of the error
+// returned by validator.v2 for the given struct field.
+func describeValidationError(sf reflect.StructField, err error) (string, string) {
+	unit := "characters"
+	if sf.Type.Kind() == reflect.Slice {
+		unit = "items"
+	}
+
+	switch err {
+	case validator.ErrZeroValue:
+		return "nonzero", "is required"
+	case validator.ErrMin:
+		return "min", fmt.Sprintf("must have at least %s %s", validateTagParam(sf, "min"), unit)
+	case validator.ErrMax:
+		return "max", fmt.Sprintf("must have at most %s %s", validateTagParam(sf, "max"), unit)
+	case validator.ErrLen:
+		return "len", fmt.Sprintf("must have exactly %s %s", validateTagParam(sf, "len"), unit)
+	case validator.ErrRegexp:
+		return "regexp", "has invalid format"
+	case ErrInvalidApplyURL:
+		return "applyurl", "must be http(s) url or email address"
+	case ErrInvalidLocation:
+		return "location", "must be in `City, Country` form, e.g `London, United Kingdom`"
+	case ErrInvalidTag:
+		return "tags", fmt.Sprintf("must only contain letters, digits, spaces or `+#./-` and have at most %d characters each", maxTagLength)
+	default:
+		return "invalid", err.Error()
+	}
+}
+
+// validateTagParam returns the param of the rule in `validate` tag, e.g
+// `10` for `max=10`.
+func validateTagParam(sf reflect.StructField, rule string) string {
+	for _, part := range strings.Split(sf.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(part, "=")
+		if name == rule {
+			return param
+		}
+	}
+	return ""
+}
+
+func jsonFieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+// JoinJSONPath returns the json path of the named field of the value
+// located at path, e.g `bulk_vacancies[0].apply_url`.
+func JoinJSONPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// validateApplyURL accepts http(s) url, email address or mailto url, empty
+// value is left to `nonzero` rule.
+func validateApplyURL(v interface{}, _ string) error {
+	val, ok := v.(string)
+	if !ok {
+		return validator.ErrUnsupported
+	}
+	if val == "" {
+		return nil
+	}
+	if IsEmailAddress(strings.TrimPrefix(val, "mailto:")) {
+		return nil
+	}
+
+	if !isHTTPURL(val) {
+		return ErrInvalidApplyURL
+	}
+	return nil
+}
+
+func isHTTPURL(val string) bool {
+	u, err := url.Parse(val)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validateLocation accepts location in `City, Country` or
+// `City, State, Country` form as well as city state such as `Singapore`,
+// empty value & remote location such as `Global Remote` are accepted since
+// the company HQ is not always known.
+func validateLocation(v interface{}, _ string) error {
+	val, ok := v.(string)
+	if !ok {
+		return validator.ErrUnsupported
+	}
+	lowerVal := strings.ToLower(strings.TrimSpace(val))
+	if lowerVal == "" || strings.Contains(lowerVal, "remote") || cityStates[lowerVal] {
+		return nil
+	}
+
+	parts := strings.Split(val, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ErrInvalidLocation
+	}
+	for _, part := range parts {
+		if !locationPartPattern.MatchString(strings.TrimSpace(part)) {
+			return ErrInvalidLocation
+		}
+	}
+	return nil
+}
+
+func validateTags(v interface{}, _ string) error {
+	tags, ok := v.([]string)
+	if !ok {
+		return validator.ErrUnsupported
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			return ErrInvalidTag
+		}
+	}
+	return nil
+}
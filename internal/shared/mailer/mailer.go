@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"gopkg.in/validator.v2"
+)
+
+// Mailer sends multipart email through SMTP, it is shared by the server &
+// workers so every email is sent the same way.
+type Mailer struct {
+	MailerConfig
+}
+
+type MailerConfig struct {
+	Host string `validate:"nonzero"`
+	Port int    `validate:"nonzero"`
+	From string `validate:"nonzero"`
+	// Password is only optional for local SMTP server such as mailpit,
+	// which is used without authentication
+	Password string
+}
+
+func NewMailer(cfg MailerConfig) (*Mailer, error) {
+	err := validator.Validate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	m := &Mailer{MailerConfig: cfg}
+	if m.Password == "" && !m.isLocal() {
+		return nil, fmt.Errorf("invalid configuration: password is required for %s", m.Host)
+	}
+
+	return m, nil
+}
+
+// Message is the email to send, the plain & html bodies are sent as the
+// alternatives of each other.
+type Message struct {
+	To []string
+	// Headers are written as they are, e.g `From`, `To`, `Subject` &
+	// `Message-ID`
+	Headers map[string]string
+	// Boundary separates the alternative bodies, it must not appear in
+	// the bodies
+	Boundary string
+	// BodyHTML is the content of html body element
+	BodyHTML  string
+	BodyPlain string
+}
+
+func (m *Mailer) Send(msg Message) error {
+	addr := fmt.Sprintf("%s:%d", m.Host, m.Port)
+
+	var auth smtp.Auth
+	if !m.isLocal() {
+		auth = smtp.PlainAuth("IDN Remote Entry", m.From, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(addr, auth, m.From, msg.To, []byte(buildMessage(msg)))
+}
+
+func (m *Mailer) isLocal() bool {
+	return m.Host == "mailpit" || strings.Contains(m.Host, "localhost")
+}
+
+func buildMessage(msg Message) string {
+	var message strings.Builder
+
+	// Add headers
+	for k, v := range msg.Headers {
+		fmt.Fprintf(&message, "%s: %s\r\n", k, v)
+	}
+
+	// Add MIME headers
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", msg.Boundary)
+
+	// Add plain text version
+	fmt.Fprintf(&message, "--%s\r\n", msg.Boundary)
+	fmt.Fprintf(&message, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&message, "%s\r\n\r\n", strings.ReplaceAll(msg.BodyPlain, "<br>", "\n"))
+
+	// Add HTML version
+	fmt.Fprintf(&message, "--%s\r\n", msg.Boundary)
+	fmt.Fprintf(&message, "Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&message, `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+</head>
+<body>
+    %s
+</body>
+</html>`, msg.BodyHTML)
+
+	fmt.Fprintf(&message, "\r\n\r\n--%s--", msg.Boundary)
+	return message.String()
+}
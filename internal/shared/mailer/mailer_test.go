@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     MailerConfig
+		wantErr bool
+	}{
+		{
+			name: "smtp server with password",
+			cfg:  MailerConfig{Host: "smtp.example.com", Port: 587, From: "from@example.com", Password: "password"},
+		},
+		{
+			name:    "smtp server without password",
+			cfg:     MailerConfig{Host: "smtp.example.com", Port: 587, From: "from@example.com"},
+			wantErr: true,
+		},
+		{
+			name: "local smtp server without password",
+			cfg:  MailerConfig{Host: "mailpit", Port: 1025, From: "from@example.com"},
+		},
+		{
+			name:    "missing host",
+			cfg:     MailerConfig{Port: 587, From: "from@example.com", Password: "password"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMailer(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	message := buildMessage(Message{
+		To:        []string{"to@example.com"},
+		Headers:   map[string]string{"Subject": "Hello"},
+		Boundary:  "boundary-1",
+		BodyHTML:  "<p>Hello</p>",
+		BodyPlain: "Hello<br>World",
+	})
+
+	assert.True(t, strings.HasPrefix(message, "Subject: Hello\r\nMIME-Version: 1.0\r\n"))
+	assert.Contains(t, message, "Content-Type: multipart/alternative; boundary=boundary-1\r\n")
+	assert.Contains(t, message, "Content-Type: text/plain; charset=UTF-8\r\n\r\nHello\nWorld\r\n")
+	assert.Contains(t, message, "<body>\n    <p>Hello</p>\n</body>")
+	assert.True(t, strings.HasSuffix(message, "--boundary-1--"))
+}
@@ -1,24 +1,79 @@
 package rmq
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
-	"os/signal"
-	"syscall"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/wagslane/go-rabbitmq"
 	"gopkg.in/validator.v2"
 )
 
+const (
+	defaultShutdownTimeout = 30 * time.Second
+	// handlerCancelGracePeriod is how long the canceled handlers are waited
+	// on shutdown
+	handlerCancelGracePeriod = 5 * time.Second
+)
+
+// ErrShutdown is the cause of the handler context when the handler is
+// canceled because the consumer is shutting down.
+var ErrShutdown = errors.New("consumer is shutting down")
+
+// IsShutdown returns true when ctx is canceled because the consumer is
+// shutting down, rather than because the handler has timed out.
+func IsShutdown(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrShutdown)
+}
+
 type Consumer struct {
+	rmqConn     *rabbitmq.Conn
 	rmqConsumer *rabbitmq.Consumer
-	queueName   string
+	// rmqPublisher is used for publishing failed messages to the retry &
+	// dead-letter queues
+	rmqPublisher *rabbitmq.Publisher
+	// handlerMu is read locked by every in-flight handler, it is write
+	// locked on shutdown so no new handler is started
+	handlerMu sync.RWMutex
+	// closed is closed once the consumer is closed, the deliveries received
+	// during shutdown are held until then
+	closed    chan struct{}
+	closeOnce sync.Once
+	ConsumerConfig
 }
 
 type ConsumerConfig struct {
 	QueueName          string `validate:"nonzero"`
 	RabbitMQConnString string `validate:"nonzero"`
+
+	// MaxAttempts is how many times the message is handled before it is
+	// dead-lettered, default to 3
+	MaxAttempts int
+	// RetryBaseDelay is the delay before the first retry, it is doubled on
+	// every next retry, default to 5 seconds
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the delay between retries, default to 1 hour
+	RetryMaxDelay time.Duration
+
+	// Concurrency is how many messages are handled in parallel, default
+	// to 1. The handler must be safe for concurrent use when it is set
+	// above 1.
+	Concurrency int
+	// Prefetch is how many unacknowledged messages rabbitmq delivers to the
+	// consumer at once, it is raised to Concurrency when it is lower so no
+	// handler sits idle
+	Prefetch int
+	// HandlerTimeout limits how long a single message is handled, it is
+	// not limited when zero
+	HandlerTimeout time.Duration
+	// ShutdownTimeout is how long the in-flight handlers are waited on
+	// shutdown before they are canceled, default to 30 seconds
+	ShutdownTimeout time.Duration
 }
 
 func NewConsumer(cfg ConsumerConfig) (*Consumer, error) {
@@ -27,6 +82,14 @@ func NewConsumer(cfg ConsumerConfig) (*Consumer, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	cfg = cfg.withDefaults()
+
+	// declare the retry & dead-letter queues of the queue
+	err = setupFailureQueues(cfg.RabbitMQConnString, cfg.QueueName, cfg.MaxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup failure queues: %w", err)
+	}
+
 	// initialize rabbitmq connection
 	rmqConn, err := rabbitmq.NewConn(
 		cfg.RabbitMQConnString,
@@ -45,42 +108,231 @@ func NewConsumer(cfg ConsumerConfig) (*Consumer, error) {
 		rabbitmq.WithConsumerOptionsExchangeDeclare,
 		rabbitmq.WithConsumerOptionsQueueDurable,
 		rabbitmq.WithConsumerOptionsExchangeDurable,
+		rabbitmq.WithConsumerOptionsConcurrency(cfg.Concurrency),
+		rabbitmq.WithConsumerOptionsQOSPrefetch(cfg.Prefetch),
+		// the in-flight handlers are drained by Run
+		rabbitmq.WithConsumerOptionsForceShutdown,
 	)
 	if err != nil {
+		rmqConn.Close()
 		return nil, fmt.Errorf("failed to initialize rabbitmq consumer: %w", err)
 	}
 
+	// initialize publisher for retrying & dead-lettering messages
+	rmqPublisher, err := rabbitmq.NewPublisher(
+		rmqConn,
+		rabbitmq.WithPublisherOptionsLogging,
+	)
+	if err != nil {
+		rmqConsumer.Close()
+		rmqConn.Close()
+		return nil, fmt.Errorf("failed to initialize failure publisher: %w", err)
+	}
+
 	return &Consumer{
-		rmqConsumer: rmqConsumer,
-		queueName:   cfg.QueueName,
+		rmqConn:        rmqConn,
+		rmqConsumer:    rmqConsumer,
+		rmqPublisher:   rmqPublisher,
+		closed:         make(chan struct{}),
+		ConsumerConfig: cfg,
 	}, nil
 }
 
-// Run starts the consumer and block until it's done.
-func (c *Consumer) Run(h func(rabbitmq.Delivery) rabbitmq.Action) error {
-	// define channel to receive shutdown signal
-	shutdownCh := make(chan os.Signal, 1)
-	signal.Notify(shutdownCh, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT)
-	done := make(chan bool)
+// withDefaults returns the config with the unset options filled with their
+// default values.
+func (cfg ConsumerConfig) withDefaults() ConsumerConfig {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryBaseDelay <= 0 {
+		cfg.RetryBaseDelay = defaultRetryBaseDelay
+	}
+	if cfg.RetryMaxDelay <= 0 {
+		cfg.RetryMaxDelay = defaultRetryMaxDelay
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Prefetch < cfg.Concurrency {
+		cfg.Prefetch = cfg.Concurrency
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	return cfg
+}
+
+// Handler handles the delivered message, ctx is canceled once the handler
+// timeout has elapsed or when the handler is still running after the
+// shutdown timeout.
+type Handler func(ctx context.Context, d rabbitmq.Delivery) rabbitmq.Action
+
+// Run starts the consumer and block until ctx is canceled. On cancellation
+// the consumer stops taking new messages, waits for the in-flight handlers
+// up to the shutdown timeout, cancels the ones still running with
+// ErrShutdown, then closes the connection.
+func (c *Consumer) Run(ctx context.Context, h Handler) error {
+	// the handlers are only canceled once the shutdown timeout has elapsed,
+	// not as soon as ctx is canceled
+	handlerCtx, cancelHandlers := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer cancelHandlers(nil)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.rmqConsumer.Run(c.wrapHandler(handlerCtx, h))
+	}()
+
+	select {
+	case err := <-errCh:
+		c.Close()
+		if err != nil {
+			return fmt.Errorf("failed to consume messages: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down consumer of %s, waiting for in-flight messages...", c.QueueName)
+	c.drain(cancelHandlers)
+	c.Close()
+	log.Printf("consumer of %s is shut down", c.QueueName)
+
+	return nil
+}
+
+// wrapHandler returns the delivery handler which calls h with the handler
+// context, the deliveries received during shutdown are not handled.
+func (c *Consumer) wrapHandler(handlerCtx context.Context, h Handler) rabbitmq.Handler {
+	return func(d rabbitmq.Delivery) rabbitmq.Action {
+		if !c.handlerMu.TryRLock() {
+			// the consumer is shutting down, hold the message until the
+			// channel is closed so rabbitmq puts it back to the queue.
+			// Requeueing it right away only makes rabbitmq redeliver it
+			// over & over until the consumer is closed.
+			<-c.closed
+			return rabbitmq.NackRequeue
+		}
+		defer c.handlerMu.RUnlock()
 
+		ctx := handlerCtx
+		if c.HandlerTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.HandlerTimeout)
+			defer cancel()
+		}
+		return h(ctx, d)
+	}
+}
+
+// drain waits for the in-flight handlers up to the shutdown timeout, the
+// handlers still running after that are canceled with ErrShutdown and
+// waited a bit more so they can still settle their messages.
+func (c *Consumer) drain(cancelHandlers context.CancelCauseFunc) {
+	drained := make(chan struct{})
 	go func() {
-		<-shutdownCh
-		log.Println("shutting down worker...")
-		done <- true
+		// never unlocked, so no handler is started afterwards
+		c.handlerMu.Lock()
+		close(drained)
 	}()
 
-	// start consuming messages
-	err := c.rmqConsumer.Run(h)
+	select {
+	case <-drained:
+		return
+	case <-time.After(c.ShutdownTimeout):
+	}
+
+	log.Printf("in-flight messages of %s are not done after %s, canceling them...", c.QueueName, c.ShutdownTimeout)
+	cancelHandlers(ErrShutdown)
+	select {
+	case <-drained:
+	case <-time.After(handlerCancelGracePeriod):
+		// the unsettled messages are redelivered once the connection is closed
+		log.Printf("in-flight messages of %s are still not done, closing anyway", c.QueueName)
+	}
+}
+
+// Retry schedules the failed message to be handled again after exponential
+// backoff delay, the message is dead-lettered instead once it has reached
+// the max attempts. The message should only be discarded when it returns no
+// error.
+func (c *Consumer) Retry(ctx context.Context, d rabbitmq.Delivery, cause error) error {
+	attempt := Attempt(d)
+	if attempt >= c.MaxAttempts {
+		return c.DeadLetter(ctx, d, attempt, cause)
+	}
+
+	delay := retryDelay(c.RetryBaseDelay, c.RetryMaxDelay, attempt, rand.Int63n)
+	headers := rabbitmq.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderRetryCount] = int64(attempt)
+
+	// the retry queue sends the message back to the queue once it expires
+	err := c.rmqPublisher.PublishWithContext(
+		ctx,
+		d.Body,
+		[]string{RetryQueueName(c.QueueName, attempt)},
+		rabbitmq.WithPublishOptionsContentType(contentTypeOf(d)),
+		rabbitmq.WithPublishOptionsMessageID(d.MessageId),
+		rabbitmq.WithPublishOptionsTimestamp(d.Timestamp),
+		rabbitmq.WithPublishOptionsHeaders(headers),
+		rabbitmq.WithPublishOptionsExpiration(strconv.FormatInt(delay.Milliseconds(), 10)),
+		rabbitmq.WithPublishOptionsPersistentDelivery,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to start consuming messages: %w", err)
+		return fmt.Errorf("failed to publish message to retry queue: %w", err)
 	}
 
-	// wait for cleanup to finish
-	<-done
+	return nil
+}
+
+// DeadLetter routes the terminally failed message to the dead-letter queue,
+// the given attempts & cause are recorded in the message headers. The
+// message should only be discarded when it returns no error.
+func (c *Consumer) DeadLetter(ctx context.Context, d rabbitmq.Delivery, attempts int, cause error) error {
+	messageID := d.MessageId
+	if messageID == "" {
+		messageID = generateDeadLetterID()
+	}
+
+	err := c.rmqPublisher.PublishWithContext(
+		ctx,
+		d.Body,
+		[]string{c.QueueName},
+		rabbitmq.WithPublishOptionsExchange(DeadLetterName(c.QueueName)),
+		rabbitmq.WithPublishOptionsContentType(contentTypeOf(d)),
+		rabbitmq.WithPublishOptionsMessageID(messageID),
+		rabbitmq.WithPublishOptionsTimestamp(d.Timestamp),
+		rabbitmq.WithPublishOptionsHeaders(deadLetterHeaders(d.Delivery, attempts, cause, time.Now())),
+		rabbitmq.WithPublishOptionsPersistentDelivery,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish message to dead-letter queue: %w", err)
+	}
 
 	return nil
 }
 
+// Close stops the consumer & closes the connection without waiting for
+// the in-flight handlers, it is safe to be called more than once.
 func (c *Consumer) Close() {
-	c.rmqConsumer.Close()
+	c.closeOnce.Do(func() {
+		c.rmqConsumer.Close()
+		// release the held deliveries, they are put back to the queue as
+		// the channel is already closed
+		close(c.closed)
+		c.rmqPublisher.Close()
+		if err := c.rmqConn.Close(); err != nil {
+			log.Printf("failed to close rabbitmq connection: %v", err)
+		}
+	})
+}
+
+func contentTypeOf(d rabbitmq.Delivery) string {
+	if d.ContentType == "" {
+		return "application/json"
+	}
+	return d.ContentType
 }
@@ -0,0 +1,141 @@
+package rmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+func TestConsumerConfigWithDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      ConsumerConfig
+		expected ConsumerConfig
+	}{
+		{
+			name: "empty",
+			cfg:  ConsumerConfig{},
+			expected: ConsumerConfig{
+				MaxAttempts:     defaultMaxAttempts,
+				RetryBaseDelay:  defaultRetryBaseDelay,
+				RetryMaxDelay:   defaultRetryMaxDelay,
+				Concurrency:     1,
+				Prefetch:        1,
+				ShutdownTimeout: defaultShutdownTimeout,
+			},
+		},
+		{
+			name: "prefetch lower than concurrency",
+			cfg: ConsumerConfig{
+				Concurrency:    4,
+				Prefetch:       2,
+				HandlerTimeout: time.Minute,
+			},
+			expected: ConsumerConfig{
+				MaxAttempts:     defaultMaxAttempts,
+				RetryBaseDelay:  defaultRetryBaseDelay,
+				RetryMaxDelay:   defaultRetryMaxDelay,
+				Concurrency:     4,
+				Prefetch:        4,
+				HandlerTimeout:  time.Minute,
+				ShutdownTimeout: defaultShutdownTimeout,
+			},
+		},
+		{
+			name: "everything set",
+			cfg: ConsumerConfig{
+				MaxAttempts:     5,
+				RetryBaseDelay:  time.Second,
+				RetryMaxDelay:   time.Minute,
+				Concurrency:     4,
+				Prefetch:        8,
+				ShutdownTimeout: time.Minute,
+			},
+			expected: ConsumerConfig{
+				MaxAttempts:     5,
+				RetryBaseDelay:  time.Second,
+				RetryMaxDelay:   time.Minute,
+				Concurrency:     4,
+				Prefetch:        8,
+				ShutdownTimeout: time.Minute,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.cfg.withDefaults())
+		})
+	}
+}
+
+func TestConsumerDrain(t *testing.T) {
+	t.Run("in-flight handler is done before timeout", func(t *testing.T) {
+		c := &Consumer{ConsumerConfig: ConsumerConfig{QueueName: "test", ShutdownTimeout: time.Second}}
+		c.handlerMu.RLock()
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			c.handlerMu.RUnlock()
+		}()
+
+		canceled := false
+		c.drain(func(error) { canceled = true })
+		assert.False(t, canceled)
+		// no new handler is started after draining
+		assert.False(t, c.handlerMu.TryRLock())
+	})
+
+	t.Run("in-flight handler is canceled after timeout", func(t *testing.T) {
+		c := &Consumer{ConsumerConfig: ConsumerConfig{QueueName: "test", ShutdownTimeout: 50 * time.Millisecond}}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		c.handlerMu.RLock()
+		go func() {
+			<-ctx.Done()
+			c.handlerMu.RUnlock()
+		}()
+
+		c.drain(cancel)
+		assert.True(t, IsShutdown(ctx))
+	})
+}
+
+func TestConsumerWrapHandler(t *testing.T) {
+	handlerCtx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	t.Run("handler timeout is not shutdown", func(t *testing.T) {
+		c := &Consumer{closed: make(chan struct{}), ConsumerConfig: ConsumerConfig{HandlerTimeout: time.Millisecond}}
+		handle := c.wrapHandler(handlerCtx, func(ctx context.Context, d rabbitmq.Delivery) rabbitmq.Action {
+			<-ctx.Done()
+			assert.False(t, IsShutdown(ctx))
+			return rabbitmq.Ack
+		})
+		assert.Equal(t, rabbitmq.Ack, handle(rabbitmq.Delivery{}))
+	})
+
+	t.Run("delivery during shutdown is held until closed", func(t *testing.T) {
+		c := &Consumer{closed: make(chan struct{})}
+		handle := c.wrapHandler(handlerCtx, func(ctx context.Context, d rabbitmq.Delivery) rabbitmq.Action {
+			t.Error("handler should not be called during shutdown")
+			return rabbitmq.Ack
+		})
+		// simulate drain
+		c.handlerMu.Lock()
+
+		actionCh := make(chan rabbitmq.Action, 1)
+		go func() {
+			actionCh <- handle(rabbitmq.Delivery{})
+		}()
+		select {
+		case <-actionCh:
+			t.Fatal("delivery should be held until the consumer is closed")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(c.closed)
+		assert.Equal(t, rabbitmq.NackRequeue, <-actionCh)
+	})
+}
@@ -0,0 +1,341 @@
+package rmq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/wagslane/go-rabbitmq"
+	"gopkg.in/validator.v2"
+)
+
+const (
+	// HeaderLastError holds the error of the last failed attempt of handling
+	// the dead-lettered message.
+	HeaderLastError = "x-last-error"
+	// HeaderAttempts holds how many times the message has been handled
+	// before it is dead-lettered.
+	HeaderAttempts = "x-attempts"
+	// HeaderPublishedAt holds when the message was published to its queue,
+	// in RFC 3339 format.
+	HeaderPublishedAt = "x-published-at"
+	// HeaderDeadLetteredAt holds when the message was dead-lettered, in
+	// RFC 3339 format.
+	HeaderDeadLetteredAt = "x-dead-lettered-at"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter is not found")
+
+// DeadLetterName returns the name of the dead-letter exchange of the queue,
+// its dead-letter queue is also named the same.
+func DeadLetterName(queueName string) string {
+	return queueName + ".dlq"
+}
+
+// declareDeadLetter declares the dead-letter exchange of the queue along
+// with its dead-letter queue, messages are routed using the queue name.
+func declareDeadLetter(ch *amqp.Channel, queueName string) error {
+	name := DeadLetterName(queueName)
+	err := ch.ExchangeDeclare(name, amqp.ExchangeDirect, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange: %w", err)
+	}
+	_, err = ch.QueueDeclare(name, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %w", err)
+	}
+	err = ch.QueueBind(name, queueName, name, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue: %w", err)
+	}
+
+	return nil
+}
+
+// deadLetterHeaders returns the headers of the dead-lettered message, the
+// original headers are kept.
+func deadLetterHeaders(d amqp.Delivery, attempts int, cause error, now time.Time) rabbitmq.Table {
+	headers := rabbitmq.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	if cause != nil {
+		headers[HeaderLastError] = cause.Error()
+	}
+	headers[HeaderAttempts] = int64(attempts)
+	if !d.Timestamp.IsZero() {
+		headers[HeaderPublishedAt] = d.Timestamp.UTC().Format(time.RFC3339)
+	}
+	headers[HeaderDeadLetteredAt] = now.UTC().Format(time.RFC3339)
+
+	return headers
+}
+
+func generateDeadLetterID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// DeadLetter is message which has failed terminally and been routed to the
+// dead-letter queue.
+type DeadLetter struct {
+	ID             string
+	Body           []byte
+	LastError      string
+	Attempts       int
+	PublishedAt    time.Time
+	DeadLetteredAt time.Time
+	Headers        map[string]interface{}
+}
+
+func newDeadLetter(d amqp.Delivery) DeadLetter {
+	dl := DeadLetter{
+		ID:      d.MessageId,
+		Body:    d.Body,
+		Headers: d.Headers,
+	}
+	if v, ok := d.Headers[HeaderLastError].(string); ok {
+		dl.LastError = v
+	}
+	switch v := d.Headers[HeaderAttempts].(type) {
+	case int32:
+		dl.Attempts = int(v)
+	case int64:
+		dl.Attempts = int(v)
+	}
+	if v, ok := d.Headers[HeaderPublishedAt].(string); ok {
+		dl.PublishedAt, _ = time.Parse(time.RFC3339, v)
+	}
+	if v, ok := d.Headers[HeaderDeadLetteredAt].(string); ok {
+		dl.DeadLetteredAt, _ = time.Parse(time.RFC3339, v)
+	}
+
+	return dl
+}
+
+// replayHeaders returns the original headers of the dead-lettered message,
+// the retry count is also removed so the replayed message gets a fresh set
+// of attempts.
+func replayHeaders(headers amqp.Table) amqp.Table {
+	replayed := amqp.Table{}
+	for k, v := range headers {
+		switch k {
+		case HeaderLastError, HeaderAttempts, HeaderPublishedAt, HeaderDeadLetteredAt, HeaderRetryCount:
+			continue
+		}
+		replayed[k] = v
+	}
+
+	return replayed
+}
+
+// replayPublishing returns the dead-lettered message to be published back to
+// its queue, the message id & the time it was originally published are kept
+// so the replayed message can be traced back to the original one.
+func replayPublishing(d amqp.Delivery) amqp.Publishing {
+	publishedAt := d.Timestamp
+	if v, ok := d.Headers[HeaderPublishedAt].(string); ok && publishedAt.IsZero() {
+		// messages dead-lettered without timestamp only have the header
+		publishedAt, _ = time.Parse(time.RFC3339, v)
+	}
+
+	return amqp.Publishing{
+		Headers:      replayHeaders(d.Headers),
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Timestamp:    publishedAt,
+		Body:         d.Body,
+	}
+}
+
+// DeadLetterQueue is used for inspecting, replaying & purging the messages
+// in the dead-letter queue of a queue.
+type DeadLetterQueue struct {
+	conn      *amqp.Connection
+	queueName string
+}
+
+type DeadLetterQueueConfig struct {
+	QueueName          string `validate:"nonzero"`
+	RabbitMQConnString string `validate:"nonzero"`
+}
+
+func NewDeadLetterQueue(cfg DeadLetterQueueConfig) (*DeadLetterQueue, error) {
+	// validate config
+	err := validator.Validate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	conn, err := amqp.Dial(cfg.RabbitMQConnString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to rabbitmq: %w", err)
+	}
+
+	// make sure the dead-letter queue exists even when no worker has
+	// consumed the queue yet
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+	err = declareDeadLetter(ch, cfg.QueueName)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &DeadLetterQueue{
+		conn:      conn,
+		queueName: cfg.QueueName,
+	}, nil
+}
+
+// List returns every message in the dead-letter queue from the oldest.
+func (q *DeadLetterQueue) List(ctx context.Context) ([]DeadLetter, error) {
+	var deadLetters []DeadLetter
+	err := q.withChannel(func(ch *amqp.Channel, deliveries []amqp.Delivery) error {
+		for _, d := range deliveries {
+			deadLetters = append(deadLetters, newDeadLetter(d))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deadLetters, nil
+}
+
+// Get returns the dead-lettered message with the given id, it returns
+// ErrDeadLetterNotFound when the message is not in the queue.
+func (q *DeadLetterQueue) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	deadLetters, err := q.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, dl := range deadLetters {
+		if dl.ID == id {
+			return &dl, nil
+		}
+	}
+
+	return nil, ErrDeadLetterNotFound
+}
+
+// Replay publishes the dead-lettered messages with the given ids back to
+// their queue then removes them from the dead-letter queue, every message
+// is replayed when no id is given. It returns the number of replayed
+// messages.
+func (q *DeadLetterQueue) Replay(ctx context.Context, ids []string) (int, error) {
+	count := 0
+	err := q.withChannel(func(ch *amqp.Channel, deliveries []amqp.Delivery) error {
+		for _, d := range deliveries {
+			if !matchDeadLetter(d, ids) {
+				continue
+			}
+			err := ch.PublishWithContext(ctx, q.queueName, q.queueName, false, false, replayPublishing(d))
+			if err != nil {
+				return fmt.Errorf("failed to replay message %s: %w", d.MessageId, err)
+			}
+			if err := d.Ack(false); err != nil {
+				return fmt.Errorf("failed to remove replayed message %s: %w", d.MessageId, err)
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// Purge removes the dead-lettered messages with the given ids, every message
+// is removed when no id is given. It returns the number of removed messages.
+func (q *DeadLetterQueue) Purge(ctx context.Context, ids []string) (int, error) {
+	if len(ids) == 0 {
+		ch, err := q.conn.Channel()
+		if err != nil {
+			return 0, fmt.Errorf("failed to open channel: %w", err)
+		}
+		defer ch.Close()
+
+		count, err := ch.QueuePurge(DeadLetterName(q.queueName), false)
+		if err != nil {
+			return 0, fmt.Errorf("failed to purge dead-letter queue: %w", err)
+		}
+		return count, nil
+	}
+
+	count := 0
+	err := q.withChannel(func(ch *amqp.Channel, deliveries []amqp.Delivery) error {
+		for _, d := range deliveries {
+			if !matchDeadLetter(d, ids) {
+				continue
+			}
+			if err := d.Ack(false); err != nil {
+				return fmt.Errorf("failed to remove message %s: %w", d.MessageId, err)
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+func (q *DeadLetterQueue) Close() {
+	q.conn.Close()
+}
+
+// withChannel fetches every message in the dead-letter queue without
+// acknowledging them, then calls fn with them. Messages which are not
+// acknowledged by fn are put back to the queue once fn returns.
+func (q *DeadLetterQueue) withChannel(fn func(ch *amqp.Channel, deliveries []amqp.Delivery) error) error {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	// unacknowledged messages are requeued when the channel is closed
+	defer ch.Close()
+
+	var deliveries []amqp.Delivery
+	for {
+		d, ok, err := ch.Get(DeadLetterName(q.queueName), false)
+		if err != nil {
+			return fmt.Errorf("failed to fetch dead-lettered message: %w", err)
+		}
+		if !ok {
+			break
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return fn(ch, deliveries)
+}
+
+func matchDeadLetter(d amqp.Delivery, ids []string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		if strings.EqualFold(d.MessageId, id) {
+			return true
+		}
+	}
+
+	return false
+}
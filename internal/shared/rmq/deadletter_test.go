@@ -0,0 +1,70 @@
+package rmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterHeaders(t *testing.T) {
+	publishedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	deadLetteredAt := publishedAt.Add(time.Minute)
+	d := amqp.Delivery{
+		MessageId: "abc",
+		Headers:   amqp.Table{"x-trace-id": "trace-1", HeaderRetryCount: int64(2)},
+		Timestamp: publishedAt,
+		Body:      []byte(`{"submission_type":"url"}`),
+	}
+
+	headers := deadLetterHeaders(d, 3, errors.New("handler failed"), deadLetteredAt)
+	assert.Equal(t, "trace-1", headers["x-trace-id"])
+	assert.Equal(t, "handler failed", headers[HeaderLastError])
+	assert.Equal(t, int64(3), headers[HeaderAttempts])
+	assert.Equal(t, "2025-01-01T10:00:00Z", headers[HeaderPublishedAt])
+	assert.Equal(t, "2025-01-01T10:01:00Z", headers[HeaderDeadLetteredAt])
+
+	// the headers should be read back as it is
+	d.Headers = amqp.Table(headers)
+	dl := newDeadLetter(d)
+	assert.Equal(t, "abc", dl.ID)
+	assert.Equal(t, "handler failed", dl.LastError)
+	assert.Equal(t, 3, dl.Attempts)
+	assert.True(t, publishedAt.Equal(dl.PublishedAt))
+	assert.True(t, deadLetteredAt.Equal(dl.DeadLetteredAt))
+
+	// the dead-letter headers & retry count are dropped on replay so the
+	// message gets a fresh set of attempts
+	assert.Equal(t, amqp.Table{"x-trace-id": "trace-1"}, replayHeaders(d.Headers))
+}
+
+func TestReplayPublishing(t *testing.T) {
+	publishedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	d := amqp.Delivery{
+		MessageId:   "abc",
+		ContentType: "application/json",
+		Headers: amqp.Table{
+			"x-trace-id":         "trace-1",
+			HeaderAttempts:       int64(3),
+			HeaderPublishedAt:    "2025-01-01T10:00:00Z",
+			HeaderDeadLetteredAt: "2025-01-01T10:01:00Z",
+		},
+		Timestamp: publishedAt,
+		Body:      []byte(`{"submission_type":"url"}`),
+	}
+
+	msg := replayPublishing(d)
+	assert.Equal(t, "abc", msg.MessageId)
+	assert.True(t, publishedAt.Equal(msg.Timestamp))
+	assert.Equal(t, "application/json", msg.ContentType)
+	assert.Equal(t, amqp.Persistent, msg.DeliveryMode)
+	assert.Equal(t, amqp.Table{"x-trace-id": "trace-1"}, msg.Headers)
+	assert.Equal(t, d.Body, msg.Body)
+
+	// message dead-lettered without timestamp falls back to the header
+	d.Timestamp = time.Time{}
+	msg = replayPublishing(d)
+	assert.True(t, publishedAt.Equal(msg.Timestamp))
+}
@@ -3,6 +3,7 @@ package rmq
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/wagslane/go-rabbitmq"
 	"gopkg.in/validator.v2"
@@ -59,6 +60,7 @@ func (p *Publisher) Publish(ctx context.Context, m Message) error {
 		[]string{p.queueName},
 		rabbitmq.WithPublishOptionsContentType("application/json"),
 		rabbitmq.WithPublishOptionsExchange(p.queueName),
+		rabbitmq.WithPublishOptionsTimestamp(time.Now()),
 	)
 }
 
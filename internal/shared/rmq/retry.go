@@ -0,0 +1,99 @@
+package rmq
+
+import (
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+const (
+	// HeaderRetryCount holds how many times the message has been retried,
+	// it is absent on the first attempt.
+	HeaderRetryCount = "x-retry-count"
+
+	defaultMaxAttempts    = 3
+	defaultRetryBaseDelay = 5 * time.Second
+	defaultRetryMaxDelay  = time.Hour
+)
+
+// RetryQueueName returns the name of the queue holding the messages which
+// have failed the given attempt.
+func RetryQueueName(queueName string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queueName, attempt)
+}
+
+// Attempt returns the attempt number of the delivered message, starting
+// from 1.
+func Attempt(d rabbitmq.Delivery) int {
+	switch v := d.Headers[HeaderRetryCount].(type) {
+	case int32:
+		return int(v) + 1
+	case int64:
+		return int(v) + 1
+	default:
+		return 1
+	}
+}
+
+// retryDelay returns the exponential backoff delay after the given failed
+// attempt. Half of the delay is randomized by rnd so retries of messages
+// failed at the same time are spread out.
+func retryDelay(base, max time.Duration, attempt int, rnd func(n int64) int64) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+
+	return half + time.Duration(rnd(int64(half)+1))
+}
+
+// declareRetryQueues declares a retry queue for every attempt except the
+// last one. The messages have per-message ttl & the queue routes them back
+// to the queue once they expire. Since rabbitmq only expires messages at
+// the head of the queue, keeping one queue per attempt means only the
+// jitter may hold back the next message.
+func declareRetryQueues(ch *amqp.Channel, queueName string, maxAttempts int) error {
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		_, err := ch.QueueDeclare(RetryQueueName(queueName, attempt), true, false, false, false, amqp.Table{
+			"x-dead-letter-exchange":    queueName,
+			"x-dead-letter-routing-key": queueName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue for attempt %d: %w", attempt, err)
+		}
+	}
+
+	return nil
+}
+
+// setupFailureQueues connects to rabbitmq just for declaring the retry &
+// dead-letter queues of the queue.
+func setupFailureQueues(connString, queueName string, maxAttempts int) error {
+	conn, err := amqp.Dial(connString)
+	if err != nil {
+		return fmt.Errorf("failed to connect to rabbitmq: %w", err)
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer ch.Close()
+
+	err = declareRetryQueues(ch, queueName, maxAttempts)
+	if err != nil {
+		return err
+	}
+
+	return declareDeadLetter(ch, queueName)
+}
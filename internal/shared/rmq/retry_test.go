@@ -0,0 +1,60 @@
+package rmq
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+func TestRetryDelay(t *testing.T) {
+	noJitter := func(n int64) int64 { return n - 1 }
+	fullJitter := func(n int64) int64 { return 0 }
+
+	tests := []struct {
+		name     string
+		attempt  int
+		rnd      func(n int64) int64
+		expDelay time.Duration
+	}{
+		{
+			name:     "first attempt",
+			attempt:  1,
+			rnd:      noJitter,
+			expDelay: 5 * time.Second,
+		},
+		{
+			name:     "third attempt",
+			attempt:  3,
+			rnd:      noJitter,
+			expDelay: 20 * time.Second,
+		},
+		{
+			name:     "third attempt with jitter",
+			attempt:  3,
+			rnd:      fullJitter,
+			expDelay: 10 * time.Second,
+		},
+		{
+			name:     "capped by max delay",
+			attempt:  20,
+			rnd:      noJitter,
+			expDelay: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay := retryDelay(5*time.Second, time.Minute, tt.attempt, tt.rnd)
+			assert.Equal(t, tt.expDelay, delay)
+		})
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	assert.Equal(t, 1, Attempt(rabbitmq.Delivery{}))
+	assert.Equal(t, 3, Attempt(rabbitmq.Delivery{Delivery: amqp.Delivery{Headers: amqp.Table{HeaderRetryCount: int32(2)}}}))
+	assert.Equal(t, 2, Attempt(rabbitmq.Delivery{Delivery: amqp.Delivery{Headers: amqp.Table{HeaderRetryCount: int64(1)}}}))
+}
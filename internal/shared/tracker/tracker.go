@@ -0,0 +1,97 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"gopkg.in/validator.v2"
+)
+
+const (
+	tableSubmission = "submissions"
+)
+
+// Tracker persists submission lifecycle in MySQL so server and workers
+// share the same view of every submission.
+type Tracker struct {
+	TrackerConfig
+}
+
+type TrackerConfig struct {
+	DB *sql.DB `validate:"nonnil"`
+}
+
+func NewTracker(cfg TrackerConfig) (*Tracker, error) {
+	err := validator.Validate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &Tracker{
+		TrackerConfig: cfg,
+	}, nil
+}
+
+func (t *Tracker) SaveSubmission(ctx context.Context, sub core.Submission) error {
+	query := fmt.Sprintf("INSERT INTO %s (id, state, submission_type, submission_email, apply_url, api_key_id) VALUES (?, ?, ?, ?, ?, ?)", tableSubmission)
+	_, err := t.DB.ExecContext(ctx, query, sub.ID, sub.State, sub.SubmissionType, sub.SubmissionEmail, sub.ApplyURL, sub.APIKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to save submission: %w", err)
+	}
+	return nil
+}
+
+func (t *Tracker) UpdateSubmission(ctx context.Context, upd core.SubmissionUpdate) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET state = ?, public_url = IF(? = '', public_url, ?), err_msg = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, tableSubmission)
+	// we don't check affected rows here since mysql reports zero affected
+	// rows when the same state is written twice within the same second
+	_, err := t.DB.ExecContext(ctx, query, upd.State, upd.PublicURL, upd.PublicURL, upd.ErrMessage, upd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update submission: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Tracker) GetSubmission(ctx context.Context, id string) (*core.Submission, error) {
+	query := fmt.Sprintf(`
+		SELECT id, state, submission_type, submission_email, apply_url, public_url, err_msg, api_key_id, UNIX_TIMESTAMP(created_at), UNIX_TIMESTAMP(updated_at)
+		FROM %s
+		WHERE id = ?
+	`, tableSubmission)
+
+	var (
+		sub                  core.Submission
+		errMessage           sql.NullString
+		createdAt, updatedAt int64
+	)
+	err := t.DB.QueryRowContext(ctx, query, id).Scan(
+		&sub.ID,
+		&sub.State,
+		&sub.SubmissionType,
+		&sub.SubmissionEmail,
+		&sub.ApplyURL,
+		&sub.PublicURL,
+		&errMessage,
+		&sub.APIKeyID,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, core.NewNotFoundError("submission not found")
+		}
+		return nil, fmt.Errorf("failed to get submission: %w", err)
+	}
+	sub.ErrMessage = errMessage.String
+	sub.CreatedAt = time.Unix(createdAt, 0).UTC()
+	sub.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+
+	return &sub, nil
+}
@@ -0,0 +1,98 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
+	"github.com/ghazlabs/idn-remote-entry/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv(testutil.EnvKeyMysqlDsn)
+	db, err := sql.Open("mysql", dsn)
+	require.NoError(t, err)
+
+	// Clean up test data
+	_, err = db.Exec("TRUNCATE TABLE submissions")
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestSubmissionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tracker, err := NewTracker(TrackerConfig{DB: db})
+	require.NoError(t, err)
+
+	// Test getting non-existent submission
+	_, err = tracker.GetSubmission(ctx, "non-existent")
+	assert.Error(t, err)
+
+	// Test saving new submission
+	sub := core.Submission{
+		ID:              "test-submission",
+		State:           core.SubmissionStateReceived,
+		SubmissionType:  core.SubmitTypeURL,
+		SubmissionEmail: "test@example.com",
+		ApplyURL:        "https://example.com/apply",
+		APIKeyID:        "key-1",
+	}
+	err = tracker.SaveSubmission(ctx, sub)
+	require.NoError(t, err)
+
+	saved, err := tracker.GetSubmission(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, core.SubmissionStateReceived, saved.State)
+	assert.Equal(t, "key-1", saved.APIKeyID)
+	assert.Equal(t, sub.ApplyURL, saved.ApplyURL)
+	assert.Empty(t, saved.PublicURL)
+	assert.False(t, saved.CreatedAt.IsZero())
+
+	// Test failed state keeps the error message
+	err = tracker.UpdateSubmission(ctx, core.SubmissionUpdate{
+		ID:         sub.ID,
+		State:      core.SubmissionStateFailed,
+		ErrMessage: "resolver timeout",
+	})
+	require.NoError(t, err)
+
+	failed, err := tracker.GetSubmission(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, core.SubmissionStateFailed, failed.State)
+	assert.Equal(t, "resolver timeout", failed.ErrMessage)
+
+	// Test saved state sets public url and clears the error message
+	err = tracker.UpdateSubmission(ctx, core.SubmissionUpdate{
+		ID:        sub.ID,
+		State:     core.SubmissionStateSaved,
+		PublicURL: "https://idnremote.com/jobs/123",
+	})
+	require.NoError(t, err)
+
+	// Test notified state keeps the public url
+	err = tracker.UpdateSubmission(ctx, core.SubmissionUpdate{
+		ID:    sub.ID,
+		State: core.SubmissionStateNotified,
+	})
+	require.NoError(t, err)
+
+	notified, err := tracker.GetSubmission(ctx, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, core.SubmissionStateNotified, notified.State)
+	assert.Equal(t, "https://idnremote.com/jobs/123", notified.PublicURL)
+	assert.Empty(t, notified.ErrMessage)
+
+	// Test saving duplicate submission (should fail due to primary key)
+	err = tracker.SaveSubmission(ctx, sub)
+	assert.Error(t, err)
+}
@@ -16,5 +16,9 @@ type VacancyResolver interface {
 }
 
 type Notifier interface {
-	Notify(ctx context.Context, v shcore.VacancyRecord) error
+	Notify(ctx context.Context, n shcore.Notification) error
+}
+
+type Tracker interface {
+	UpdateSubmission(ctx context.Context, upd shcore.SubmissionUpdate) error
 }
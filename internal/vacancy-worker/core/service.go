@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	"gopkg.in/validator.v2"
@@ -10,12 +11,17 @@ import (
 
 type Service interface {
 	Handle(ctx context.Context, req core.SubmitRequest) error
+	// HandleFailure marks the submission as failed, it is called once the
+	// request is given up rather than on every failed attempt since the
+	// request may still succeed on retry.
+	HandleFailure(ctx context.Context, req core.SubmitRequest, cause error)
 }
 
 type ServiceConfig struct {
 	Storage         Storage         `validate:"nonnil"`
 	VacancyResolver VacancyResolver `validate:"nonnil"`
 	Notifier        Notifier        `validate:"nonnil"`
+	Tracker         Tracker         `validate:"nonnil"`
 }
 
 func NewService(cfg ServiceConfig) (Service, error) {
@@ -38,13 +44,18 @@ func (s *service) Handle(ctx context.Context, req core.SubmitRequest) error {
 	// validate request ensure it is valid request
 	err := req.Validate()
 	if err != nil {
-		return core.NewBadRequestError(err.Error())
+		return core.NewValidationError(err)
 	}
 
 	// handle request
 	switch req.SubmissionType {
 	case core.SubmitTypeManual:
 	case core.SubmitTypeURL:
+		s.updateSubmission(ctx, core.SubmissionUpdate{
+			ID:    req.SubmissionID,
+			State: core.SubmissionStateResolving,
+		})
+
 		// resolve apply url to get vacancy details
 		v, err := s.VacancyResolver.Resolve(ctx, req.Vacancy.ApplyURL)
 		if err != nil {
@@ -62,11 +73,43 @@ func (s *service) Handle(ctx context.Context, req core.SubmitRequest) error {
 		return core.NewInternalError(fmt.Errorf("failed to save vacancy: %w", err))
 	}
 
+	s.updateSubmission(ctx, core.SubmissionUpdate{
+		ID:        req.SubmissionID,
+		State:     core.SubmissionStateSaved,
+		PublicURL: rec.PublicURL,
+	})
+
 	// notify to whatsapp
-	err = s.Notifier.Notify(ctx, *rec)
+	err = s.Notifier.Notify(ctx, core.Notification{
+		SubmissionID:    req.SubmissionID,
+		SubmissionEmail: req.SubmissionEmail,
+		VacancyRecord:   *rec,
+	})
 	if err != nil {
 		return core.NewInternalError(fmt.Errorf("failed to notify to whatsapp: %w", err))
 	}
 
 	return nil
 }
+
+func (s *service) HandleFailure(ctx context.Context, req core.SubmitRequest, cause error) {
+	s.updateSubmission(ctx, core.SubmissionUpdate{
+		ID:         req.SubmissionID,
+		State:      core.SubmissionStateFailed,
+		ErrMessage: cause.Error(),
+	})
+}
+
+// updateSubmission records the submission state transition, failure is only
+// logged since tracking must not break the vacancy processing itself.
+func (s *service) updateSubmission(ctx context.Context, upd core.SubmissionUpdate) {
+	// requests issued before tracking was introduced have no submission id
+	if upd.ID == "" {
+		return
+	}
+
+	err := s.Tracker.UpdateSubmission(ctx, upd)
+	if err != nil {
+		log.Printf("failed to update submission %s to %s: %v", upd.ID, upd.State, err)
+	}
+}
@@ -28,10 +28,7 @@ type NotifierConfig struct {
 	RmqPublisher *rmq.Publisher `validate:"nonnil"`
 }
 
-func (n *Notifier) Notify(ctx context.Context, v core.VacancyRecord) error {
-	ntf := core.Notification{
-		VacancyRecord: v,
-	}
+func (n *Notifier) Notify(ctx context.Context, ntf core.Notification) error {
 	err := n.RmqPublisher.Publish(ctx, ntf)
 	if err != nil {
 		return fmt.Errorf("failed to publish notification %+v: %w", ntf, err)
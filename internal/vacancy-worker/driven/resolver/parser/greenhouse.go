@@ -33,6 +33,10 @@ func NewGreenhouseParser(cfg GreenhouseParserConfig) (*GreenhouseParser, error)
 	}, nil
 }
 
+func (p *GreenhouseParser) Name() string {
+	return "greenhouse"
+}
+
 func (p *GreenhouseParser) Parse(ctx context.Context, url string) (*core.Vacancy, error) {
 	text, jobTitle, err := p.getInfo(ctx, url)
 	if err != nil {
@@ -29,6 +29,10 @@ func NewOCRParser(cfg OCRParserConfig) (*OCRParser, error) {
 	}, nil
 }
 
+func (p *OCRParser) Name() string {
+	return "ocr"
+}
+
 func (p *OCRParser) Parse(ctx context.Context, url string) (*core.Vacancy, error) {
 	// take a screenshot of the URL
 	buf, err := util.TakeScreenshot(ctx, url)
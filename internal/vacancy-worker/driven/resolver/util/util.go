@@ -3,11 +3,13 @@ package util
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/chromedp/chromedp"
+	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	"github.com/invopop/jsonschema"
 	"github.com/openai/openai-go"
 )
@@ -50,7 +52,12 @@ func CallOpenAI[T any](
 		Model:       openai.String(openai.ChatModelGPT4o2024_08_06),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to call the OpenAI API: %w", err)
+		err = fmt.Errorf("failed to call the OpenAI API: %w", err)
+		var apiErr *openai.Error
+		if errors.As(err, &apiErr) {
+			return nil, core.NewUpstreamError(apiErr.StatusCode, err)
+		}
+		return nil, core.NewUpstreamUnavailableError(err)
 	}
 
 	var info T
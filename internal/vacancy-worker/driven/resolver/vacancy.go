@@ -15,6 +15,9 @@ type VacancyResolver struct {
 
 type Parser interface {
 	Parse(ctx context.Context, url string) (*core.Vacancy, error)
+	// Name identifies the parser, e.g to tell submitter which parser
+	// handled the url
+	Name() string
 }
 
 type HQLocator interface {
@@ -43,37 +46,22 @@ func NewVacancyResolver(cfg VacancyResolverConfig) (*VacancyResolver, error) {
 }
 
 func (r *VacancyResolver) Resolve(ctx context.Context, url string) (*core.Vacancy, error) {
-	var vac *core.Vacancy
-	var err error
-	for _, reg := range r.ParserRegistries {
-		for _, apex := range reg.ApexDomains {
-			if strings.Contains(url, apex) {
-				// parse the vacancy from URL
-				vac, err = reg.Parser.Parse(ctx, url)
-				if err != nil {
-					return nil, fmt.Errorf("failed to parse the vacancy: %w", err)
-				}
-
-				goto parserFound
-			}
-		}
-	}
-
-	// if no parser found, use the default parser
-	vac, err = r.DefaultParser.Parse(ctx, url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse the vacancy: %w", err)
-	}
+	vac, _, err := r.ResolveWithParser(ctx, url)
+	return vac, err
+}
 
-parserFound:
-	err = vac.Validate()
+// ResolveWithParser is like Resolve, but it also returns the name of the
+// parser which handled the url.
+func (r *VacancyResolver) ResolveWithParser(ctx context.Context, url string) (*core.Vacancy, string, error) {
+	parser := r.findParser(url)
+	vac, err := parser.Parse(ctx, url)
 	if err != nil {
-		return nil, core.NewBadRequestError(err.Error())
+		return nil, "", fmt.Errorf("failed to parse the vacancy: %w", err)
 	}
 
 	// if the company location is not found (which indicated by "Global Remote")
 	// locate the company's headquarters
-	if strings.Contains(strings.ToLower(vac.CompanyLocation), "remote") {
+	if vac.CompanyName != "" && strings.Contains(strings.ToLower(vac.CompanyLocation), "remote") {
 		hqLoc, _ := r.HQLocator.Locate(ctx, vac.CompanyName)
 		if len(hqLoc) > 0 {
 			// update if the company location is found
@@ -81,5 +69,24 @@ parserFound:
 		}
 	}
 
-	return vac, nil
+	// validate after locating the HQ so the located value is validated too
+	err = vac.Validate()
+	if err != nil {
+		return nil, "", core.NewValidationError(err)
+	}
+
+	return vac, parser.Name(), nil
+}
+
+// findParser returns the parser registered for the url domain, if no parser
+// found the default parser is returned.
+func (r *VacancyResolver) findParser(url string) Parser {
+	for _, reg := range r.ParserRegistries {
+		for _, apex := range reg.ApexDomains {
+			if strings.Contains(url, apex) {
+				return reg.Parser
+			}
+		}
+	}
+	return r.DefaultParser
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	shcore "github.com/ghazlabs/idn-remote-entry/internal/shared/core"
 	"github.com/ghazlabs/idn-remote-entry/internal/testutil"
 	"github.com/ghazlabs/idn-remote-entry/internal/vacancy-worker/driven/resolver"
 	"github.com/ghazlabs/idn-remote-entry/internal/vacancy-worker/driven/resolver/hqloc"
@@ -129,3 +130,70 @@ func TestResolve(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveWithParser(t *testing.T) {
+	vacResolver, err := resolver.NewVacancyResolver(resolver.VacancyResolverConfig{
+		ParserRegistries: []resolver.ParserRegistry{
+			{
+				ApexDomains: []string{"greenhouse.io"},
+				Parser:      &stubParser{name: "greenhouse"},
+			},
+		},
+		DefaultParser: &stubParser{name: "ocr"},
+		HQLocator:     &stubLocator{},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Name        string
+		VacancyURL  string
+		ExpParser   string
+		ExpLocation string
+	}{
+		{
+			Name:        "registered domain",
+			VacancyURL:  "https://job-boards.greenhouse.io/goodnotes/jobs/5158740004",
+			ExpParser:   "greenhouse",
+			ExpLocation: "Hong Kong",
+		},
+		{
+			Name:        "unregistered domain",
+			VacancyURL:  "https://apply.workable.com/joinmakropro/j/A182E331FE/",
+			ExpParser:   "ocr",
+			ExpLocation: "Hong Kong",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			vac, parserName, err := vacResolver.ResolveWithParser(context.Background(), testCase.VacancyURL)
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.ExpParser, parserName)
+			assert.Equal(t, testCase.VacancyURL, vac.ApplyURL)
+			assert.Equal(t, testCase.ExpLocation, vac.CompanyLocation)
+		})
+	}
+}
+
+type stubParser struct {
+	name string
+}
+
+func (p *stubParser) Name() string {
+	return p.name
+}
+
+func (p *stubParser) Parse(ctx context.Context, url string) (*shcore.Vacancy, error) {
+	return &shcore.Vacancy{
+		JobTitle:        "Software Engineer",
+		CompanyName:     "Goodnotes",
+		CompanyLocation: "Global Remote",
+		ApplyURL:        url,
+	}, nil
+}
+
+type stubLocator struct{}
+
+func (l *stubLocator) Locate(ctx context.Context, companyName string) (string, error) {
+	return "Hong Kong", nil
+}
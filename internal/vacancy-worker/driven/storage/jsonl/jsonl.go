@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
@@ -18,6 +19,9 @@ import (
 
 type JSONLStorage struct {
 	filePath string
+	// mu keeps the records from being interleaved or read half written when
+	// the storage is used by concurrent handlers
+	mu sync.RWMutex
 }
 
 type JSONLStorageConfig struct {
@@ -46,6 +50,9 @@ func NewJSONLStorage(cfg JSONLStorageConfig) (*JSONLStorage, error) {
 }
 
 func (s *JSONLStorage) Save(ctx context.Context, v core.Vacancy) (*core.VacancyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
@@ -75,6 +82,9 @@ func (s *JSONLStorage) Save(ctx context.Context, v core.Vacancy) (*core.VacancyR
 }
 
 func (s *JSONLStorage) LookupCompanyLocation(ctx context.Context, companyName string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	file, err := os.Open(s.filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %w", err)
@@ -102,3 +112,34 @@ func (s *JSONLStorage) LookupCompanyLocation(ctx context.Context, companyName st
 
 	return "", nil
 }
+
+// FindByApplyURL returns the vacancy having the same normalized apply url,
+// it returns nil when there is no such vacancy.
+func (s *JSONLStorage) FindByApplyURL(ctx context.Context, applyURL string) (*core.VacancyRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	file, err := os.Open(s.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	normalizedURL := core.NormalizeApplyURL(applyURL)
+	decoder := json.NewDecoder(file)
+	for {
+		var record core.VacancyRecord
+		if err := decoder.Decode(&record); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode record: %w", err)
+		}
+
+		if core.NormalizeApplyURL(record.Vacancy.ApplyURL) == normalizedURL {
+			return &record, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -2,7 +2,9 @@ package jsonl
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
@@ -54,3 +56,79 @@ func TestJSONLStorageLookupCompanyLocation(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "New York", location)
 }
+
+func TestJSONLStorageFindByApplyURL(t *testing.T) {
+	filePath := "test_vacancies.jsonl"
+	defer os.Remove(filePath)
+
+	storage, err := NewJSONLStorage(JSONLStorageConfig{FilePath: filePath})
+	assert.NoError(t, err)
+
+	vacancy := core.Vacancy{
+		JobTitle:    "Software Engineer",
+		CompanyName: "Tech Corp",
+		ApplyURL:    "https://techcorp.com/jobs/123?lang=en&team=backend",
+	}
+	saved, err := storage.Save(context.Background(), vacancy)
+	assert.NoError(t, err)
+
+	sameURLs := []string{
+		"https://techcorp.com/jobs/123?lang=en&team=backend",
+		"HTTPS://TechCorp.com/jobs/123/?team=backend&lang=en",
+		"https://techcorp.com:443/jobs/123?lang=en&team=backend&utm_source=linkedin&utm_medium=social",
+		"https://techcorp.com/jobs/123?gh_src=abc&lang=en&team=backend&ref=twitter#apply",
+		" https://techcorp.com/jobs/123/?lang=en&team=backend&fbclid=xyz ",
+	}
+	for _, applyURL := range sameURLs {
+		record, err := storage.FindByApplyURL(context.Background(), applyURL)
+		assert.NoError(t, err)
+		if assert.NotNil(t, record, applyURL) {
+			assert.Equal(t, saved.ID, record.ID)
+		}
+	}
+
+	otherURLs := []string{
+		"https://techcorp.com/jobs/124?lang=en&team=backend",
+		"https://techcorp.com/jobs/123?lang=id&team=backend",
+		"https://techcorp.com/Jobs/123?lang=en&team=backend",
+		"https://other.com/jobs/123?lang=en&team=backend",
+	}
+	for _, applyURL := range otherURLs {
+		record, err := storage.FindByApplyURL(context.Background(), applyURL)
+		assert.NoError(t, err)
+		assert.Nil(t, record, applyURL)
+	}
+}
+
+func TestJSONLStorageConcurrentSave(t *testing.T) {
+	filePath := "test_vacancies.jsonl"
+	defer os.Remove(filePath)
+
+	storage, err := NewJSONLStorage(JSONLStorageConfig{FilePath: filePath})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := storage.Save(context.Background(), core.Vacancy{
+				JobTitle:    "Software Engineer",
+				CompanyName: "Tech Corp",
+				ApplyURL:    fmt.Sprintf("https://techcorp.com/apply/%d", i),
+			})
+			assert.NoError(t, err)
+
+			_, err = storage.FindByApplyURL(context.Background(), "https://techcorp.com/apply/0")
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	// every record should be written on its own line
+	for i := 0; i < 20; i++ {
+		rec, err := storage.FindByApplyURL(context.Background(), fmt.Sprintf("https://techcorp.com/apply/%d", i))
+		assert.NoError(t, err)
+		assert.NotNil(t, rec)
+	}
+}
@@ -0,0 +1,42 @@
+package notion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupKeyword(t *testing.T) {
+	tests := []struct {
+		name          string
+		normalizedURL string
+		expected      string
+	}{
+		{
+			name:          "last path segment",
+			normalizedURL: "https://boards.greenhouse.io/company/jobs/123?gh_jid=123",
+			expected:      "123",
+		},
+		{
+			name:          "no path",
+			normalizedURL: "https://example.com?job=1",
+			expected:      "example.com",
+		},
+		{
+			name:          "segment which may be percent-encoded",
+			normalizedURL: "https://example.com/jobs/software engineer",
+			expected:      "example.com",
+		},
+		{
+			name:          "not absolute url",
+			normalizedURL: "example.com/jobs",
+			expected:      "example.com/jobs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, lookupKeyword(tt.normalizedURL))
+		})
+	}
+}
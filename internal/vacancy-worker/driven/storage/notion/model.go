@@ -108,11 +108,36 @@ func NewInsertRecordPaylod(databaseID string, now time.Time, v core.Vacancy) Ins
 }
 
 type Page struct {
+	ID         string           `json:"id"`
 	Properties RecordProperties `json:"properties"`
 }
 
+func (p Page) ToVacancy() core.Vacancy {
+	v := core.Vacancy{
+		JobTitle:         joinTextContents(p.Properties.Title.Title),
+		CompanyName:      joinTextContents(p.Properties.CompanyName.RichText),
+		CompanyLocation:  joinTextContents(p.Properties.CompanyLocation.RichText),
+		ShortDescription: joinTextContents(p.Properties.ShortDescription.RichText),
+		ApplyURL:         p.Properties.ApplyURL.URL,
+	}
+	for _, tag := range p.Properties.RelevantTags.MultiSelect {
+		v.RelevantTags = append(v.RelevantTags, tag.Name)
+	}
+	return v
+}
+
+func joinTextContents(blocks []BlockTextContent) string {
+	var sb strings.Builder
+	for _, block := range blocks {
+		sb.WriteString(block.Text.Content)
+	}
+	return sb.String()
+}
+
 type lookupRecordResponse struct {
-	Results []Page `json:"results"`
+	Results    []Page `json:"results"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor"`
 }
 
 func (r lookupRecordResponse) GetCompanyLocation() string {
@@ -3,6 +3,8 @@ package notion
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ghazlabs/idn-remote-entry/internal/shared/core"
@@ -46,10 +48,10 @@ func (s *NotionStorage) Save(ctx context.Context, v core.Vacancy) (*core.Vacancy
 		SetResult(&respBody).
 		Post("https://api.notion.com/v1/pages")
 	if err != nil {
-		return nil, fmt.Errorf("failed to call api to save the vacancy: %w", err)
+		return nil, core.NewUpstreamUnavailableError(fmt.Errorf("failed to call api to save the vacancy: %w", err))
 	}
 	if resp.IsError() {
-		return nil, fmt.Errorf("failed to save the vacancy: %s", resp.String())
+		return nil, core.NewUpstreamError(resp.StatusCode(), fmt.Errorf("failed to save the vacancy: %s", resp.String()))
 	}
 
 	rec := &core.VacancyRecord{
@@ -85,11 +87,88 @@ func (s *NotionStorage) LookupCompanyLocation(ctx context.Context, companyName s
 		SetResult(&respBody).
 		Post(fmt.Sprintf("https://api.notion.com/v1/databases/%s/query", s.DatabaseID))
 	if err != nil {
-		return "", fmt.Errorf("failed to call notion api to lookup company location: %w", err)
+		return "", core.NewUpstreamUnavailableError(fmt.Errorf("failed to call notion api to lookup company location: %w", err))
 	}
 	if resp.IsError() {
-		return "", fmt.Errorf("failed to lookup company location from Notion: %s", resp.String())
+		return "", core.NewUpstreamError(resp.StatusCode(), fmt.Errorf("failed to lookup company location from Notion: %s", resp.String()))
 	}
 
 	return respBody.GetCompanyLocation(), nil
 }
+
+// FindByApplyURL returns the vacancy having the same normalized apply url,
+// it returns nil when there is no such vacancy.
+func (s *NotionStorage) FindByApplyURL(ctx context.Context, applyURL string) (*core.VacancyRecord, error) {
+	normalizedURL := core.NormalizeApplyURL(applyURL)
+
+	// notion can't compare normalized url, so the filter only narrows down
+	// the candidates, every candidate is then compared by its normalized
+	// url to find the exact match
+	keyword := lookupKeyword(normalizedURL)
+
+	nextCursor := ""
+	for {
+		body := map[string]interface{}{
+			"filter": map[string]interface{}{
+				"property": "Apply URL",
+				"url": map[string]string{
+					"contains": keyword,
+				},
+			},
+			"page_size": 100,
+		}
+		if nextCursor != "" {
+			body["start_cursor"] = nextCursor
+		}
+
+		var respBody lookupRecordResponse
+		resp, err := s.HttpClient.R().
+			SetContext(ctx).
+			SetHeader("Authorization", "Bearer "+s.NotionToken).
+			SetHeader("Content-Type", "application/json").
+			SetHeader("Notion-Version", "2022-06-28").
+			SetBody(body).
+			SetResult(&respBody).
+			Post(fmt.Sprintf("https://api.notion.com/v1/databases/%s/query", s.DatabaseID))
+		if err != nil {
+			return nil, core.NewUpstreamUnavailableError(fmt.Errorf("failed to call notion api to find vacancy: %w", err))
+		}
+		if resp.IsError() {
+			return nil, core.NewUpstreamError(resp.StatusCode(), fmt.Errorf("failed to find vacancy from Notion: %s", resp.String()))
+		}
+
+		for _, page := range respBody.Results {
+			if core.NormalizeApplyURL(page.Properties.ApplyURL.URL) == normalizedURL {
+				return &core.VacancyRecord{
+					ID:        page.ID,
+					PublicURL: fmt.Sprintf("%s%s", URIIDNRemote, page.ID),
+					Vacancy:   page.ToVacancy(),
+				}, nil
+			}
+		}
+
+		if !respBody.HasMore {
+			return nil, nil
+		}
+		nextCursor = respBody.NextCursor
+	}
+}
+
+// lookupKeyword returns the part of the normalized url which is expected
+// to appear as it is in every url having the same normalized url, that is
+// the last segment of the path. The host is used instead when the segment
+// may be written differently (e.g percent-encoded) or there is no path.
+func lookupKeyword(normalizedURL string) string {
+	u, err := url.Parse(normalizedURL)
+	if err != nil || u.Host == "" {
+		return normalizedURL
+	}
+
+	segments := strings.Split(u.Path, "/")
+	lastSegment := segments[len(segments)-1]
+	if lastSegment == "" || url.PathEscape(lastSegment) != lastSegment {
+		return u.Hostname()
+	}
+
+	return lastSegment
+}